@@ -1,6 +1,7 @@
 package main
 
 import (
+	"context"
 	"log"
 	"os"
 	"runtime"
@@ -9,16 +10,19 @@ import (
 
 	"github.com/DariSorokina/go-first-sprint/internal/app"
 	"github.com/DariSorokina/go-first-sprint/internal/config"
+	"github.com/DariSorokina/go-first-sprint/internal/cookie"
 	"github.com/DariSorokina/go-first-sprint/internal/logger"
 	"github.com/DariSorokina/go-first-sprint/internal/server"
 	"github.com/DariSorokina/go-first-sprint/internal/storage"
 )
 
 func shortner() {
-	flagConfig := config.ParseFlags()
+	flagConfig, err := config.ParseFlags()
+	if err != nil {
+		log.Fatal("Failed to parse configuration:", err)
+	}
 
 	var l *logger.Logger
-	var err error
 	if l, err = logger.CreateLogger(flagConfig.FlagLogLevel); err != nil {
 		log.Fatal("Failed to create logger:", err)
 	}
@@ -29,10 +33,17 @@ func shortner() {
 	}
 	defer storage.Close()
 
-	app := app.NewApp(storage, l)
-	serv := server.NewServer(app, flagConfig, l)
+	app, err := app.NewApp(storage, flagConfig, l)
+	if err != nil {
+		panic(err)
+	}
+	tokens, err := cookie.NewTokens(flagConfig)
+	if err != nil {
+		panic(err)
+	}
+	serv := server.NewServer(app, tokens, flagConfig, l)
 
-	if err := server.Run(serv); err != nil {
+	if err := server.Run(context.Background(), serv); err != nil {
 		panic(err)
 	}
 }
@@ -1,34 +1,167 @@
 package main
 
 import (
+	"context"
+	"crypto/tls"
+	"fmt"
 	"log"
+	"os"
+	"os/signal"
+	"syscall"
 
 	"github.com/DariSorokina/go-first-sprint/internal/app"
 	"github.com/DariSorokina/go-first-sprint/internal/config"
+	"github.com/DariSorokina/go-first-sprint/internal/cookie"
+	"github.com/DariSorokina/go-first-sprint/internal/grpcserver"
 	"github.com/DariSorokina/go-first-sprint/internal/logger"
+	"github.com/DariSorokina/go-first-sprint/internal/metrics"
 	"github.com/DariSorokina/go-first-sprint/internal/server"
 	"github.com/DariSorokina/go-first-sprint/internal/storage"
+	"github.com/DariSorokina/go-first-sprint/internal/tracing"
+	"golang.org/x/sync/errgroup"
 )
 
 func main() {
-	flagConfig := config.ParseFlags()
+	if len(os.Args) > 1 && os.Args[1] == "migrate" {
+		os.Exit(runMigrate(os.Args[2:]))
+	}
+	os.Exit(run())
+}
+
+// run starts the shortener and blocks until it stops after a fatal error or SIGINT, SIGTERM or SIGQUIT.
+// It returns the process exit code.
+func run() int {
+	flagConfig, err := config.ParseFlags()
+	if err != nil {
+		log.Println("Failed to parse configuration:", err)
+		return 2
+	}
+
+	if flagConfig.FlagPrintConfig {
+		fmt.Println(flagConfig.Redacted())
+		return 0
+	}
 
 	var l *logger.Logger
-	var err error
 	if l, err = logger.CreateLogger(flagConfig.FlagLogLevel); err != nil {
-		log.Fatal("Failed to create logger:", err)
+		log.Println("Failed to create logger:", err)
+		return 1
 	}
+	defer l.Sync()
+
+	shutdownTracing, err := tracing.Setup(context.Background(), flagConfig)
+	if err != nil {
+		l.Sugar().Errorf("Failed to set up tracing: %s", err)
+		return 1
+	}
+	defer func() {
+		ctx, cancel := context.WithTimeout(context.Background(), flagConfig.FlagShutdownTimeout)
+		defer cancel()
+		if err := shutdownTracing(ctx); err != nil {
+			l.Sugar().Errorf("Failed to flush traces: %s", err)
+		}
+	}()
 
 	storage, err := storage.SetStorage(flagConfig, l)
 	if err != nil {
-		panic(err)
+		l.Sugar().Errorf("Failed to set storage: %s", err)
+		return 1
 	}
-	defer storage.Close()
 
-	app := app.NewApp(storage, l)
-	serv := server.NewServer(app, flagConfig, l)
+	exitCode := serve(flagConfig, storage, l)
+	if err = storage.Close(); err != nil {
+		l.Sugar().Errorf("Failed to close storage: %s", err)
+		exitCode = 1
+	}
+	if exitCode == 0 {
+		l.Info("Server stopped")
+	}
+	return exitCode
+}
 
-	if err := server.Run(serv); err != nil {
-		panic(err)
+// serve runs the servers and background workers on the storage until a fatal error or a signal,
+// and stops them in an order that saves everything accepted before. It returns the process exit code.
+func serve(flagConfig *config.FlagConfig, db storage.Database, l *logger.Logger) int {
+	app, err := app.NewApp(tracing.NewDatabase(metrics.NewDatabase(db)), flagConfig, l)
+	if err != nil {
+		l.Sugar().Errorf("Failed to create app: %s", err)
+		return 1
+	}
+	if err = metrics.RegisterDeleteQueue(app.DeleteQueueDepth); err != nil {
+		l.Sugar().Errorf("Failed to register metrics: %s", err)
+		return 1
+	}
+
+	if flagConfig.FlagJWTKeys == config.DefaultJWTKeys {
+		l.Warn("Signing user tokens with the default JWT key, set JWT_KEYS in production")
+	}
+	tokens, err := cookie.NewTokens(flagConfig)
+	if err != nil {
+		l.Sugar().Errorf("Failed to create user tokens: %s", err)
+		return 1
+	}
+
+	serv := server.NewServer(app, tokens, flagConfig, l)
+	grpcServ := grpcserver.NewServer(app, tokens, serv.RateLimiter(), flagConfig, l)
+	var grpcTLS *tls.Config
+	if flagConfig.FlagEnableHTTPS {
+		// The gRPC server serves the certificate of the HTTPS server rather than plaintext.
+		if grpcTLS, err = serv.TLSConfig(); err != nil {
+			l.Sugar().Errorf("Failed to configure TLS: %s", err)
+			return 1
+		}
+	}
+
+	// The deleter and the click recorder outlive the servers, so deletions and clicks accepted while
+	// the servers shut down are still saved.
+	deleterCtx, stopDeleter := context.WithCancel(context.Background())
+	go app.RunDeleter(deleterCtx, flagConfig.FlagDeleteWorkers, flagConfig.FlagDeleteBatchSize, flagConfig.FlagDeleteFlush)
+	recorderCtx, stopRecorder := context.WithCancel(context.Background())
+	recorderDone := make(chan struct{})
+	go func() {
+		defer close(recorderDone)
+		app.RunClickRecorder(recorderCtx, flagConfig.FlagClickFlush)
+	}()
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM, syscall.SIGQUIT)
+	defer stop()
+
+	group, groupCtx := errgroup.WithContext(ctx)
+	group.Go(func() error {
+		app.RunJanitor(groupCtx, flagConfig.FlagJanitorInterval)
+		return nil
+	})
+	group.Go(func() error {
+		return server.Run(groupCtx, serv)
+	})
+	group.Go(func() error {
+		return grpcserver.Run(groupCtx, grpcServ, grpcTLS)
+	})
+	group.Go(func() error {
+		return metrics.Run(groupCtx, flagConfig.FlagMetricsAddr, flagConfig.FlagShutdownTimeout, l)
+	})
+
+	exitCode := 0
+	if err := group.Wait(); err != nil {
+		l.Sugar().Errorf("Server stopped with error: %s", err)
+		exitCode = 1
+	}
+
+	// Neither the HTTP nor the gRPC server accepts requests any more, so no deletion can be queued
+	// while the queued ones are flushed. Clicks are saved last.
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), flagConfig.FlagShutdownTimeout)
+	defer cancel()
+	stopDeleter()
+	if err := app.WaitDeletions(shutdownCtx); err != nil {
+		l.Sugar().Errorf("Failed to flush URL deletions: %s", err)
+		exitCode = 1
+	}
+	stopRecorder()
+	select {
+	case <-recorderDone:
+	case <-shutdownCtx.Done():
+		l.Sugar().Errorf("Failed to flush clicks: %s", shutdownCtx.Err())
+		exitCode = 1
 	}
+	return exitCode
 }
@@ -0,0 +1,98 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+
+	"github.com/DariSorokina/go-first-sprint/internal/config"
+	"github.com/DariSorokina/go-first-sprint/internal/logger"
+	"github.com/DariSorokina/go-first-sprint/internal/migrations"
+	_ "github.com/jackc/pgx/v5/stdlib"
+)
+
+const migrateUsage = `usage: shortener migrate [flags] up | down [steps] | status`
+
+// runMigrate applies, rolls back or prints the status of the PostgreSQL schema migrations
+// and returns the process exit code.
+func runMigrate(args []string) int {
+	flagConfig, err := config.ParseArgs(args)
+	if err != nil {
+		log.Println("Failed to parse configuration:", err)
+		return 2
+	}
+	if flagConfig.FlagPostgresqlDSN == "" {
+		log.Println("migrate requires a PostgreSQL DSN, set -d or DATABASE_DSN")
+		return 2
+	}
+
+	var l *logger.Logger
+	if l, err = logger.CreateLogger(flagConfig.FlagLogLevel); err != nil {
+		log.Println("Failed to create logger:", err)
+		return 1
+	}
+	defer l.Sync()
+
+	db, err := sql.Open("pgx", flagConfig.FlagPostgresqlDSN)
+	if err != nil {
+		l.Sugar().Errorf("Failed to open a database: %s", err)
+		return 1
+	}
+	defer db.Close()
+
+	migrator, err := migrations.NewMigrator(db, l)
+	if err != nil {
+		l.Sugar().Errorf("Failed to load migrations: %s", err)
+		return 1
+	}
+
+	ctx := context.Background()
+	switch flag.Arg(0) {
+	case "up":
+		applied, err := migrator.Up(ctx)
+		for _, migration := range applied {
+			fmt.Printf("applied %04d_%s\n", migration.Version, migration.Name)
+		}
+		if err != nil {
+			l.Sugar().Errorf("Failed to apply migrations: %s", err)
+			return 1
+		}
+	case "down":
+		steps := 1
+		if flag.NArg() > 1 {
+			if steps, err = strconv.Atoi(flag.Arg(1)); err != nil || steps <= 0 {
+				fmt.Fprintln(os.Stderr, migrateUsage)
+				return 2
+			}
+		}
+		rolledBack, err := migrator.Down(ctx, steps)
+		for _, migration := range rolledBack {
+			fmt.Printf("rolled back %04d_%s\n", migration.Version, migration.Name)
+		}
+		if err != nil {
+			l.Sugar().Errorf("Failed to roll back migrations: %s", err)
+			return 1
+		}
+	case "status":
+		statuses, err := migrator.Status(ctx)
+		if err != nil {
+			l.Sugar().Errorf("Failed to read migration status: %s", err)
+			return 1
+		}
+		for _, status := range statuses {
+			if status.Applied {
+				fmt.Printf("%04d_%s\tapplied %s\n", status.Version, status.Name, status.AppliedAt.Format("2006-01-02 15:04:05 MST"))
+			} else {
+				fmt.Printf("%04d_%s\tpending\n", status.Version, status.Name)
+			}
+		}
+	default:
+		fmt.Fprintln(os.Stderr, migrateUsage)
+		return 2
+	}
+	return 0
+}
@@ -0,0 +1,52 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/DariSorokina/go-first-sprint/internal/metrics"
+	"github.com/DariSorokina/go-first-sprint/internal/tracing"
+)
+
+// ErrInvalidAlias indicates that the requested alias can not be used as a short URL.
+var ErrInvalidAlias = errors.New("invalid alias")
+
+var aliasPattern = regexp.MustCompile(`^[A-Za-z0-9_-]{3,64}$`)
+
+// reservedAliases holds path segments served by the router itself, so an alias can not shadow them.
+var reservedAliases = map[string]bool{
+	"api":     true,
+	"ping":    true,
+	"metrics": true,
+	"debug":   true,
+}
+
+func validateAlias(alias string) error {
+	if !aliasPattern.MatchString(alias) {
+		return fmt.Errorf("%w: alias must be 3 to 64 characters of letters, digits, '-' or '_'", ErrInvalidAlias)
+	}
+	if reservedAliases[strings.ToLower(alias)] {
+		return fmt.Errorf("%w: alias %q is reserved", ErrInvalidAlias, alias)
+	}
+	return nil
+}
+
+// ToAliasURL is a method to store a long URL under a user-chosen alias.
+// If the alias is already taken, it returns the ID of the user owning it together with storage.ErrAliasAlreadyExist.
+func (app *App) ToAliasURL(ctx context.Context, alias, longURL string, userID int, expiresAt time.Time) (ownerID int, err error) {
+	ctx, span := tracing.Start(ctx, "App.ToAliasURL")
+	defer func() { tracing.End(span, err) }()
+
+	if err = validateAlias(alias); err != nil {
+		return 0, err
+	}
+	ownerID, err = app.storage.SetAlias(ctx, alias, longURL, userID, expiresAt)
+	if err == nil {
+		metrics.URLsShortened(1)
+	}
+	return ownerID, err
+}
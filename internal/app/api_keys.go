@@ -0,0 +1,142 @@
+package app
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/DariSorokina/go-first-sprint/internal/models"
+	"github.com/DariSorokina/go-first-sprint/internal/storage"
+	"github.com/DariSorokina/go-first-sprint/internal/tracing"
+)
+
+// APIKeyPrefix starts every API key, so they can be told apart from JWTs in an Authorization header.
+const APIKeyPrefix = "sk_"
+
+// apiKeyDisplayLength is the number of leading characters of a key stored in clear to identify it in lists.
+const apiKeyDisplayLength = len(APIKeyPrefix) + 8
+
+// ErrInvalidAPIKeyScopes indicates that an API key is requested without scopes or with an unknown one.
+var ErrInvalidAPIKeyScopes = errors.New("invalid API key scopes")
+
+// ErrInvalidAPIKey indicates that an API key is unknown or revoked.
+var ErrInvalidAPIKey = errors.New("invalid API key")
+
+var apiKeyScopes = map[string]bool{
+	models.ScopeShorten: true,
+	models.ScopeRead:    true,
+	models.ScopeDelete:  true,
+}
+
+func validateAPIKeyScopes(scopes []string) error {
+	if len(scopes) == 0 {
+		return fmt.Errorf("%w: at least one scope is required", ErrInvalidAPIKeyScopes)
+	}
+	for _, scope := range scopes {
+		if !apiKeyScopes[scope] {
+			return fmt.Errorf("%w: unknown scope %q", ErrInvalidAPIKeyScopes, scope)
+		}
+	}
+	return nil
+}
+
+// hashAPIKey returns the hash under which the key is stored. Keys are long random strings,
+// so a fast hash is enough to keep them unusable if the storage leaks.
+func hashAPIKey(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
+
+func randomHex(n int) (string, error) {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// CreateAPIKey is a method to issue a new API key with the given scopes to the user.
+// The key itself is only returned here, the storage keeps its hash.
+func (app *App) CreateAPIKey(ctx context.Context, userID int, name string, scopes []string) (apiKey models.APIKey, key string, err error) {
+	ctx, span := tracing.Start(ctx, "App.CreateAPIKey")
+	defer func() { tracing.End(span, err) }()
+
+	if err = validateAPIKeyScopes(scopes); err != nil {
+		return models.APIKey{}, "", err
+	}
+
+	id, err := randomHex(8)
+	if err != nil {
+		return models.APIKey{}, "", err
+	}
+	secret, err := randomHex(32)
+	if err != nil {
+		return models.APIKey{}, "", err
+	}
+	key = APIKeyPrefix + secret
+
+	seen := make(map[string]bool, len(scopes))
+	uniqueScopes := make([]string, 0, len(scopes))
+	for _, scope := range scopes {
+		if !seen[scope] {
+			seen[scope] = true
+			uniqueScopes = append(uniqueScopes, scope)
+		}
+	}
+
+	apiKey = models.APIKey{
+		ID:        id,
+		UserID:    userID,
+		Name:      name,
+		Scopes:    uniqueScopes,
+		Prefix:    key[:apiKeyDisplayLength],
+		Hash:      hashAPIKey(key),
+		CreatedAt: time.Now().UTC().Truncate(time.Microsecond),
+	}
+	if err = app.storage.CreateAPIKey(ctx, apiKey); err != nil {
+		return models.APIKey{}, "", err
+	}
+	return apiKey, key, nil
+}
+
+// ListAPIKeys is a method to get the API keys of the user, including revoked ones.
+func (app *App) ListAPIKeys(ctx context.Context, userID int) (keys []models.APIKey, err error) {
+	ctx, span := tracing.Start(ctx, "App.ListAPIKeys")
+	defer func() { tracing.End(span, err) }()
+	return app.storage.ListAPIKeys(ctx, userID)
+}
+
+// RevokeAPIKey is a method to revoke the user's API key. Revoking a revoked key succeeds,
+// and a key of another user is reported as storage.ErrAPIKeyNotFound.
+func (app *App) RevokeAPIKey(ctx context.Context, id string, userID int) (err error) {
+	ctx, span := tracing.Start(ctx, "App.RevokeAPIKey")
+	defer func() { tracing.End(span, err) }()
+	return app.storage.RevokeAPIKey(ctx, id, userID, time.Now().UTC().Truncate(time.Microsecond))
+}
+
+// AuthenticateAPIKey is a method to find the API key the request was made with.
+// It returns ErrInvalidAPIKey if the key is unknown or revoked.
+func (app *App) AuthenticateAPIKey(ctx context.Context, key string) (apiKey models.APIKey, err error) {
+	ctx, span := tracing.Start(ctx, "App.AuthenticateAPIKey")
+	defer func() { tracing.End(span, err) }()
+
+	if !strings.HasPrefix(key, APIKeyPrefix) {
+		return models.APIKey{}, ErrInvalidAPIKey
+	}
+	apiKey, err = app.storage.GetAPIKeyByHash(ctx, hashAPIKey(key))
+	if errors.Is(err, storage.ErrNotFound) {
+		return models.APIKey{}, ErrInvalidAPIKey
+	}
+	if err != nil {
+		return models.APIKey{}, err
+	}
+	if apiKey.RevokedAt != nil {
+		return models.APIKey{}, ErrInvalidAPIKey
+	}
+	return apiKey, nil
+}
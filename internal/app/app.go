@@ -3,64 +3,155 @@ package app
 
 import (
 	"context"
-	"crypto/md5"
-	"fmt"
+	"crypto/rand"
+	"errors"
+	"sync"
+	"time"
 
+	"github.com/DariSorokina/go-first-sprint/internal/config"
 	"github.com/DariSorokina/go-first-sprint/internal/logger"
+	"github.com/DariSorokina/go-first-sprint/internal/metrics"
 	"github.com/DariSorokina/go-first-sprint/internal/models"
 	"github.com/DariSorokina/go-first-sprint/internal/storage"
+	"github.com/DariSorokina/go-first-sprint/internal/tracing"
 )
 
 // App is a structure representing the application logic.
 type App struct {
-	storage storage.Database
-	log     *logger.Logger
+	storage   storage.Database
+	generator CodeGenerator
+	clicks    chan models.ClickEvent
+	ipKey     []byte // Key of the HMAC hashing client networks in click events.
+	deletions chan models.URLsClientID
+	pending   sync.WaitGroup // Deletion requests accepted but not flushed to the storage yet.
+	jobs      *deleteJobs
+	grace     time.Duration // Time a deleted short URL can be restored before the janitor purges it.
+	retention time.Duration // Time an expired short URL keeps answering as expired before the janitor removes it.
+	log       *logger.Logger
 }
 
 // NewApp is a constructor function to create a new App instance.
-func NewApp(storage storage.Database, l *logger.Logger) *App {
-	return &App{storage: storage, log: l}
+func NewApp(storage storage.Database, flagConfig *config.FlagConfig, l *logger.Logger) (*App, error) {
+	generator, err := NewCodeGenerator(flagConfig.FlagCodeGenerator, flagConfig.FlagCodeLength)
+	if err != nil {
+		return nil, err
+	}
+	ipKey := []byte(flagConfig.FlagClickIPKey)
+	if len(ipKey) == 0 {
+		ipKey = make([]byte, 32)
+		if _, err = rand.Read(ipKey); err != nil {
+			return nil, err
+		}
+	}
+	clicks := make(chan models.ClickEvent, flagConfig.FlagClickBufferSize)
+	deletions := make(chan models.URLsClientID, flagConfig.FlagDeleteQueueSize)
+	return &App{storage: storage, generator: generator, clicks: clicks, ipKey: ipKey, deletions: deletions,
+		jobs: newDeleteJobs(flagConfig.FlagDeleteJobTTL), grace: flagConfig.FlagRestoreGrace,
+		retention: flagConfig.FlagExpiryRetention, log: l}, nil
 }
 
 // ToShortenURL is a method to shorten a long URL and store it in the database.
-func (app *App) ToShortenURL(ctx context.Context, longURL string, userID int) (shortURL string, err error) {
-	shortURL, err = app.storage.GetShort(ctx, longURL)
-	if err != nil {
-		return
+// If the long URL is already shortened, the existing short URL is returned with storage.ErrShortURLAlreadyExist.
+// A zero expiresAt means the short URL never expires.
+func (app *App) ToShortenURL(ctx context.Context, longURL string, userID int, expiresAt time.Time) (shortURL string, err error) {
+	ctx, span := tracing.Start(ctx, "App.ToShortenURL")
+	defer func() { tracing.End(span, err) }()
+
+	for attempt := 0; attempt < maxGenerateAttempts; attempt++ {
+		code, err := app.generator.Generate(longURL, attempt)
+		if err != nil {
+			return "", err
+		}
+
+		shortURL, err = app.storage.SetValue(ctx, code, longURL, userID, expiresAt)
+		if err == nil {
+			metrics.URLsShortened(1)
+		}
+		if !errors.Is(err, storage.ErrShortURLTaken) {
+			return shortURL, err
+		}
+		app.log.Sugar().Infof("Short url %s is already taken, attempt %d", code, attempt+1)
 	}
-	shortURL = encodeString(longURL)
-	app.storage.SetValue(ctx, shortURL, longURL, userID)
-	return
+	return "", ErrShortURLGeneration
+}
+
+// ToShortenBatch is a method to shorten a batch of long URLs and store them in the database at once.
+// Items whose long URL is already shortened get the existing short URL and Conflict set.
+func (app *App) ToShortenBatch(ctx context.Context, urls []models.BatchURL, userID int) (err error) {
+	ctx, span := tracing.Start(ctx, "App.ToShortenBatch")
+	defer func() { tracing.End(span, err) }()
+
+	if len(urls) == 0 {
+		return nil
+	}
+
+	for attempt := 0; attempt < maxGenerateAttempts; attempt++ {
+		for i := range urls {
+			code, err := app.generator.Generate(urls[i].OriginalURL, attempt)
+			if err != nil {
+				return err
+			}
+			urls[i].ShortURL, urls[i].Conflict = code, false
+		}
+
+		err := app.storage.SetValues(ctx, urls, userID)
+		if err == nil {
+			var shortened int
+			for _, url := range urls {
+				if !url.Conflict {
+					shortened++
+				}
+			}
+			metrics.URLsShortened(shortened)
+		}
+		if !errors.Is(err, storage.ErrShortURLTaken) {
+			return err
+		}
+		app.log.Sugar().Infof("Short url of a batch is already taken, attempt %d", attempt+1)
+	}
+	return ErrShortURLGeneration
 }
 
 // ToOriginalURL is a method to retrieve the original URL from a short URL.
 func (app *App) ToOriginalURL(ctx context.Context, shortURL string) (longURL string, err error) {
+	ctx, span := tracing.Start(ctx, "App.ToOriginalURL")
+	defer func() { tracing.End(span, err) }()
+
 	longURL, err = app.storage.GetOriginal(ctx, shortURL)
+	if err == nil {
+		metrics.Redirected()
+	}
 	return
 }
 
 // GetURLsByUserID is a method to retrieve URLs associated with a specific user ID.
-func (app *App) GetURLsByUserID(ctx context.Context, userID int) (urls []models.URLPair) {
-	urls = app.storage.GetURLsByUserID(ctx, userID)
+func (app *App) GetURLsByUserID(ctx context.Context, userID int) (urls []models.URLPair, err error) {
+	ctx, span := tracing.Start(ctx, "App.GetURLsByUserID")
+	defer func() { tracing.End(span, err) }()
+
+	urls, err = app.storage.GetURLsByUserID(ctx, userID)
 	return
 }
 
-// DeleteURLs is a method to handle deletion of URLs based on client requests.
-func (app *App) DeleteURLs(deleteURLsChannel <-chan models.URLsClientID) {
-	for urlsClientID := range deleteURLsChannel {
-		go app.storage.DeleteURLsWorker(urlsClientID.URLs, urlsClientID.ClientID)
+// GetInternalStats is a method to count short URLs and distinct users held by the storage
+// and deletion requests waiting in the queue.
+func (app *App) GetInternalStats(ctx context.Context) (stats models.InternalStats, err error) {
+	ctx, span := tracing.Start(ctx, "App.GetInternalStats")
+	defer func() { tracing.End(span, err) }()
+
+	stats.DeleteQueue = app.DeleteQueueDepth()
+	if stats.URLs, err = app.storage.CountURLs(ctx); err != nil {
+		return
 	}
+	stats.Users, err = app.storage.CountUsers(ctx)
+	return
 }
 
 // Ping is a method to check the database connectivity.
 func (app *App) Ping(ctx context.Context) (err error) {
+	ctx, span := tracing.Start(ctx, "App.Ping")
+	defer func() { tracing.End(span, err) }()
+
 	err = app.storage.Ping(ctx)
 	return
 }
-
-func encodeString(data string) string {
-	encodedMD5 := md5.Sum([]byte(data))
-	encodedMD5Trimed := encodedMD5[:5]
-	return fmt.Sprintf("%x", encodedMD5Trimed)
-
-}
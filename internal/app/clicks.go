@@ -0,0 +1,103 @@
+package app
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"net"
+	"time"
+
+	"github.com/DariSorokina/go-first-sprint/internal/models"
+	"github.com/DariSorokina/go-first-sprint/internal/tracing"
+)
+
+// clickBatchSize is the number of click events that triggers a flush before the flush interval passes.
+const clickBatchSize = 100
+
+// RecordClick is a method to queue a redirect event for asynchronous saving.
+// It never blocks: if the buffer is full, the event is dropped.
+func (app *App) RecordClick(shortURL, referrer, userAgent, clientIP string) {
+	click := models.ClickEvent{
+		ShortURL:  shortURL,
+		ClickedAt: time.Now().UTC(),
+		Referrer:  referrer,
+		UserAgent: userAgent,
+		IPHash:    app.hashIP(clientIP),
+	}
+
+	select {
+	case app.clicks <- click:
+	default:
+		app.log.Sugar().Warnf("Click buffer is full, dropping click on %s", shortURL)
+	}
+}
+
+// RunClickRecorder is a method to save queued click events to the storage in batches until ctx is done.
+// A batch is flushed when it reaches clickBatchSize or when flushInterval passes, whichever comes first.
+func (app *App) RunClickRecorder(ctx context.Context, flushInterval time.Duration) {
+	ticker := time.NewTicker(flushInterval)
+	defer ticker.Stop()
+
+	batch := make([]models.ClickEvent, 0, clickBatchSize)
+	flush := func() {
+		if len(batch) == 0 {
+			return
+		}
+		flushCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		if err := app.storage.SaveClicks(flushCtx, batch); err != nil {
+			app.log.Sugar().Errorf("Failed to save %d clicks: %s", len(batch), err)
+		}
+		batch = batch[:0]
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			for {
+				select {
+				case click := <-app.clicks:
+					batch = append(batch, click)
+				default:
+					flush()
+					return
+				}
+			}
+		case click := <-app.clicks:
+			batch = append(batch, click)
+			if len(batch) >= clickBatchSize {
+				flush()
+			}
+		case <-ticker.C:
+			flush()
+		}
+	}
+}
+
+// GetLinkStats is a method to retrieve click statistics for a short URL owned by the user.
+func (app *App) GetLinkStats(ctx context.Context, shortURL string, userID int) (stats models.LinkStats, err error) {
+	ctx, span := tracing.Start(ctx, "App.GetLinkStats")
+	defer func() { tracing.End(span, err) }()
+
+	stats, err = app.storage.GetLinkStats(ctx, shortURL, userID)
+	return
+}
+
+// hashIP returns a keyed hash of the client's network rather than of its address: the last octet of an IPv4
+// address and all but the first 48 bits of an IPv6 one are dropped, so the hash can not be brute-forced
+// back to a single client even if the key leaks.
+func (app *App) hashIP(clientIP string) string {
+	ip := net.ParseIP(clientIP)
+	if ip == nil {
+		return ""
+	}
+	network := ip.Mask(net.CIDRMask(48, 128))
+	if ip4 := ip.To4(); ip4 != nil {
+		network = ip4.Mask(net.CIDRMask(24, 32))
+	}
+
+	mac := hmac.New(sha256.New, app.ipKey)
+	mac.Write(network)
+	return hex.EncodeToString(mac.Sum(nil))
+}
@@ -0,0 +1,36 @@
+package app
+
+import (
+	"testing"
+
+	"github.com/DariSorokina/go-first-sprint/internal/config"
+	"github.com/DariSorokina/go-first-sprint/internal/logger"
+	"github.com/DariSorokina/go-first-sprint/internal/storage"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHashIP(t *testing.T) {
+	l, err := logger.CreateLogger("error")
+	require.NoError(t, err)
+
+	newApp := func(key string) *App {
+		flagConfig := config.NewFlagConfig()
+		flagConfig.FlagClickIPKey = key
+		app, err := NewApp(storage.NewStorage("", l), flagConfig, l)
+		require.NoError(t, err)
+		return app
+	}
+	app := newApp("secret")
+
+	hash := app.hashIP("192.0.2.1")
+	assert.Len(t, hash, 64)
+	assert.Equal(t, hash, app.hashIP("192.0.2.200"), "addresses of one /24 network share a hash")
+	assert.NotEqual(t, hash, app.hashIP("192.0.3.1"))
+	assert.Equal(t, app.hashIP("2001:db8:1::1"), app.hashIP("2001:db8:1:ffff::2"), "addresses of one /48 network share a hash")
+	assert.Empty(t, app.hashIP(""))
+
+	assert.Equal(t, hash, newApp("secret").hashIP("192.0.2.1"), "hashes are stable for a configured key")
+	assert.NotEqual(t, hash, newApp("other").hashIP("192.0.2.1"))
+	assert.NotEqual(t, newApp("").hashIP("192.0.2.1"), newApp("").hashIP("192.0.2.1"), "without a key, every start uses a random key")
+}
@@ -0,0 +1,164 @@
+package app
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/DariSorokina/go-first-sprint/internal/models"
+	"github.com/DariSorokina/go-first-sprint/internal/tracing"
+)
+
+// ErrDeleteJobNotFound indicates that the deletion job does not exist, has expired or belongs to another user.
+var ErrDeleteJobNotFound = errors.New("deletion job was not found")
+
+// deleteJob is a deletion job with the number of its short URLs not processed yet.
+type deleteJob struct {
+	models.DeleteJob
+	remaining int
+}
+
+// deleteJobs keeps deletion jobs and forgets finished ones after the retention time. Jobs live in the memory
+// of the instance that accepted them, so their status is only known to that instance and is lost on restart,
+// while the deletions themselves are saved to the storage.
+type deleteJobs struct {
+	mutex     sync.Mutex
+	byID      map[string]*deleteJob
+	retention time.Duration
+}
+
+func newDeleteJobs(retention time.Duration) *deleteJobs {
+	return &deleteJobs{byID: make(map[string]*deleteJob), retention: retention}
+}
+
+// create registers a queued job for the short URLs of the user.
+func (jobs *deleteJobs) create(userID int, shortURLs []string) (string, error) {
+	id := make([]byte, 16)
+	if _, err := rand.Read(id); err != nil {
+		return "", err
+	}
+
+	job := &deleteJob{
+		DeleteJob: models.DeleteJob{
+			ID:        hex.EncodeToString(id),
+			UserID:    userID,
+			Status:    models.DeleteJobQueued,
+			Results:   make(map[string]string, len(shortURLs)),
+			CreatedAt: time.Now(),
+		},
+		remaining: len(shortURLs),
+	}
+
+	jobs.mutex.Lock()
+	defer jobs.mutex.Unlock()
+
+	if job.remaining == 0 {
+		job.finish(job.CreatedAt)
+	}
+	jobs.byID[job.ID] = job
+	return job.ID, nil
+}
+
+// purge forgets jobs that finished more than the retention time ago, wherever they are among unfinished
+// ones, and returns how many were forgotten.
+func (jobs *deleteJobs) purge(now time.Time) (purged int) {
+	jobs.mutex.Lock()
+	defer jobs.mutex.Unlock()
+
+	for id, job := range jobs.byID {
+		if job.FinishedAt != nil && now.Sub(*job.FinishedAt) >= jobs.retention {
+			delete(jobs.byID, id)
+			purged++
+		}
+	}
+	return purged
+}
+
+// remove forgets a job that was never queued.
+func (jobs *deleteJobs) remove(id string) {
+	jobs.mutex.Lock()
+	defer jobs.mutex.Unlock()
+
+	delete(jobs.byID, id)
+}
+
+// start marks the jobs as running.
+func (jobs *deleteJobs) start(chunk []models.URLsClientID) {
+	jobs.mutex.Lock()
+	defer jobs.mutex.Unlock()
+
+	for _, deletion := range chunk {
+		if job, ok := jobs.byID[deletion.JobID]; ok && job.Status == models.DeleteJobQueued {
+			job.Status = models.DeleteJobRunning
+		}
+	}
+}
+
+// complete records the results of a processed chunk, or its error if results is nil,
+// and finishes the jobs that have no short URLs left.
+func (jobs *deleteJobs) complete(chunk []models.URLsClientID, results []map[string]string, err error) {
+	jobs.mutex.Lock()
+	defer jobs.mutex.Unlock()
+
+	now := time.Now()
+	for i, deletion := range chunk {
+		job, ok := jobs.byID[deletion.JobID]
+		if !ok {
+			continue
+		}
+		if err != nil {
+			job.Status, job.Error = models.DeleteJobFailed, err.Error()
+		} else {
+			for shortURL, result := range results[i] {
+				job.Results[shortURL] = result
+			}
+		}
+		if job.remaining -= len(deletion.URLs); job.remaining <= 0 {
+			job.finish(now)
+		}
+	}
+}
+
+// get returns a copy of the job if it belongs to the user.
+func (jobs *deleteJobs) get(id string, userID int) (models.DeleteJob, bool) {
+	jobs.mutex.Lock()
+	defer jobs.mutex.Unlock()
+
+	job, ok := jobs.byID[id]
+	if !ok || job.UserID != userID {
+		return models.DeleteJob{}, false
+	}
+
+	copied := job.DeleteJob
+	copied.Results = make(map[string]string, len(job.Results))
+	for shortURL, result := range job.Results {
+		copied.Results[shortURL] = result
+	}
+	return copied, true
+}
+
+func (job *deleteJob) finish(now time.Time) {
+	if job.Status != models.DeleteJobFailed {
+		job.Status = models.DeleteJobDone
+	}
+	job.FinishedAt = &now
+}
+
+// GetDeleteJob is a method to get the state of a deletion job of the user. Only the instance that accepted
+// the job knows it, until it is purged or the instance restarts. It returns the error of ctx if the request is cancelled or its deadline has passed.
+func (app *App) GetDeleteJob(ctx context.Context, id string, userID int) (job models.DeleteJob, err error) {
+	_, span := tracing.Start(ctx, "App.GetDeleteJob")
+	defer func() { tracing.End(span, err) }()
+
+	if err = ctx.Err(); err != nil {
+		return models.DeleteJob{}, err
+	}
+	job, ok := app.jobs.get(id, userID)
+	if !ok {
+		return job, ErrDeleteJobNotFound
+	}
+	return job, nil
+}
@@ -0,0 +1,177 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/DariSorokina/go-first-sprint/internal/models"
+	"github.com/DariSorokina/go-first-sprint/internal/tracing"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// ErrDeleteQueueFull indicates that the deletion queue is full and the request was not accepted.
+var ErrDeleteQueueFull = errors.New("deletion queue is full")
+
+// DeleteURLs is a method to request deletion of the user's short URLs.
+// It returns the ID of the deletion job once the request is queued, or ErrDeleteQueueFull without waiting
+// if the queue is full; RunDeleter workers delete it with their next flush and WaitDeletions blocks until that is done.
+func (app *App) DeleteURLs(shortURLs []string, userID int) (jobID string, err error) {
+	jobID, err = app.jobs.create(userID, shortURLs)
+	if err != nil || len(shortURLs) == 0 {
+		return jobID, err
+	}
+
+	app.pending.Add(1)
+	select {
+	case app.deletions <- models.URLsClientID{URLs: shortURLs, ClientID: userID, JobID: jobID}:
+		return jobID, nil
+	default:
+		app.pending.Done()
+		app.jobs.remove(jobID)
+		return "", ErrDeleteQueueFull
+	}
+}
+
+// DeleteQueueDepth is a method to get the number of deletion requests waiting in the queue.
+func (app *App) DeleteQueueDepth() int {
+	return len(app.deletions)
+}
+
+// RestoreURLs is a method to restore the user's short URLs deleted within the restore grace period.
+// It returns the restoration result of each short URL, such as models.RestorationGraceExpired.
+func (app *App) RestoreURLs(ctx context.Context, shortURLs []string, userID int) (results map[string]string, err error) {
+	ctx, span := tracing.Start(ctx, "App.RestoreURLs")
+	defer func() { tracing.End(span, err) }()
+	return app.storage.RestoreURLs(ctx, shortURLs, userID, time.Now().Add(-app.grace))
+}
+
+// RunDeleter is a method to run a fixed pool of workers deleting the queued requests of all users until ctx is done.
+// Each worker collects requests and deletes them from the storage once batchSize short URLs are collected
+// or flushInterval passes, with one storage call per batchSize short URLs. When ctx is done,
+// the workers flush the requests still queued and RunDeleter returns after all of them have stopped.
+func (app *App) RunDeleter(ctx context.Context, workers, batchSize int, flushInterval time.Duration) {
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			app.runDeleteWorker(ctx, batchSize, flushInterval)
+		}()
+	}
+	wg.Wait()
+}
+
+func (app *App) runDeleteWorker(ctx context.Context, batchSize int, flushInterval time.Duration) {
+	ticker := time.NewTicker(flushInterval)
+	defer ticker.Stop()
+
+	var batch []models.URLsClientID
+	collected := 0
+	flush := func() {
+		if len(batch) == 0 {
+			return
+		}
+		for _, chunk := range splitDeletions(batch, batchSize) {
+			app.deleteChunk(chunk)
+		}
+		app.pending.Add(-len(batch))
+		batch, collected = batch[:0], 0
+	}
+	add := func(deletion models.URLsClientID) {
+		batch = append(batch, deletion)
+		if collected += len(deletion.URLs); collected >= batchSize {
+			flush()
+		}
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			for {
+				select {
+				case deletion := <-app.deletions:
+					add(deletion)
+				default:
+					flush()
+					return
+				}
+			}
+		case deletion := <-app.deletions:
+			add(deletion)
+		case <-ticker.C:
+			flush()
+		}
+	}
+}
+
+// deleteChunk deletes one chunk of requests with a single storage call, records the results in their jobs
+// and logs the outcome.
+func (app *App) deleteChunk(chunk []models.URLsClientID) {
+	requested := 0
+	for _, deletion := range chunk {
+		requested += len(deletion.URLs)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	ctx, span := tracing.Start(ctx, "App.deleteChunk", attribute.Int("shortener.deletion.urls", requested))
+	app.jobs.start(chunk)
+	results, err := app.storage.DeleteURLs(ctx, chunk)
+	tracing.End(span, err)
+	app.jobs.complete(chunk, results, err)
+	if err != nil {
+		app.log.Sugar().Errorf("Failed to delete %d urls of %d requests: %s", requested, len(chunk), err)
+		return
+	}
+
+	deleted := 0
+	for _, requestResults := range results {
+		for _, result := range requestResults {
+			if result == models.DeletionDeleted {
+				deleted++
+			}
+		}
+	}
+	app.log.Sugar().Infof("Deleted %d of %d requested urls from %d requests", deleted, requested, len(chunk))
+}
+
+// splitDeletions splits the requests into chunks of at most batchSize short URLs, splitting a request if needed.
+func splitDeletions(batch []models.URLsClientID, batchSize int) (chunks [][]models.URLsClientID) {
+	var chunk []models.URLsClientID
+	size := 0
+	for _, deletion := range batch {
+		urls := deletion.URLs
+		for len(urls) > 0 {
+			n := min(batchSize-size, len(urls))
+			chunk = append(chunk, models.URLsClientID{URLs: urls[:n], ClientID: deletion.ClientID, JobID: deletion.JobID})
+			urls = urls[n:]
+			if size += n; size == batchSize {
+				chunks = append(chunks, chunk)
+				chunk, size = nil, 0
+			}
+		}
+	}
+	if len(chunk) > 0 {
+		chunks = append(chunks, chunk)
+	}
+	return chunks
+}
+
+// WaitDeletions is a method to block until all requested URL deletions are flushed or ctx is done.
+func (app *App) WaitDeletions(ctx context.Context) error {
+	done := make(chan struct{})
+	go func() {
+		app.pending.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,121 @@
+package app
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/DariSorokina/go-first-sprint/internal/config"
+	"github.com/DariSorokina/go-first-sprint/internal/logger"
+	"github.com/DariSorokina/go-first-sprint/internal/models"
+	"github.com/DariSorokina/go-first-sprint/internal/storage"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRunDeleter(t *testing.T) {
+	l, err := logger.CreateLogger("error")
+	require.NoError(t, err)
+
+	ctx := context.Background()
+	store := storage.NewStorage("", l)
+	app, err := NewApp(store, config.NewFlagConfig(), l)
+	require.NoError(t, err)
+
+	first, err := app.ToShortenURL(ctx, "https://example.com/first", 1, time.Time{})
+	require.NoError(t, err)
+	second, err := app.ToShortenURL(ctx, "https://example.com/second", 2, time.Time{})
+	require.NoError(t, err)
+
+	deleterCtx, stop := context.WithCancel(ctx)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		app.RunDeleter(deleterCtx, 2, 1000, time.Hour)
+	}()
+
+	firstJob, err := app.DeleteURLs([]string{first, second}, 1)
+	require.NoError(t, err)
+	secondJob, err := app.DeleteURLs([]string{second}, 2)
+	require.NoError(t, err)
+
+	job, err := app.GetDeleteJob(ctx, firstJob, 1)
+	require.NoError(t, err)
+	assert.Equal(t, models.DeleteJobQueued, job.Status)
+	_, err = app.GetDeleteJob(ctx, firstJob, 2)
+	assert.ErrorIs(t, err, ErrDeleteJobNotFound, "jobs of other users are not visible")
+
+	// The flush interval is never reached, the queued requests are flushed on stop.
+	stop()
+	<-done
+
+	waitCtx, cancel := context.WithTimeout(ctx, time.Second)
+	defer cancel()
+	require.NoError(t, app.WaitDeletions(waitCtx))
+
+	_, err = app.ToOriginalURL(ctx, first)
+	assert.ErrorIs(t, err, storage.ErrDeletedURL)
+	_, err = app.ToOriginalURL(ctx, second)
+	assert.ErrorIs(t, err, storage.ErrDeletedURL)
+
+	job, err = app.GetDeleteJob(ctx, firstJob, 1)
+	require.NoError(t, err)
+	assert.Equal(t, models.DeleteJobDone, job.Status)
+	assert.Equal(t, map[string]string{first: models.DeletionDeleted, second: models.DeletionNotOwned}, job.Results)
+	assert.NotNil(t, job.FinishedAt)
+
+	job, err = app.GetDeleteJob(ctx, secondJob, 2)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{second: models.DeletionDeleted}, job.Results)
+}
+
+func TestDeleteJobsRetention(t *testing.T) {
+	jobs := newDeleteJobs(time.Minute)
+
+	queued, err := jobs.create(1, []string{"aaaaa"})
+	require.NoError(t, err)
+	finished, err := jobs.create(1, nil)
+	require.NoError(t, err)
+
+	job, ok := jobs.get(finished, 1)
+	require.True(t, ok)
+	assert.Equal(t, models.DeleteJobDone, job.Status, "a job without short URLs is done at once")
+
+	assert.Zero(t, jobs.purge(time.Now()), "finished jobs are kept for the retention time")
+	assert.Equal(t, 1, jobs.purge(time.Now().Add(2*time.Minute)))
+	_, ok = jobs.get(finished, 1)
+	assert.False(t, ok, "finished jobs are forgotten after the retention time, even behind an unfinished one")
+	_, ok = jobs.get(queued, 1)
+	assert.True(t, ok, "unfinished jobs are kept")
+}
+
+func TestDeleteURLsQueueFull(t *testing.T) {
+	l, err := logger.CreateLogger("error")
+	require.NoError(t, err)
+
+	flagConfig := config.NewFlagConfig()
+	flagConfig.FlagDeleteQueueSize = 1
+	app, err := NewApp(storage.NewStorage("", l), flagConfig, l)
+	require.NoError(t, err)
+
+	_, err = app.DeleteURLs([]string{"aaaaa"}, 1)
+	require.NoError(t, err)
+	jobID, err := app.DeleteURLs([]string{"bbbbb"}, 1)
+	assert.ErrorIs(t, err, ErrDeleteQueueFull)
+	assert.Empty(t, jobID)
+	assert.Equal(t, 1, app.DeleteQueueDepth())
+}
+
+func TestSplitDeletions(t *testing.T) {
+	chunks := splitDeletions([]models.URLsClientID{
+		{URLs: []string{"a", "b", "c"}, ClientID: 1},
+		{URLs: []string{"d"}, ClientID: 2},
+		{URLs: []string{"e", "f"}, ClientID: 3},
+	}, 2)
+
+	assert.Equal(t, [][]models.URLsClientID{
+		{{URLs: []string{"a", "b"}, ClientID: 1}},
+		{{URLs: []string{"c"}, ClientID: 1}, {URLs: []string{"d"}, ClientID: 2}},
+		{{URLs: []string{"e", "f"}, ClientID: 3}},
+	}, chunks)
+}
@@ -0,0 +1,78 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// ErrInvalidExpiration indicates that the requested expiration can not be applied to a short URL.
+var ErrInvalidExpiration = errors.New("invalid expiration")
+
+// ExpirationTime converts the optional expires_at and ttl_seconds request fields into an absolute expiration time.
+// It returns the zero time if neither is set, meaning the short URL never expires.
+func ExpirationTime(expiresAt *time.Time, ttlSeconds int64) (time.Time, error) {
+	switch {
+	case expiresAt != nil && ttlSeconds != 0:
+		return time.Time{}, fmt.Errorf("%w: expires_at and ttl_seconds can not be used together", ErrInvalidExpiration)
+	case ttlSeconds < 0:
+		return time.Time{}, fmt.Errorf("%w: ttl_seconds must be positive", ErrInvalidExpiration)
+	case ttlSeconds > 0:
+		return time.Now().Add(time.Duration(ttlSeconds) * time.Second), nil
+	case expiresAt != nil:
+		if !expiresAt.After(time.Now()) {
+			return time.Time{}, fmt.Errorf("%w: expires_at must be in the future", ErrInvalidExpiration)
+		}
+		return *expiresAt, nil
+	default:
+		return time.Time{}, nil
+	}
+}
+
+// RunJanitor is a method to periodically remove short URLs expired longer ago than the retention period
+// and short URLs deleted longer ago than the restore grace period from the storage, and to forget finished
+// deletion jobs past their retention time, until ctx is done. The configuration requires a positive interval;
+// a non-positive one disables the janitor.
+func (app *App) RunJanitor(ctx context.Context, interval time.Duration) {
+	if interval <= 0 {
+		return
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			app.cleanUp(ctx, now)
+		}
+	}
+}
+
+// cleanUp removes the short URLs expired before the retention period, purges the ones deleted before the grace period,
+// and forgets the finished deletion jobs past their retention time. The steps are independent, so a failure of one
+// does not skip the others.
+func (app *App) cleanUp(ctx context.Context, now time.Time) {
+	deleted, err := app.storage.DeleteExpired(ctx, now.Add(-app.retention))
+	switch {
+	case err != nil:
+		app.log.Sugar().Errorf("Failed to delete expired urls: %s", err)
+	case deleted > 0:
+		app.log.Sugar().Infof("Deleted expired urls: %d", deleted)
+	}
+
+	purged, err := app.storage.PurgeDeleted(ctx, now.Add(-app.grace))
+	switch {
+	case err != nil:
+		app.log.Sugar().Errorf("Failed to purge deleted urls: %s", err)
+	case purged > 0:
+		app.log.Sugar().Infof("Purged deleted urls: %d", purged)
+	}
+
+	if purged := app.jobs.purge(now); purged > 0 {
+		app.log.Sugar().Infof("Forgot finished deletion jobs: %d", purged)
+	}
+}
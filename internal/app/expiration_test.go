@@ -0,0 +1,83 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/DariSorokina/go-first-sprint/internal/config"
+	"github.com/DariSorokina/go-first-sprint/internal/logger"
+	"github.com/DariSorokina/go-first-sprint/internal/models"
+	"github.com/DariSorokina/go-first-sprint/internal/storage"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// failingExpiryStorage is a storage that can not delete expired short URLs.
+type failingExpiryStorage struct {
+	*storage.Storage
+}
+
+func (failingExpiryStorage) DeleteExpired(ctx context.Context, now time.Time) (int, error) {
+	return 0, errors.New("connection refused")
+}
+
+func TestCleanUpKeepsExpiredURLsForRetention(t *testing.T) {
+	l, err := logger.CreateLogger("fatal")
+	require.NoError(t, err)
+
+	ctx := context.Background()
+	store := storage.NewStorage("", l)
+	_, err = store.SetValue(ctx, "aaaaa", "https://example.com/a", 1, time.Now().Add(-time.Minute))
+	require.NoError(t, err)
+
+	app, err := NewApp(store, config.NewFlagConfig(), l)
+	require.NoError(t, err)
+
+	app.cleanUp(ctx, time.Now())
+	_, err = store.GetOriginal(ctx, "aaaaa")
+	assert.ErrorIs(t, err, storage.ErrExpiredURL, "the expired short URL is kept during the retention period")
+
+	app.cleanUp(ctx, time.Now().Add(app.retention))
+	_, err = store.GetOriginal(ctx, "aaaaa")
+	assert.ErrorIs(t, err, storage.ErrURLNotFound)
+}
+
+func TestCleanUpPurgesWhenExpiryFails(t *testing.T) {
+	l, err := logger.CreateLogger("fatal")
+	require.NoError(t, err)
+
+	ctx := context.Background()
+	store := storage.NewStorage("", l)
+	_, err = store.SetValue(ctx, "aaaaa", "https://example.com/a", 1, time.Time{})
+	require.NoError(t, err)
+	_, err = store.DeleteURLs(ctx, []models.URLsClientID{{URLs: []string{"aaaaa"}, ClientID: 1}})
+	require.NoError(t, err)
+
+	app, err := NewApp(failingExpiryStorage{store}, config.NewFlagConfig(), l)
+	require.NoError(t, err)
+	app.cleanUp(ctx, time.Now().Add(app.grace+time.Minute))
+
+	_, err = store.GetOriginal(ctx, "aaaaa")
+	assert.ErrorIs(t, err, storage.ErrURLNotFound, "the deleted short URL is purged although expiry failed")
+}
+
+func TestCleanUpForgetsFinishedDeleteJobs(t *testing.T) {
+	l, err := logger.CreateLogger("fatal")
+	require.NoError(t, err)
+
+	ctx := context.Background()
+	app, err := NewApp(storage.NewStorage("", l), config.NewFlagConfig(), l)
+	require.NoError(t, err)
+	jobID, err := app.DeleteURLs(nil, 1)
+	require.NoError(t, err)
+
+	app.cleanUp(ctx, time.Now())
+	_, err = app.GetDeleteJob(ctx, jobID, 1)
+	require.NoError(t, err, "the finished job is kept for its retention time")
+
+	app.cleanUp(ctx, time.Now().Add(app.jobs.retention))
+	_, err = app.GetDeleteJob(ctx, jobID, 1)
+	assert.ErrorIs(t, err, ErrDeleteJobNotFound)
+}
@@ -0,0 +1,142 @@
+package app
+
+import (
+	"crypto/md5"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// Supported short code generator modes.
+const (
+	GeneratorHash     = "hash"     // Truncated MD5 digest of the original URL.
+	GeneratorSequence = "sequence" // Base62 of a monotonically increasing ID, for a single instance only.
+	GeneratorRandom   = "random"   // Random base62 string.
+)
+
+const base62Alphabet = "0123456789abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ"
+
+// maxGenerateAttempts limits how many codes are tried before giving up on a collision.
+const maxGenerateAttempts = 10
+
+// ErrShortURLGeneration indicates that no free short code was found within maxGenerateAttempts.
+var ErrShortURLGeneration = errors.New("failed to generate a unique short url")
+
+// CodeGenerator is a set of method signatures for short code generation.
+// Attempt is zero for the first try and is increased on every collision,
+// so deterministic generators can produce a different code on retry.
+type CodeGenerator interface {
+	Generate(longURL string, attempt int) (string, error)
+}
+
+// NewCodeGenerator creates a CodeGenerator of the given mode producing codes of the given length.
+// The sequence generator needs a length of at least the base62 length of its seed, currently 7 characters.
+func NewCodeGenerator(mode string, length int) (CodeGenerator, error) {
+	if length <= 0 {
+		return nil, fmt.Errorf("short url length must be positive, got %d", length)
+	}
+
+	switch mode {
+	case GeneratorHash:
+		if length > md5.Size*2 {
+			return nil, fmt.Errorf("short url length for %s generator must not exceed %d, got %d", mode, md5.Size*2, length)
+		}
+		return &hashGenerator{length: length}, nil
+	case GeneratorSequence:
+		start := time.Now()
+		// Shorter codes would silently come out longer than configured.
+		if minLength := len(encodeBase62(uint64(start.UnixMilli()))); length < minLength {
+			return nil, fmt.Errorf("short url length for %s generator must be at least %d, got %d", mode, minLength, length)
+		}
+		return newSequenceGenerator(length, start), nil
+	case GeneratorRandom:
+		return &randomGenerator{length: length}, nil
+	default:
+		return nil, fmt.Errorf("unknown short url generator %q", mode)
+	}
+}
+
+type hashGenerator struct {
+	length int
+}
+
+// Generate returns the first length hex characters of the MD5 digest of longURL.
+// On retry the attempt number is appended to longURL before hashing.
+func (g *hashGenerator) Generate(longURL string, attempt int) (string, error) {
+	data := longURL
+	if attempt > 0 {
+		data += strconv.Itoa(attempt)
+	}
+	encodedMD5 := md5.Sum([]byte(data))
+	return fmt.Sprintf("%x", encodedMD5)[:g.length], nil
+}
+
+// sequenceGenerator keeps its counter in memory and is meant for a single instance.
+// Instances sharing a storage issue the same IDs, which then only resolve through collision retries.
+type sequenceGenerator struct {
+	length  int
+	counter atomic.Uint64
+}
+
+// newSequenceGenerator seeds the counter with the start time in milliseconds, so a restarted
+// instance keeps issuing increasing IDs instead of starting over, as long as it issued fewer
+// IDs than milliseconds passed since it started.
+func newSequenceGenerator(length int, start time.Time) *sequenceGenerator {
+	g := &sequenceGenerator{length: length}
+	g.counter.Store(uint64(start.UnixMilli()))
+	return g
+}
+
+// Generate returns base62 of the next ID, left-padded with zeros to the configured length.
+func (g *sequenceGenerator) Generate(longURL string, attempt int) (string, error) {
+	code := encodeBase62(g.counter.Add(1))
+	if len(code) < g.length {
+		code = strings.Repeat(string(base62Alphabet[0]), g.length-len(code)) + code
+	}
+	return code, nil
+}
+
+type randomGenerator struct {
+	length int
+}
+
+// Generate returns a random base62 string of the configured length.
+func (g *randomGenerator) Generate(longURL string, attempt int) (string, error) {
+	code := make([]byte, 0, g.length)
+	buf := make([]byte, g.length)
+	for len(code) < g.length {
+		if _, err := rand.Read(buf); err != nil {
+			return "", err
+		}
+		for _, b := range buf {
+			// Skip bytes above the largest multiple of 62 to keep the distribution uniform.
+			if int(b) >= 256-256%len(base62Alphabet) {
+				continue
+			}
+			code = append(code, base62Alphabet[int(b)%len(base62Alphabet)])
+			if len(code) == g.length {
+				break
+			}
+		}
+	}
+	return string(code), nil
+}
+
+func encodeBase62(n uint64) string {
+	if n == 0 {
+		return string(base62Alphabet[0])
+	}
+	var encoded []byte
+	for n > 0 {
+		encoded = append(encoded, base62Alphabet[n%uint64(len(base62Alphabet))])
+		n /= uint64(len(base62Alphabet))
+	}
+	for i, j := 0, len(encoded)-1; i < j; i, j = i+1, j-1 {
+		encoded[i], encoded[j] = encoded[j], encoded[i]
+	}
+	return string(encoded)
+}
@@ -0,0 +1,78 @@
+package app
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCodeGenerators(t *testing.T) {
+	testCases := []struct {
+		mode   string
+		length int
+	}{
+		{mode: GeneratorHash, length: 10},
+		{mode: GeneratorSequence, length: 12},
+		{mode: GeneratorRandom, length: 8},
+	}
+	for _, test := range testCases {
+		t.Run(test.mode, func(t *testing.T) {
+			generator, err := NewCodeGenerator(test.mode, test.length)
+			require.NoError(t, err)
+
+			first, err := generator.Generate("https://practicum.yandex.ru/", 0)
+			require.NoError(t, err)
+			second, err := generator.Generate("https://practicum.yandex.ru/", 1)
+			require.NoError(t, err)
+
+			assert.Len(t, first, test.length)
+			assert.Len(t, second, test.length)
+			assert.NotEqual(t, first, second)
+		})
+	}
+}
+
+func TestHashGeneratorKeepsLegacyCodes(t *testing.T) {
+	generator, err := NewCodeGenerator(GeneratorHash, 10)
+	require.NoError(t, err)
+
+	code, err := generator.Generate("", 0)
+	require.NoError(t, err)
+	assert.Equal(t, "d41d8cd98f", code)
+}
+
+func TestSequenceGeneratorSurvivesRestart(t *testing.T) {
+	start := time.Now()
+	issued := make(map[string]bool)
+
+	generator := newSequenceGenerator(10, start)
+	for attempt := 0; attempt < 100; attempt++ {
+		code, err := generator.Generate("https://practicum.yandex.ru/", attempt)
+		require.NoError(t, err)
+		issued[code] = true
+	}
+
+	restarted := newSequenceGenerator(10, start.Add(time.Second))
+	for attempt := 0; attempt < 100; attempt++ {
+		code, err := restarted.Generate("https://practicum.yandex.ru/", attempt)
+		require.NoError(t, err)
+		assert.False(t, issued[code], "code %s was issued before the restart", code)
+	}
+	assert.Greater(t, restarted.counter.Load(), generator.counter.Load())
+}
+
+func TestNewCodeGeneratorValidation(t *testing.T) {
+	_, err := NewCodeGenerator("unknown", 10)
+	assert.Error(t, err)
+
+	_, err = NewCodeGenerator(GeneratorRandom, 0)
+	assert.Error(t, err)
+
+	_, err = NewCodeGenerator(GeneratorHash, 33)
+	assert.Error(t, err)
+
+	_, err = NewCodeGenerator(GeneratorSequence, 6)
+	assert.Error(t, err, "codes of the millisecond seed do not fit")
+}
@@ -0,0 +1,21 @@
+package app
+
+import (
+	"context"
+
+	"github.com/DariSorokina/go-first-sprint/internal/tracing"
+)
+
+// CreateUser is a method to register a new user and get the ID issued to it by the storage.
+func (app *App) CreateUser(ctx context.Context) (userID int, err error) {
+	ctx, span := tracing.Start(ctx, "App.CreateUser")
+	defer func() { tracing.End(span, err) }()
+	return app.storage.CreateUser(ctx)
+}
+
+// UserExists is a method to check that the user ID was issued by the storage.
+func (app *App) UserExists(ctx context.Context, userID int) (exists bool, err error) {
+	ctx, span := tracing.Start(ctx, "App.UserExists")
+	defer func() { tracing.End(span, err) }()
+	return app.storage.UserExists(ctx, userID)
+}
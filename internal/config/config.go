@@ -1,9 +1,16 @@
-// Package config provides functionality for managing configuration flags and environment variables.
+// Package config provides functionality for managing configuration flags, environment variables
+// and the JSON configuration file.
 package config
 
 import (
 	"flag"
+	"fmt"
+	"net"
 	"os"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
 )
 
 // FlagConfig is a structure containing configuration flags for the server.
@@ -13,37 +20,420 @@ type FlagConfig struct {
 	FlagLogLevel        string
 	FlagFileStoragePath string
 	FlagPostgresqlDSN   string
+	FlagCodeGenerator   string
+	FlagCodeLength      int
+	FlagJanitorInterval time.Duration
+	FlagExpiryRetention time.Duration
+	FlagClickBufferSize int
+	FlagClickFlush      time.Duration
+	FlagClickIPKey      string
+	FlagDeleteFlush     time.Duration
+	FlagDeleteWorkers   int
+	FlagDeleteBatchSize int
+	FlagDeleteQueueSize int
+	FlagDeleteJobTTL    time.Duration
+	FlagRestoreGrace    time.Duration
+	FlagShutdownTimeout time.Duration
+	FlagEnableHTTPS     bool
+	FlagTLSCertFile     string
+	FlagTLSKeyFile      string
+	FlagTrustedSubnet   string
+	FlagTrustedProxies  string
+	FlagGRPCAddr        string
+	FlagJWTKeys         string
+	FlagTokenLifetime   time.Duration
+	FlagCookieSecure    bool
+	FlagCookieSameSite  string
+	FlagCookieDomain    string
+	FlagRateLimitsUser  string
+	FlagRateLimitsIP    string
+	FlagMetricsAddr     string
+	FlagTraceExporter   string
+	FlagTraceEndpoint   string
+	FlagTraceInsecure   bool
+	FlagTraceFile       string
+
+	FlagConfigFile  string // Path to the JSON configuration file, set by -c or CONFIG only.
+	FlagPrintConfig bool   // Print the effective configuration and exit, set by -print-config only.
+}
+
+// option describes a setting that can be given as a command-line flag, an environment variable
+// or a key in the JSON configuration file. The file key is the lower-cased environment variable name.
+type option struct {
+	flag   string
+	env    string
+	usage  string
+	secret bool
+	value  func(flagConfig *FlagConfig) any
+}
+
+func (o option) key() string {
+	return strings.ToLower(o.env)
+}
+
+var options = []option{
+	{flag: "a", env: "SERVER_ADDRESS", usage: "address and port to run server",
+		value: func(c *FlagConfig) any { return &c.FlagRunAddr }},
+	{flag: "b", env: "BASE_URL", usage: "prefix for trimed URL",
+		value: func(c *FlagConfig) any { return &c.FlagBaseURL }},
+	{flag: "l", env: "LOG_LEVEL", usage: "log level",
+		value: func(c *FlagConfig) any { return &c.FlagLogLevel }},
+	{flag: "f", env: "FILE_STORAGE_PATH", usage: "file storage path",
+		value: func(c *FlagConfig) any { return &c.FlagFileStoragePath }},
+	{flag: "d", env: "DATABASE_DSN", usage: "postgreSQL DSN", secret: true,
+		value: func(c *FlagConfig) any { return &c.FlagPostgresqlDSN }},
+	{flag: "code-generator", env: "CODE_GENERATOR", usage: "short url generator: hash, sequence (single instance only) or random",
+		value: func(c *FlagConfig) any { return &c.FlagCodeGenerator }},
+	{flag: "code-length", env: "CODE_LENGTH", usage: "short url length, at least 7 for the sequence generator",
+		value: func(c *FlagConfig) any { return &c.FlagCodeLength }},
+	{flag: "janitor-interval", env: "JANITOR_INTERVAL", usage: "interval between removals of expired urls and purges of deleted ones",
+		value: func(c *FlagConfig) any { return &c.FlagJanitorInterval }},
+	{flag: "expiry-retention", env: "EXPIRY_RETENTION", usage: "time an expired url keeps answering as expired before it is removed",
+		value: func(c *FlagConfig) any { return &c.FlagExpiryRetention }},
+	{flag: "click-buffer-size", env: "CLICK_BUFFER_SIZE", usage: "number of click events buffered before new ones are dropped",
+		value: func(c *FlagConfig) any { return &c.FlagClickBufferSize }},
+	{flag: "click-flush-interval", env: "CLICK_FLUSH_INTERVAL", usage: "interval between click event flushes",
+		value: func(c *FlagConfig) any { return &c.FlagClickFlush }},
+	{flag: "click-ip-key", env: "CLICK_IP_KEY", usage: "key of the hashes of client networks in click events, a random one per start if empty", secret: true,
+		value: func(c *FlagConfig) any { return &c.FlagClickIPKey }},
+	{flag: "delete-flush-interval", env: "DELETE_FLUSH_INTERVAL", usage: "interval between flushes of collected url deletions",
+		value: func(c *FlagConfig) any { return &c.FlagDeleteFlush }},
+	{flag: "delete-workers", env: "DELETE_WORKERS", usage: "number of workers deleting urls",
+		value: func(c *FlagConfig) any { return &c.FlagDeleteWorkers }},
+	{flag: "delete-batch-size", env: "DELETE_BATCH_SIZE", usage: "maximum number of urls deleted with one storage call",
+		value: func(c *FlagConfig) any { return &c.FlagDeleteBatchSize }},
+	{flag: "delete-queue-size", env: "DELETE_QUEUE_SIZE", usage: "number of deletion requests queued before new ones are rejected",
+		value: func(c *FlagConfig) any { return &c.FlagDeleteQueueSize }},
+	{flag: "delete-job-retention", env: "DELETE_JOB_RETENTION", usage: "time the state of a finished deletion job is kept",
+		value: func(c *FlagConfig) any { return &c.FlagDeleteJobTTL }},
+	{flag: "restore-grace-period", env: "RESTORE_GRACE_PERIOD", usage: "time a deleted url can be restored before it is purged",
+		value: func(c *FlagConfig) any { return &c.FlagRestoreGrace }},
+	{flag: "shutdown-timeout", env: "SHUTDOWN_TIMEOUT", usage: "time to finish in-flight requests and deletions on shutdown",
+		value: func(c *FlagConfig) any { return &c.FlagShutdownTimeout }},
+	{flag: "s", env: "ENABLE_HTTPS", usage: "serve HTTPS",
+		value: func(c *FlagConfig) any { return &c.FlagEnableHTTPS }},
+	{flag: "tls-cert", env: "TLS_CERT_FILE", usage: "TLS certificate file, a self-signed certificate is generated if empty",
+		value: func(c *FlagConfig) any { return &c.FlagTLSCertFile }},
+	{flag: "tls-key", env: "TLS_KEY_FILE", usage: "TLS private key file",
+		value: func(c *FlagConfig) any { return &c.FlagTLSKeyFile }},
+	{flag: "t", env: "TRUSTED_SUBNET", usage: "CIDR of client IPs allowed to reach internal endpoints, empty denies all",
+		value: func(c *FlagConfig) any { return &c.FlagTrustedSubnet }},
+	{flag: "trusted-proxies", env: "TRUSTED_PROXIES", usage: "comma-separated CIDRs of proxies whose X-Real-IP and X-Forwarded-For headers give the client IP, empty trusts none",
+		value: func(c *FlagConfig) any { return &c.FlagTrustedProxies }},
+	{flag: "grpc-address", env: "GRPC_ADDRESS", usage: "address and port to run gRPC server, empty disables it",
+		value: func(c *FlagConfig) any { return &c.FlagGRPCAddr }},
+	{flag: "jwt-keys", env: "JWT_KEYS", usage: "comma-separated JWT signing keys as kid=secret, the first one signs new tokens", secret: true,
+		value: func(c *FlagConfig) any { return &c.FlagJWTKeys }},
+	{flag: "token-lifetime", env: "TOKEN_LIFETIME", usage: "lifetime of issued JWTs and their cookies",
+		value: func(c *FlagConfig) any { return &c.FlagTokenLifetime }},
+	{flag: "cookie-secure", env: "COOKIE_SECURE", usage: "send the user cookie over HTTPS only, defaults to the serving scheme",
+		value: func(c *FlagConfig) any { return &c.FlagCookieSecure }},
+	{flag: "cookie-samesite", env: "COOKIE_SAMESITE", usage: "SameSite attribute of the user cookie: lax, strict, none or empty to omit it",
+		value: func(c *FlagConfig) any { return &c.FlagCookieSameSite }},
+	{flag: "cookie-domain", env: "COOKIE_DOMAIN", usage: "Domain attribute of the user cookie, empty for the host only",
+		value: func(c *FlagConfig) any { return &c.FlagCookieDomain }},
+	{flag: "rate-limits-user", env: "RATE_LIMITS_USER", usage: "comma-separated request budgets per user as route=requests/period, empty disables them",
+		value: func(c *FlagConfig) any { return &c.FlagRateLimitsUser }},
+	{flag: "rate-limits-ip", env: "RATE_LIMITS_IP", usage: "comma-separated request budgets per client IP as route=requests/period, empty disables them",
+		value: func(c *FlagConfig) any { return &c.FlagRateLimitsIP }},
+	{flag: "metrics-address", env: "METRICS_ADDRESS", usage: "address and port to serve Prometheus metrics on /metrics, empty disables it",
+		value: func(c *FlagConfig) any { return &c.FlagMetricsAddr }},
+	{flag: "trace-exporter", env: "TRACE_EXPORTER", usage: "exporter of OpenTelemetry traces: otlp, stdout or empty to disable tracing",
+		value: func(c *FlagConfig) any { return &c.FlagTraceExporter }},
+	{flag: "trace-endpoint", env: "TRACE_ENDPOINT", usage: "host and port of the OTLP gRPC collector receiving traces",
+		value: func(c *FlagConfig) any { return &c.FlagTraceEndpoint }},
+	{flag: "trace-insecure", env: "TRACE_INSECURE", usage: "send traces to the OTLP collector without TLS",
+		value: func(c *FlagConfig) any { return &c.FlagTraceInsecure }},
+	{flag: "trace-file", env: "TRACE_FILE", usage: "file the stdout trace exporter appends to, empty for the standard output",
+		value: func(c *FlagConfig) any { return &c.FlagTraceFile }},
 }
 
-// NewFlagConfig is a constructor function to create a new FlagConfig instance.
+// DefaultJWTKeys is the well-known key ring used unless JWT_KEYS is set. It must be replaced in production.
+const DefaultJWTKeys = "default=supersecretkey"
+
+// NewFlagConfig is a constructor function to create a new FlagConfig instance filled with default values.
 func NewFlagConfig() *FlagConfig {
-	return &FlagConfig{}
+	return &FlagConfig{
+		FlagRunAddr:         ":8080",
+		FlagBaseURL:         "http://localhost:8080/",
+		FlagLogLevel:        "info",
+		FlagFileStoragePath: "/tmp/short-url-db.json",
+		FlagCodeGenerator:   "hash",
+		FlagCodeLength:      10,
+		FlagJanitorInterval: time.Minute,
+		FlagExpiryRetention: 30 * 24 * time.Hour,
+		FlagClickBufferSize: 1024,
+		FlagClickFlush:      5 * time.Second,
+		FlagDeleteFlush:     500 * time.Millisecond,
+		FlagDeleteWorkers:   2,
+		FlagDeleteBatchSize: 1000,
+		FlagDeleteQueueSize: 1024,
+		FlagDeleteJobTTL:    time.Hour,
+		FlagRestoreGrace:    24 * time.Hour,
+		FlagShutdownTimeout: 10 * time.Second,
+		FlagJWTKeys:         DefaultJWTKeys,
+		FlagTokenLifetime:   3 * time.Hour,
+		FlagCookieSameSite:  "lax",
+		FlagTraceEndpoint:   "localhost:4317",
+	}
 }
 
-// ParseFlags is a function to parse command-line flags and environment variables into FlagConfig.
-func ParseFlags() (flagConfig *FlagConfig) {
-	flagConfig = NewFlagConfig()
-	flag.StringVar(&flagConfig.FlagRunAddr, "a", ":8080", "address and port to run server")
-	flag.StringVar(&flagConfig.FlagBaseURL, "b", "http://localhost:8080/", "prefix for trimed URL")
-	flag.StringVar(&flagConfig.FlagLogLevel, "l", "info", "log level")
-	flag.StringVar(&flagConfig.FlagFileStoragePath, "f", "/tmp/short-url-db.json", "file storage path")
-	flag.StringVar(&flagConfig.FlagPostgresqlDSN, "d", "", "postgreSQL DSN")
-	flag.Parse()
+// ParseFlags is a function to parse command-line flags, environment variables and the JSON configuration file
+// into FlagConfig. A flag takes precedence over an environment variable, which takes precedence over the file,
+// which takes precedence over the default value.
+func ParseFlags() (flagConfig *FlagConfig, err error) {
+	return ParseArgs(os.Args[1:])
+}
 
-	if envRunAddr := os.Getenv("SERVER_ADDRESS"); envRunAddr != "" {
-		flagConfig.FlagRunAddr = envRunAddr
+// ParseArgs is like ParseFlags but parses the given arguments instead of the process ones,
+// for example the ones following a subcommand. Positional arguments are available from flag.Args.
+func ParseArgs(args []string) (flagConfig *FlagConfig, err error) {
+	return parse(flag.CommandLine, args, os.Getenv)
+}
+
+func parse(flagSet *flag.FlagSet, args []string, getenv func(string) string) (*FlagConfig, error) {
+	flags := NewFlagConfig()
+	for _, o := range options {
+		switch value := o.value(flags).(type) {
+		case *string:
+			flagSet.StringVar(value, o.flag, *value, o.usage)
+		case *int:
+			flagSet.IntVar(value, o.flag, *value, o.usage)
+		case *bool:
+			flagSet.BoolVar(value, o.flag, *value, o.usage)
+		case *time.Duration:
+			flagSet.DurationVar(value, o.flag, *value, o.usage)
+		}
 	}
-	if envBaseURL := os.Getenv("BASE_URL"); envBaseURL != "" {
-		flagConfig.FlagBaseURL = envBaseURL
+	flagSet.StringVar(&flags.FlagConfigFile, "c", "", "JSON configuration file")
+	flagSet.StringVar(&flags.FlagConfigFile, "config", "", "JSON configuration file")
+	flagSet.BoolVar(&flags.FlagPrintConfig, "print-config", false, "print the effective configuration with secrets redacted and exit")
+	if err := flagSet.Parse(args); err != nil {
+		return nil, err
 	}
-	if envLogLevel := os.Getenv("LOG_LEVEL"); envLogLevel != "" {
-		flagConfig.FlagLogLevel = envLogLevel
+
+	setFlags := make(map[string]bool)
+	flagSet.Visit(func(f *flag.Flag) {
+		setFlags[f.Name] = true
+	})
+
+	flagConfig := NewFlagConfig()
+	flagConfig.FlagPrintConfig = flags.FlagPrintConfig
+	flagConfig.FlagConfigFile = flags.FlagConfigFile
+	if envConfigFile := getenv("CONFIG"); envConfigFile != "" && !setFlags["c"] && !setFlags["config"] {
+		flagConfig.FlagConfigFile = envConfigFile
 	}
-	if envFileStoragePath := os.Getenv("FILE_STORAGE_PATH"); envFileStoragePath != "" {
-		flagConfig.FlagFileStoragePath = envFileStoragePath
+
+	setKeys := make(map[string]bool)
+	if flagConfig.FlagConfigFile != "" {
+		fileKeys, err := loadFile(flagConfig.FlagConfigFile, flagConfig)
+		if err != nil {
+			return nil, err
+		}
+		for _, key := range fileKeys {
+			setKeys[key] = true
+		}
+	}
+
+	for _, o := range options {
+		envValue := getenv(o.env)
+		if envValue == "" {
+			continue
+		}
+		if err := setFromString(o.value(flagConfig), envValue); err != nil {
+			return nil, fmt.Errorf("environment variable %s: %w", o.env, err)
+		}
+		setKeys[o.key()] = true
+	}
+
+	for _, o := range options {
+		if !setFlags[o.flag] {
+			continue
+		}
+		reflect.ValueOf(o.value(flagConfig)).Elem().Set(reflect.ValueOf(o.value(flags)).Elem())
+		setKeys[o.key()] = true
+	}
+
+	// The default base URL follows the serving scheme.
+	if flagConfig.FlagEnableHTTPS && !setKeys["base_url"] {
+		flagConfig.FlagBaseURL = "https://" + strings.TrimPrefix(flagConfig.FlagBaseURL, "http://")
 	}
-	if envPostgresqlDSN := os.Getenv("DATABASE_DSN"); envPostgresqlDSN != "" {
-		flagConfig.FlagPostgresqlDSN = envPostgresqlDSN
+	// So does the Secure attribute of the user cookie.
+	if !setKeys["cookie_secure"] {
+		flagConfig.FlagCookieSecure = flagConfig.FlagEnableHTTPS
+	}
+
+	return flagConfig, flagConfig.validate()
+}
+
+func setFromString(value any, s string) (err error) {
+	switch value := value.(type) {
+	case *string:
+		*value = s
+	case *int:
+		*value, err = strconv.Atoi(s)
+	case *bool:
+		*value, err = strconv.ParseBool(s)
+	case *time.Duration:
+		*value, err = time.ParseDuration(s)
+	}
+	return err
+}
+
+// validate checks values that can not be rejected by type alone and names the offending key.
+func (flagConfig *FlagConfig) validate() error {
+	switch {
+	case flagConfig.FlagRunAddr == "":
+		return fmt.Errorf("%s: must not be empty", "server_address")
+	case flagConfig.FlagBaseURL == "":
+		return fmt.Errorf("%s: must not be empty", "base_url")
+	case flagConfig.FlagCodeLength <= 0:
+		return fmt.Errorf("%s: must be positive, got %d", "code_length", flagConfig.FlagCodeLength)
+	case flagConfig.FlagJanitorInterval <= 0:
+		return fmt.Errorf("%s: must be positive, got %s", "janitor_interval", flagConfig.FlagJanitorInterval)
+	case flagConfig.FlagExpiryRetention <= 0:
+		return fmt.Errorf("%s: must be positive, got %s", "expiry_retention", flagConfig.FlagExpiryRetention)
+	case flagConfig.FlagClickBufferSize < 0:
+		return fmt.Errorf("%s: must not be negative, got %d", "click_buffer_size", flagConfig.FlagClickBufferSize)
+	case flagConfig.FlagClickFlush <= 0:
+		return fmt.Errorf("%s: must be positive, got %s", "click_flush_interval", flagConfig.FlagClickFlush)
+	case flagConfig.FlagDeleteFlush <= 0:
+		return fmt.Errorf("%s: must be positive, got %s", "delete_flush_interval", flagConfig.FlagDeleteFlush)
+	case flagConfig.FlagDeleteWorkers <= 0:
+		return fmt.Errorf("%s: must be positive, got %d", "delete_workers", flagConfig.FlagDeleteWorkers)
+	case flagConfig.FlagDeleteBatchSize <= 0:
+		return fmt.Errorf("%s: must be positive, got %d", "delete_batch_size", flagConfig.FlagDeleteBatchSize)
+	case flagConfig.FlagDeleteQueueSize <= 0:
+		return fmt.Errorf("%s: must be positive, got %d", "delete_queue_size", flagConfig.FlagDeleteQueueSize)
+	case flagConfig.FlagDeleteJobTTL <= 0:
+		return fmt.Errorf("%s: must be positive, got %s", "delete_job_retention", flagConfig.FlagDeleteJobTTL)
+	case flagConfig.FlagRestoreGrace <= 0:
+		return fmt.Errorf("%s: must be positive, got %s", "restore_grace_period", flagConfig.FlagRestoreGrace)
+	case flagConfig.FlagShutdownTimeout <= 0:
+		return fmt.Errorf("%s: must be positive, got %s", "shutdown_timeout", flagConfig.FlagShutdownTimeout)
+	case flagConfig.FlagTokenLifetime <= 0:
+		return fmt.Errorf("%s: must be positive, got %s", "token_lifetime", flagConfig.FlagTokenLifetime)
+	}
+	switch flagConfig.FlagCookieSameSite {
+	case "", "lax", "strict":
+	case "none":
+		if !flagConfig.FlagCookieSecure {
+			return fmt.Errorf("%s: none requires cookie_secure", "cookie_samesite")
+		}
+	default:
+		return fmt.Errorf("%s: must be lax, strict, none or empty, got %q", "cookie_samesite", flagConfig.FlagCookieSameSite)
+	}
+	switch flagConfig.FlagTraceExporter {
+	case "", "stdout":
+	case "otlp":
+		if flagConfig.FlagTraceEndpoint == "" {
+			return fmt.Errorf("%s: must not be empty for the otlp exporter", "trace_endpoint")
+		}
+	default:
+		return fmt.Errorf("%s: must be otlp, stdout or empty, got %q", "trace_exporter", flagConfig.FlagTraceExporter)
+	}
+	if _, err := ParseJWTKeys(flagConfig.FlagJWTKeys); err != nil {
+		return fmt.Errorf("%s: %w", "jwt_keys", err)
+	}
+	if _, err := ParseRateLimits(flagConfig.FlagRateLimitsUser); err != nil {
+		return fmt.Errorf("%s: %w", "rate_limits_user", err)
+	}
+	if _, err := ParseRateLimits(flagConfig.FlagRateLimitsIP); err != nil {
+		return fmt.Errorf("%s: %w", "rate_limits_ip", err)
+	}
+	if flagConfig.FlagTrustedSubnet != "" {
+		if _, _, err := net.ParseCIDR(flagConfig.FlagTrustedSubnet); err != nil {
+			return fmt.Errorf("%s: %w", "trusted_subnet", err)
+		}
+	}
+	if _, err := ParseTrustedProxies(flagConfig.FlagTrustedProxies); err != nil {
+		return fmt.Errorf("%s: %w", "trusted_proxies", err)
+	}
+	return nil
+}
+
+// JWTKey is a JWT signing key identified by the "kid" token header.
+type JWTKey struct {
+	ID     string
+	Secret string
+}
+
+// ParseJWTKeys parses a comma-separated key ring of kid=secret pairs, keeping their order.
+func ParseJWTKeys(keyRing string) (keys []JWTKey, err error) {
+	seen := make(map[string]bool)
+	for _, pair := range strings.Split(keyRing, ",") {
+		id, secret, ok := strings.Cut(strings.TrimSpace(pair), "=")
+		switch {
+		case !ok || id == "" || secret == "":
+			return nil, fmt.Errorf("key %d: must be kid=secret", len(keys)+1)
+		case seen[id]:
+			return nil, fmt.Errorf("key %d: duplicate kid %q", len(keys)+1, id)
+		}
+		seen[id] = true
+		keys = append(keys, JWTKey{ID: id, Secret: secret})
+	}
+	return keys, nil
+}
+
+// ParseTrustedProxies parses comma-separated subnets in CIDR notation. An empty list trusts no proxy.
+func ParseTrustedProxies(spec string) (subnets []*net.IPNet, err error) {
+	if strings.TrimSpace(spec) == "" {
+		return nil, nil
+	}
+	for _, cidr := range strings.Split(spec, ",") {
+		_, subnet, err := net.ParseCIDR(strings.TrimSpace(cidr))
+		if err != nil {
+			return nil, err
+		}
+		subnets = append(subnets, subnet)
+	}
+	return subnets, nil
+}
+
+// Routes with separate rate limits.
+const (
+	RateLimitShorten  = "shorten"
+	RateLimitBatch    = "batch"
+	RateLimitRedirect = "redirect"
+	RateLimitDelete   = "delete"
+)
+
+// RateLimit is a budget of requests refilled evenly over a period, allowing bursts of up to Requests.
+type RateLimit struct {
+	Requests int
+	Period   time.Duration
+}
+
+// ParseRateLimits parses comma-separated route=requests/period budgets, such as "shorten=60/1m,batch=10/1m".
+// Routes missing from the list are not limited.
+func ParseRateLimits(spec string) (limits map[string]RateLimit, err error) {
+	limits = make(map[string]RateLimit)
+	if strings.TrimSpace(spec) == "" {
+		return limits, nil
+	}
+	for _, pair := range strings.Split(spec, ",") {
+		route, budget, ok := strings.Cut(strings.TrimSpace(pair), "=")
+		requests, period, okBudget := strings.Cut(budget, "/")
+		if !ok || !okBudget {
+			return nil, fmt.Errorf("limit %q: must be route=requests/period", pair)
+		}
+		switch route {
+		case RateLimitShorten, RateLimitBatch, RateLimitRedirect, RateLimitDelete:
+		default:
+			return nil, fmt.Errorf("limit %q: unknown route %q", pair, route)
+		}
+		if _, ok = limits[route]; ok {
+			return nil, fmt.Errorf("limit %q: duplicate route %q", pair, route)
+		}
+
+		var limit RateLimit
+		if limit.Requests, err = strconv.Atoi(requests); err != nil || limit.Requests <= 0 {
+			return nil, fmt.Errorf("limit %q: requests must be a positive integer", pair)
+		}
+		if limit.Period, err = time.ParseDuration(period); err != nil || limit.Period <= 0 {
+			return nil, fmt.Errorf("limit %q: period must be a positive duration", pair)
+		}
+		limits[route] = limit
 	}
-	return
+	return limits, nil
 }
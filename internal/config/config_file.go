@@ -0,0 +1,98 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"os"
+	"reflect"
+	"regexp"
+	"sort"
+	"time"
+)
+
+// redactedValue replaces secrets in the printed configuration.
+const redactedValue = "xxxxx"
+
+// loadFile reads the JSON configuration file into flagConfig and returns the keys it contained.
+// Durations are given as strings such as "10s".
+func loadFile(fileName string, flagConfig *FlagConfig) (keys []string, err error) {
+	data, err := os.ReadFile(fileName)
+	if err != nil {
+		return nil, fmt.Errorf("config file %s: %w", fileName, err)
+	}
+
+	var values map[string]json.RawMessage
+	if err = json.Unmarshal(data, &values); err != nil {
+		return nil, fmt.Errorf("config file %s: %w", fileName, err)
+	}
+
+	optionsByKey := make(map[string]option, len(options))
+	for _, o := range options {
+		optionsByKey[o.key()] = o
+	}
+
+	for key, raw := range values {
+		o, ok := optionsByKey[key]
+		if !ok {
+			return nil, fmt.Errorf("config file %s: unknown key %q", fileName, key)
+		}
+
+		if duration, ok := o.value(flagConfig).(*time.Duration); ok {
+			var s string
+			if err = json.Unmarshal(raw, &s); err == nil {
+				*duration, err = time.ParseDuration(s)
+			}
+		} else {
+			err = json.Unmarshal(raw, o.value(flagConfig))
+		}
+		if err != nil {
+			return nil, fmt.Errorf("config file %s: key %q: %w", fileName, key, err)
+		}
+		keys = append(keys, key)
+	}
+
+	sort.Strings(keys)
+	return keys, nil
+}
+
+// Redacted returns the configuration as indented JSON using the configuration file keys,
+// with secrets such as database passwords replaced.
+func (flagConfig *FlagConfig) Redacted() string {
+	values := make(map[string]any, len(options))
+	for _, o := range options {
+		value := reflect.ValueOf(o.value(flagConfig)).Elem().Interface()
+		if duration, ok := value.(time.Duration); ok {
+			value = duration.String()
+		}
+		if s, ok := value.(string); ok && o.secret {
+			value = redactSecret(s)
+		}
+		values[o.key()] = value
+	}
+
+	data, err := json.MarshalIndent(values, "", "  ")
+	if err != nil {
+		return err.Error()
+	}
+	return string(data)
+}
+
+var passwordPattern = regexp.MustCompile(`password=\S+`)
+
+// redactSecret hides the password in URL and key/value DSNs and replaces any other non-empty secret entirely.
+func redactSecret(secret string) string {
+	if secret == "" {
+		return ""
+	}
+	if u, err := url.Parse(secret); err == nil && u.User != nil {
+		if _, ok := u.User.Password(); ok {
+			return u.Redacted()
+		}
+		return secret
+	}
+	if passwordPattern.MatchString(secret) {
+		return passwordPattern.ReplaceAllString(secret, "password="+redactedValue)
+	}
+	return redactedValue
+}
@@ -2,14 +2,15 @@
 package cookie
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"log"
-	"math/rand"
 	"net/http"
-	"strconv"
+	"strings"
 	"time"
 
+	"github.com/DariSorokina/go-first-sprint/internal/config"
 	"github.com/golang-jwt/jwt/v4"
 )
 
@@ -19,140 +20,249 @@ type Claims struct {
 	UserID int
 }
 
-// Constant TOKENEXP is used for specifying the token expiration time.
-const TOKENEXP = time.Hour * 3
+// Users issues user IDs and looks them up, so identities are shared by all instances and survive restarts.
+type Users interface {
+	CreateUser(ctx context.Context) (userID int, err error)
+	UserExists(ctx context.Context, userID int) (exists bool, err error)
+}
 
-// Constant TOKENEXP is used for specifying the secret key used for signing JWT.
-const SECRETKEY = "supersecretkey"
+type userIDContextKey struct{}
 
-var generatedUsersIDs = []int{1}
+// WithUserID returns a copy of ctx carrying the ID of the authenticated user.
+func WithUserID(ctx context.Context, userID int) context.Context {
+	return context.WithValue(ctx, userIDContextKey{}, userID)
+}
 
-func generateUserID() int {
-	randomNumber := rand.Intn(1000001)
-	return randomNumber
+// UserIDFromContext returns the ID of the user authenticated by CookieMiddleware.
+// It reports false if the request was not authenticated.
+func UserIDFromContext(ctx context.Context) (userID int, ok bool) {
+	userID, ok = ctx.Value(userIDContextKey{}).(int)
+	return userID, ok
 }
 
-func createJWTString(task string) (generatedUserID int, tokenString string, err error) {
-	if task != "test" {
-		generatedUserID = generateUserID()
-		generatedUsersIDs = append(generatedUsersIDs, generatedUserID)
-	} else {
-		generatedUserID = 1
+// BearerToken returns the token from an "Authorization: Bearer <token>" header value.
+func BearerToken(authorization string) (token string, ok bool) {
+	scheme, token, ok := strings.Cut(authorization, " ")
+	if !ok || !strings.EqualFold(scheme, "Bearer") || token == "" {
+		return "", false
 	}
+	return token, true
+}
 
+// Tokens signs JWTs with the current key of a key ring and verifies them with any key of the ring,
+// so tokens signed with an older key stay valid until they expire. It also builds the "ClientID" cookies
+// holding the tokens.
+type Tokens struct {
+	currentKeyID string            // ID of the key signing new tokens, sent in the "kid" header.
+	keys         map[string][]byte // Secrets of all keys accepted for verification by their IDs.
+	lifetime     time.Duration     // Lifetime of a token and of the cookie holding it.
+	secure       bool              // Secure attribute of the cookie.
+	sameSite     http.SameSite     // SameSite attribute of the cookie.
+	domain       string            // Domain attribute of the cookie.
+}
+
+// NewTokens creates a Tokens instance from the key ring, token lifetime and cookie attributes in flagConfig.
+func NewTokens(flagConfig *config.FlagConfig) (*Tokens, error) {
+	keys, err := config.ParseJWTKeys(flagConfig.FlagJWTKeys)
+	if err != nil {
+		return nil, err
+	}
+
+	tokens := &Tokens{
+		currentKeyID: keys[0].ID,
+		keys:         make(map[string][]byte, len(keys)),
+		lifetime:     flagConfig.FlagTokenLifetime,
+		secure:       flagConfig.FlagCookieSecure,
+		domain:       flagConfig.FlagCookieDomain,
+	}
+	for _, key := range keys {
+		tokens.keys[key.ID] = []byte(key.Secret)
+	}
+
+	switch flagConfig.FlagCookieSameSite {
+	case "lax":
+		tokens.sameSite = http.SameSiteLaxMode
+	case "strict":
+		tokens.sameSite = http.SameSiteStrictMode
+	case "none":
+		tokens.sameSite = http.SameSiteNoneMode
+	}
+	return tokens, nil
+}
+
+func (tokens *Tokens) createJWTString(userID int) (tokenString string, err error) {
 	token := jwt.NewWithClaims(jwt.SigningMethodHS256, Claims{
 		RegisteredClaims: jwt.RegisteredClaims{
-			ExpiresAt: jwt.NewNumericDate(time.Now().Add(TOKENEXP)),
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(tokens.lifetime)),
 		},
-		UserID: generatedUserID,
+		UserID: userID,
 	})
+	token.Header["kid"] = tokens.currentKeyID
 
-	tokenString, err = token.SignedString([]byte(SECRETKEY))
-	if err != nil {
-		return generatedUserID, "", err
+	return token.SignedString(tokens.keys[tokens.currentKeyID])
+}
+
+// key returns the secret of the key named by the "kid" header of the token.
+// Tokens without the header, issued before key rings were supported, are checked with the current key.
+func (tokens *Tokens) key(t *jwt.Token) (interface{}, error) {
+	if _, ok := t.Method.(*jwt.SigningMethodHMAC); !ok {
+		return nil, fmt.Errorf("unexpected signing method: %v", t.Header["alg"])
 	}
 
-	return generatedUserID, tokenString, nil
+	keyID := tokens.currentKeyID
+	if kid, ok := t.Header["kid"]; ok {
+		if keyID, ok = kid.(string); !ok {
+			return nil, fmt.Errorf("unexpected kid: %v", kid)
+		}
+	}
+	secret, ok := tokens.keys[keyID]
+	if !ok {
+		return nil, fmt.Errorf("unknown kid: %s", keyID)
+	}
+	return secret, nil
 }
 
-func getUserID(tokenString string) int {
+func (tokens *Tokens) getUserID(tokenString string) int {
 	claims := &Claims{}
 
-	token, err := jwt.ParseWithClaims(tokenString, claims,
-		func(t *jwt.Token) (interface{}, error) {
-			if _, ok := t.Method.(*jwt.SigningMethodHMAC); !ok {
-				return nil, fmt.Errorf("unexpected signing method: %v", t.Header["alg"])
-			}
-			return []byte(SECRETKEY), nil
-		})
-
-	if err != nil {
+	token, err := jwt.ParseWithClaims(tokenString, claims, tokens.key)
+	if err != nil || !token.Valid {
 		return -1
 	}
+	return claims.UserID
+}
 
-	if !token.Valid {
-		fmt.Println("Token is not valid")
-		return -1
+// NewUserToken registers a new user in users and returns its ID and a signed JWT holding it.
+func (tokens *Tokens) NewUserToken(ctx context.Context, users Users) (userID int, tokenString string, err error) {
+	userID, err = users.CreateUser(ctx)
+	if err != nil {
+		return 0, "", err
 	}
+	tokenString, err = tokens.createJWTString(userID)
+	return userID, tokenString, err
+}
 
-	fmt.Println("Token is valid")
-	return claims.UserID
+// UserIDFromToken returns the user ID from a JWT issued by this service.
+// It reports false if the token is invalid or the user ID is unknown to users,
+// and returns an error only if users can not be looked up.
+func (tokens *Tokens) UserIDFromToken(ctx context.Context, users Users, tokenString string) (userID int, ok bool, err error) {
+	userID = tokens.getUserID(tokenString)
+	if userID == -1 {
+		return userID, false, nil
+	}
+	ok, err = users.UserExists(ctx, userID)
+	return userID, ok, err
 }
 
-// СreateCookieClientID creates a JWT token and embeds it in an HTTP cookie.
-// Returns the generated user ID and the created cookie.
-func СreateCookieClientID(task string) (generatedUserID int, cookie *http.Cookie) {
-	generatedUserID, JWTString, err := createJWTString(task)
+// NewCookie creates a JWT token for the user and embeds it in an HTTP cookie that expires with the token.
+func (tokens *Tokens) NewCookie(userID int) (cookie *http.Cookie, err error) {
+	JWTString, err := tokens.createJWTString(userID)
 	if err != nil {
-		log.Println(err)
+		return nil, err
 	}
 	cookie = &http.Cookie{
 		Name:     "ClientID",
 		Value:    JWTString,
 		Path:     "/",
+		Domain:   tokens.domain,
 		HttpOnly: true,
-		MaxAge:   3600,
+		Secure:   tokens.secure,
+		SameSite: tokens.sameSite,
+		MaxAge:   int(tokens.lifetime.Seconds()),
 	}
 
-	return generatedUserID, cookie
+	return cookie, nil
 }
 
-func validateUserID(userID int) bool {
-	for _, id := range generatedUsersIDs {
-		if userID == id {
-			return true
-		}
+// newUserCookie registers a new user in users and returns its ID and a cookie holding its JWT.
+func (tokens *Tokens) newUserCookie(ctx context.Context, users Users) (userID int, cookie *http.Cookie, err error) {
+	userID, err = users.CreateUser(ctx)
+	if err != nil {
+		return 0, nil, err
 	}
-	return false
+	cookie, err = tokens.NewCookie(userID)
+	return userID, cookie, err
+}
+
+// CookieMiddleware returns a middleware that authenticates each request and puts the user ID into its context,
+// where handlers get it with UserIDFromContext. A client-supplied "ClientID" header is removed, so it can not
+// be used to impersonate another user.
+//
+// A request with an "Authorization: Bearer <jwt>" header, as sent by clients without a cookie jar,
+// is rejected with 401 if the token is invalid or its user is unknown to users. Otherwise the request must
+// carry a valid JWT in the "ClientID" cookie; if the cookie is missing or invalid, a new user is registered
+// and a JWT for it is set as a cookie. It is meant for routes creating data, see AuthMiddleware for the others.
+func (tokens *Tokens) CookieMiddleware(users Users) func(h http.Handler) http.Handler {
+	return tokens.middleware(users, true)
 }
 
-// CookieMiddleware returns a middleware that ensures each request has a valid JWT in the "ClientID" cookie.
-// If the cookie is missing or invalid, a new JWT is created and set as a cookie.
-func CookieMiddleware() func(h http.Handler) http.Handler {
+// AuthMiddleware is like CookieMiddleware, but a request without a valid "ClientID" cookie is rejected
+// with 401 instead of registering a user, so requests that only read or change existing data do not
+// store a user each time they are made anonymously.
+func (tokens *Tokens) AuthMiddleware(users Users) func(h http.Handler) http.Handler {
+	return tokens.middleware(users, false)
+}
+
+func (tokens *Tokens) middleware(users Users, registerUsers bool) func(h http.Handler) http.Handler {
 	return func(h http.Handler) http.Handler {
 		fn := func(w http.ResponseWriter, r *http.Request) {
+			r.Header.Del("ClientID")
 
-			reseivedCookie, err := r.Cookie("ClientID")
-			if err != nil {
-				switch {
-				case errors.Is(err, http.ErrNoCookie):
-					userID, createdCookie := СreateCookieClientID("")
-					http.SetCookie(w, createdCookie)
-					userIDString := strconv.Itoa(userID)
-					r.Header.Set("ClientID", userIDString)
-					if r.Method == http.MethodGet {
-						w.WriteHeader(http.StatusUnauthorized)
-					}
-					h.ServeHTTP(w, r)
-				default:
+			if authorization := r.Header.Get("Authorization"); authorization != "" {
+				tokenString, ok := BearerToken(authorization)
+				if !ok {
+					w.Header().Set("WWW-Authenticate", `Bearer error="invalid_request"`)
+					http.Error(w, "Unauthorized", http.StatusUnauthorized)
+					return
+				}
+				userID, validUserID, err := tokens.UserIDFromToken(r.Context(), users, tokenString)
+				if err != nil {
 					log.Println(err)
-					http.Error(w, "server error", http.StatusInternalServerError)
+					http.Error(w, "server error", http.StatusServiceUnavailable)
+					return
+				}
+				if !validUserID {
+					w.Header().Set("WWW-Authenticate", `Bearer error="invalid_token"`)
+					http.Error(w, "Unauthorized", http.StatusUnauthorized)
+					return
 				}
+				h.ServeHTTP(w, r.WithContext(WithUserID(r.Context(), userID)))
 				return
 			}
 
-			clientID := reseivedCookie.Value
-
-			if clientID == "" {
-				_, createdCookie := СreateCookieClientID("")
-				http.SetCookie(w, createdCookie)
-				w.WriteHeader(http.StatusUnauthorized)
+			var userID int
+			var validUserID bool
+			reseivedCookie, err := r.Cookie("ClientID")
+			switch {
+			case errors.Is(err, http.ErrNoCookie):
+			case err != nil:
+				log.Println(err)
+				http.Error(w, "server error", http.StatusInternalServerError)
 				return
+			case reseivedCookie.Value != "":
+				userID, validUserID, err = tokens.UserIDFromToken(r.Context(), users, reseivedCookie.Value)
+				if err != nil {
+					log.Println(err)
+					http.Error(w, "server error", http.StatusServiceUnavailable)
+					return
+				}
 			}
 
-			userID := getUserID(clientID)
-			validUserID := validateUserID(userID)
-
-			userIDString := strconv.Itoa(userID)
-
-			if validUserID {
-				r.Header.Set("ClientID", userIDString)
-				h.ServeHTTP(w, r)
-			} else {
-				_, createdCookie := СreateCookieClientID("")
+			if !validUserID {
+				if !registerUsers {
+					http.Error(w, "Unauthorized", http.StatusUnauthorized)
+					return
+				}
+				var createdCookie *http.Cookie
+				userID, createdCookie, err = tokens.newUserCookie(r.Context(), users)
+				if err != nil {
+					log.Println(err)
+					http.Error(w, "server error", http.StatusServiceUnavailable)
+					return
+				}
 				http.SetCookie(w, createdCookie)
-				h.ServeHTTP(w, r)
 			}
+			h.ServeHTTP(w, r.WithContext(WithUserID(r.Context(), userID)))
 		}
 		return http.HandlerFunc(fn)
 	}
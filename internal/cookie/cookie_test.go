@@ -0,0 +1,147 @@
+package cookie
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/DariSorokina/go-first-sprint/internal/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type testUsers map[int]bool
+
+func (users testUsers) CreateUser(ctx context.Context) (int, error) {
+	userID := len(users) + 1
+	users[userID] = true
+	return userID, nil
+}
+
+func (users testUsers) UserExists(ctx context.Context, userID int) (bool, error) {
+	return users[userID], nil
+}
+
+func TestTokensKeyRotation(t *testing.T) {
+	ctx := context.Background()
+	users := testUsers{}
+
+	flagConfig := config.NewFlagConfig()
+	flagConfig.FlagJWTKeys = "v1=old-secret"
+	oldTokens, err := NewTokens(flagConfig)
+	require.NoError(t, err)
+	userID, oldToken, err := oldTokens.NewUserToken(ctx, users)
+	require.NoError(t, err)
+
+	flagConfig.FlagJWTKeys = "v2=new-secret,v1=old-secret"
+	tokens, err := NewTokens(flagConfig)
+	require.NoError(t, err)
+
+	gotUserID, ok, err := tokens.UserIDFromToken(ctx, users, oldToken)
+	require.NoError(t, err)
+	assert.True(t, ok, "tokens signed with an older key stay valid")
+	assert.Equal(t, userID, gotUserID)
+
+	_, newToken, err := tokens.NewUserToken(ctx, users)
+	require.NoError(t, err)
+	_, ok, err = oldTokens.UserIDFromToken(ctx, users, newToken)
+	require.NoError(t, err)
+	assert.False(t, ok, "new tokens are signed with the current key")
+
+	flagConfig.FlagJWTKeys = "v2=new-secret"
+	tokens, err = NewTokens(flagConfig)
+	require.NoError(t, err)
+	_, ok, err = tokens.UserIDFromToken(ctx, users, oldToken)
+	require.NoError(t, err)
+	assert.False(t, ok, "tokens signed with a removed key are rejected")
+}
+
+func TestNewCookieAttributes(t *testing.T) {
+	flagConfig := config.NewFlagConfig()
+	flagConfig.FlagCookieSecure = true
+	flagConfig.FlagCookieSameSite = "strict"
+	flagConfig.FlagCookieDomain = "example.com"
+	tokens, err := NewTokens(flagConfig)
+	require.NoError(t, err)
+
+	cookie, err := tokens.NewCookie(1)
+	require.NoError(t, err)
+	assert.True(t, cookie.Secure)
+	assert.Equal(t, http.SameSiteStrictMode, cookie.SameSite)
+	assert.Equal(t, "example.com", cookie.Domain)
+	assert.Equal(t, int(flagConfig.FlagTokenLifetime.Seconds()), cookie.MaxAge, "the cookie expires with its token")
+}
+
+func TestCookieMiddlewareBearer(t *testing.T) {
+	ctx := context.Background()
+	users := testUsers{}
+	tokens, err := NewTokens(config.NewFlagConfig())
+	require.NoError(t, err)
+	userID, token, err := tokens.NewUserToken(ctx, users)
+	require.NoError(t, err)
+
+	var gotUserID int
+	var gotClientID string
+	handler := tokens.CookieMiddleware(users)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotUserID, _ = UserIDFromContext(r.Context())
+		gotClientID = r.Header.Get("ClientID")
+	}))
+
+	testCases := []struct {
+		name          string
+		authorization string
+		status        int
+		userID        int
+	}{
+		{name: "valid token", authorization: "Bearer " + token, status: http.StatusOK, userID: userID},
+		{name: "invalid token", authorization: "Bearer " + token + "x", status: http.StatusUnauthorized},
+		{name: "other scheme", authorization: "Basic dXNlcjpwYXNz", status: http.StatusUnauthorized},
+	}
+	for _, test := range testCases {
+		t.Run(test.name, func(t *testing.T) {
+			gotUserID, gotClientID = 0, ""
+			req := httptest.NewRequest(http.MethodPost, "/api/shorten", nil)
+			req.Header.Set("Authorization", test.authorization)
+			req.Header.Set("ClientID", "42")
+			res := httptest.NewRecorder()
+
+			handler.ServeHTTP(res, req)
+			assert.Equal(t, test.status, res.Code)
+			assert.Equal(t, test.userID, gotUserID)
+			assert.Empty(t, gotClientID, "a client-supplied ClientID header is removed")
+			assert.Empty(t, res.Result().Cookies(), "bearer requests get no cookie")
+		})
+	}
+}
+
+func TestMiddlewareWithoutCookie(t *testing.T) {
+	users := testUsers{}
+	tokens, err := NewTokens(config.NewFlagConfig())
+	require.NoError(t, err)
+
+	var gotUserID int
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotUserID, _ = UserIDFromContext(r.Context())
+	})
+
+	res := httptest.NewRecorder()
+	tokens.AuthMiddleware(users)(next).ServeHTTP(res, httptest.NewRequest(http.MethodGet, "/api/user/urls", nil))
+	assert.Equal(t, http.StatusUnauthorized, res.Code)
+	assert.Empty(t, res.Result().Cookies())
+	assert.Empty(t, users, "reading routes do not register users")
+
+	req := httptest.NewRequest(http.MethodGet, "/api/user/urls", nil)
+	req.AddCookie(&http.Cookie{Name: "ClientID", Value: "invalid"})
+	res = httptest.NewRecorder()
+	tokens.AuthMiddleware(users)(next).ServeHTTP(res, req)
+	assert.Equal(t, http.StatusUnauthorized, res.Code)
+	assert.Empty(t, users)
+
+	res = httptest.NewRecorder()
+	tokens.CookieMiddleware(users)(next).ServeHTTP(res, httptest.NewRequest(http.MethodPost, "/api/shorten", nil))
+	assert.Equal(t, http.StatusOK, res.Code)
+	assert.Equal(t, 1, gotUserID)
+	require.Len(t, res.Result().Cookies(), 1, "routes creating data register a user")
+	assert.Equal(t, "ClientID", res.Result().Cookies()[0].Name)
+}
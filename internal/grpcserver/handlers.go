@@ -0,0 +1,189 @@
+package grpcserver
+
+import (
+	"context"
+	"errors"
+	"net/url"
+
+	"github.com/DariSorokina/go-first-sprint/internal/app"
+	"github.com/DariSorokina/go-first-sprint/internal/models"
+	pb "github.com/DariSorokina/go-first-sprint/internal/proto"
+	"github.com/DariSorokina/go-first-sprint/internal/storage"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// Shorten shortens a URL, optionally under an alias and with a time-to-live.
+func (server *Server) Shorten(ctx context.Context, req *pb.ShortenRequest) (*pb.ShortenResponse, error) {
+	userID := userIDFromContext(ctx)
+
+	expiresAt, err := app.ExpirationTime(nil, req.GetTtlSeconds())
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	var response pb.ShortenResponse
+	shortenedURL := req.GetAlias()
+	if shortenedURL != "" {
+		var ownerID int
+		ownerID, err = server.app.ToAliasURL(ctx, shortenedURL, req.GetUrl(), userID, expiresAt)
+		if errors.Is(err, storage.ErrAliasAlreadyExist) {
+			response.Owner = int64(ownerID)
+		}
+	} else {
+		shortenedURL, err = server.app.ToShortenURL(ctx, req.GetUrl(), userID, expiresAt)
+	}
+
+	switch {
+	case errors.Is(err, app.ErrInvalidAlias):
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	case errors.Is(err, storage.ErrAliasAlreadyExist), errors.Is(err, storage.ErrShortURLAlreadyExist):
+		response.AlreadyExists = true
+	case err != nil:
+		return nil, server.storageError("failed to shorten URL", err)
+	}
+
+	if response.Result, err = server.joinBaseURL(shortenedURL); err != nil {
+		return nil, err
+	}
+	return &response, nil
+}
+
+// ShortenBatch shortens several URLs at once, each with an optional time-to-live, keeping the
+// correlation IDs of the request and flagging the items whose URL was already shortened.
+func (server *Server) ShortenBatch(ctx context.Context, req *pb.ShortenBatchRequest) (*pb.ShortenBatchResponse, error) {
+	userID := userIDFromContext(ctx)
+
+	batch := make([]models.BatchURL, 0, len(req.GetItems()))
+	for _, item := range req.GetItems() {
+		expiresAt, err := app.ExpirationTime(nil, item.GetTtlSeconds())
+		if err != nil {
+			return nil, status.Errorf(codes.InvalidArgument, "%s: %s", item.GetCorrelationId(), err)
+		}
+		batch = append(batch, models.BatchURL{CorrelationID: item.GetCorrelationId(), OriginalURL: item.GetOriginalUrl(), ExpiresAt: expiresAt})
+	}
+
+	if err := server.app.ToShortenBatch(ctx, batch, userID); err != nil {
+		return nil, server.storageError("failed to shorten URLs", err)
+	}
+
+	var response pb.ShortenBatchResponse
+	for _, item := range batch {
+		shortURL, err := server.joinBaseURL(item.ShortURL)
+		if err != nil {
+			return nil, err
+		}
+		response.Items = append(response.Items, &pb.ShortenBatchResponse_Item{CorrelationId: item.CorrelationID, ShortUrl: shortURL, Conflict: item.Conflict})
+	}
+	return &response, nil
+}
+
+// GetOriginal returns the original URL for a short URL.
+func (server *Server) GetOriginal(ctx context.Context, req *pb.GetOriginalRequest) (*pb.GetOriginalResponse, error) {
+	longURL, err := server.app.ToOriginalURL(ctx, req.GetShortUrl())
+	if err != nil {
+		return nil, server.storageError("failed to get original URL", err)
+	}
+
+	return &pb.GetOriginalResponse{OriginalUrl: longURL}, nil
+}
+
+// ListUserURLs returns the URLs shortened by the calling user.
+func (server *Server) ListUserURLs(ctx context.Context, req *pb.ListUserURLsRequest) (*pb.ListUserURLsResponse, error) {
+	urlPairs, err := server.app.GetURLsByUserID(ctx, userIDFromContext(ctx))
+	if err != nil {
+		return nil, server.storageError("failed to get user URLs", err)
+	}
+
+	var response pb.ListUserURLsResponse
+	for _, urlPair := range urlPairs {
+		shortURL, err := server.joinBaseURL(urlPair.ShortenURL)
+		if err != nil {
+			return nil, err
+		}
+		response.Urls = append(response.Urls, &pb.ListUserURLsResponse_URLPair{ShortUrl: shortURL, OriginalUrl: urlPair.OriginalURL})
+	}
+	return &response, nil
+}
+
+// DeleteUserURLs starts deleting the given short URLs of the calling user and returns the deletion job ID
+// without waiting. It fails with InvalidArgument when no short URLs are given and with Unavailable when the
+// deletion queue is full.
+func (server *Server) DeleteUserURLs(ctx context.Context, req *pb.DeleteUserURLsRequest) (*pb.DeleteUserURLsResponse, error) {
+	if len(req.GetShortUrls()) == 0 {
+		return nil, status.Error(codes.InvalidArgument, "no short URLs provided")
+	}
+	jobID, err := server.app.DeleteURLs(req.GetShortUrls(), userIDFromContext(ctx))
+	if errors.Is(err, app.ErrDeleteQueueFull) {
+		return nil, status.Error(codes.Unavailable, err.Error())
+	}
+	if err != nil {
+		server.log.Sugar().Errorf("Failed to create deletion job: %s", err)
+		return nil, status.Error(codes.Internal, "failed to create deletion job")
+	}
+	return &pb.DeleteUserURLsResponse{JobId: jobID}, nil
+}
+
+// GetDeleteJob returns the status of a deletion job of the calling user.
+func (server *Server) GetDeleteJob(ctx context.Context, req *pb.GetDeleteJobRequest) (*pb.GetDeleteJobResponse, error) {
+	job, err := server.app.GetDeleteJob(ctx, req.GetJobId(), userIDFromContext(ctx))
+	if errors.Is(err, app.ErrDeleteJobNotFound) {
+		return nil, status.Error(codes.NotFound, err.Error())
+	}
+	if err != nil {
+		return nil, server.storageError("failed to get deletion job", err)
+	}
+	return &pb.GetDeleteJobResponse{JobId: job.ID, Status: job.Status, Results: job.Results, Error: job.Error}, nil
+}
+
+// RestoreUserURLs restores the given short URLs of the calling user deleted within the restore grace period.
+func (server *Server) RestoreUserURLs(ctx context.Context, req *pb.RestoreUserURLsRequest) (*pb.RestoreUserURLsResponse, error) {
+	results, err := server.app.RestoreURLs(ctx, req.GetShortUrls(), userIDFromContext(ctx))
+	if err != nil {
+		return nil, server.storageError("failed to restore urls", err)
+	}
+	return &pb.RestoreUserURLsResponse{Results: results}, nil
+}
+
+// Ping checks the storage connectivity.
+func (server *Server) Ping(ctx context.Context, req *pb.PingRequest) (*pb.PingResponse, error) {
+	if err := server.app.Ping(ctx); err != nil {
+		return nil, status.Error(codes.Unavailable, "storage connection failed")
+	}
+	return &pb.PingResponse{}, nil
+}
+
+// Stats returns the number of URLs and distinct users held by the service.
+func (server *Server) Stats(ctx context.Context, req *pb.StatsRequest) (*pb.StatsResponse, error) {
+	stats, err := server.app.GetInternalStats(ctx)
+	if err != nil {
+		return nil, server.storageError("failed to get statistics", err)
+	}
+	return &pb.StatsResponse{Urls: int64(stats.URLs), Users: int64(stats.Users), DeleteQueue: int64(stats.DeleteQueue)}, nil
+}
+
+// storageError converts a storage error into a status with the code matching its category
+// and logs server-side failures.
+func (server *Server) storageError(message string, err error) error {
+	code := codes.Internal
+	switch {
+	case errors.Is(err, storage.ErrNotFound), errors.Is(err, storage.ErrDeleted), errors.Is(err, storage.ErrExpired):
+		return status.Error(codes.NotFound, err.Error())
+	case errors.Is(err, storage.ErrConflict):
+		return status.Error(codes.AlreadyExists, err.Error())
+	case errors.Is(err, storage.ErrUnavailable), errors.Is(err, context.DeadlineExceeded):
+		code = codes.Unavailable
+	}
+
+	server.log.Sugar().Errorf("%s: %s", message, err)
+	return status.Error(code, message)
+}
+
+func (server *Server) joinBaseURL(shortURL string) (string, error) {
+	joined, err := url.JoinPath(server.flagConfig.FlagBaseURL, shortURL)
+	if err != nil {
+		server.log.Sugar().Errorf("Failed to join provided URL path with short URL: %s", err)
+		return "", status.Error(codes.Internal, "bad URL path provided")
+	}
+	return joined, nil
+}
@@ -0,0 +1,254 @@
+package grpcserver
+
+import (
+	"context"
+	"errors"
+	"math"
+	"net"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/DariSorokina/go-first-sprint/internal/app"
+	"github.com/DariSorokina/go-first-sprint/internal/config"
+	"github.com/DariSorokina/go-first-sprint/internal/cookie"
+	"github.com/DariSorokina/go-first-sprint/internal/middleware"
+	"github.com/DariSorokina/go-first-sprint/internal/models"
+	pb "github.com/DariSorokina/go-first-sprint/internal/proto"
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+// clientIDKey is the metadata key carrying the same JWT as the "ClientID" cookie.
+const clientIDKey = "clientid"
+
+// authorizationKey is the metadata key carrying an "Authorization: Bearer <jwt>" value like the HTTP header,
+// or an API key as "Bearer sk_..." or "ApiKey sk_...".
+const authorizationKey = "authorization"
+
+// apiKeyScheme is the authorization scheme naming an API key explicitly.
+const apiKeyScheme = "ApiKey"
+
+// realIPKey is the metadata key carrying the client address set by a trusted proxy like the HTTP header.
+const realIPKey = "x-real-ip"
+
+// forwardedForKey is the metadata key carrying the addresses of the client and the proxies like the HTTP header.
+const forwardedForKey = "x-forwarded-for"
+
+// retryAfterKey is the response header key carrying the seconds until a rate limited call is allowed.
+const retryAfterKey = "retry-after"
+
+// authMethods lists the calls that act on behalf of a user.
+var authMethods = map[string]bool{
+	pb.Shortener_Shorten_FullMethodName:         true,
+	pb.Shortener_ShortenBatch_FullMethodName:    true,
+	pb.Shortener_ListUserURLs_FullMethodName:    true,
+	pb.Shortener_DeleteUserURLs_FullMethodName:  true,
+	pb.Shortener_GetDeleteJob_FullMethodName:    true,
+	pb.Shortener_RestoreUserURLs_FullMethodName: true,
+}
+
+// registeringMethods lists the calls creating data, which issue a new user to a caller without one.
+var registeringMethods = map[string]bool{
+	pb.Shortener_Shorten_FullMethodName:      true,
+	pb.Shortener_ShortenBatch_FullMethodName: true,
+}
+
+// methodScopes maps the calls acting on behalf of a user to the API key scope they require, like the
+// RequireScope checks of the HTTP routes.
+var methodScopes = map[string]string{
+	pb.Shortener_Shorten_FullMethodName:         models.ScopeShorten,
+	pb.Shortener_ShortenBatch_FullMethodName:    models.ScopeShorten,
+	pb.Shortener_ListUserURLs_FullMethodName:    models.ScopeRead,
+	pb.Shortener_GetDeleteJob_FullMethodName:    models.ScopeRead,
+	pb.Shortener_DeleteUserURLs_FullMethodName:  models.ScopeDelete,
+	pb.Shortener_RestoreUserURLs_FullMethodName: models.ScopeDelete,
+}
+
+// rateLimitedMethods maps the calls with budgets to their rate limit routes, shared with the HTTP endpoints.
+var rateLimitedMethods = map[string]string{
+	pb.Shortener_Shorten_FullMethodName:        config.RateLimitShorten,
+	pb.Shortener_ShortenBatch_FullMethodName:   config.RateLimitBatch,
+	pb.Shortener_GetOriginal_FullMethodName:    config.RateLimitRedirect,
+	pb.Shortener_DeleteUserURLs_FullMethodName: config.RateLimitDelete,
+}
+
+// loggingInterceptor logs every call the same way Logger.WithLogging logs HTTP requests.
+func (server *Server) loggingInterceptor(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+	t1 := time.Now()
+	resp, err := handler(ctx, req)
+	server.log.Info("served",
+		zap.String("method", info.FullMethod),
+		zap.String("code", status.Code(err).String()),
+		zap.Duration("duration", time.Since(t1)))
+	return resp, err
+}
+
+// trustedSubnetInterceptor rejects Stats calls whose client IP is outside the trusted subnet. The client IP
+// is resolved by clientIP, so the "x-real-ip" metadata only counts on connections from a trusted proxy.
+func (server *Server) trustedSubnetInterceptor(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+	if info.FullMethod != pb.Shortener_Stats_FullMethodName {
+		return handler(ctx, req)
+	}
+
+	_, subnet, err := net.ParseCIDR(server.flagConfig.FlagTrustedSubnet)
+	if err != nil {
+		return nil, status.Error(codes.PermissionDenied, "forbidden")
+	}
+
+	realIP := net.ParseIP(server.clientIP(ctx))
+	if realIP == nil || !subnet.Contains(realIP) {
+		return nil, status.Error(codes.PermissionDenied, "forbidden")
+	}
+	return handler(ctx, req)
+}
+
+// clientRateLimitInterceptor rejects calls from a client IP whose budget for the call is spent before
+// authInterceptor runs, so they can not create users.
+func (server *Server) clientRateLimitInterceptor(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+	route, ok := rateLimitedMethods[info.FullMethod]
+	if !ok {
+		return handler(ctx, req)
+	}
+	if allowed, retryAfter := server.limiter.PeekIP(ctx, route, server.clientIP(ctx)); !allowed {
+		return nil, server.tooManyRequests(ctx, retryAfter)
+	}
+	return handler(ctx, req)
+}
+
+// rateLimitInterceptor counts calls against the budgets of the user and the client IP like
+// RateLimiter.Limit does for HTTP. A call exceeding either budget is rejected as ResourceExhausted
+// with the seconds until it is allowed in the "retry-after" response header, and spends neither budget.
+func (server *Server) rateLimitInterceptor(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+	route, ok := rateLimitedMethods[info.FullMethod]
+	if !ok {
+		return handler(ctx, req)
+	}
+	if allowed, retryAfter := server.limiter.Take(ctx, route, userIDFromContext(ctx), server.clientIP(ctx)); !allowed {
+		return nil, server.tooManyRequests(ctx, retryAfter)
+	}
+	return handler(ctx, req)
+}
+
+func (server *Server) tooManyRequests(ctx context.Context, retryAfter time.Duration) error {
+	seconds := max(1, int(math.Ceil(retryAfter.Seconds())))
+	if err := grpc.SetHeader(ctx, metadata.Pairs(retryAfterKey, strconv.Itoa(seconds))); err != nil {
+		server.log.Sugar().Errorf("Failed to set retry-after header: %s", err)
+	}
+	return status.Error(codes.ResourceExhausted, "too many requests")
+}
+
+// clientIP resolves the client address of the call like middleware.ClientIPMiddleware does for HTTP:
+// the "x-real-ip" and "x-forwarded-for" metadata are only trusted on connections from a trusted proxy.
+func (server *Server) clientIP(ctx context.Context) string {
+	var remoteAddr string
+	if p, ok := peer.FromContext(ctx); ok && p.Addr != nil {
+		remoteAddr = p.Addr.String()
+	}
+	header := make(http.Header)
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		header["X-Real-Ip"] = md.Get(realIPKey)
+		header["X-Forwarded-For"] = md.Get(forwardedForKey)
+	}
+	return middleware.ResolveClientIP(remoteAddr, header, server.proxies)
+}
+
+// authInterceptor resolves the user from the "authorization" or "clientid" metadata like
+// middleware.APIKeyMiddleware and cookie.CookieMiddleware do for HTTP. A call with an API key is rejected
+// as PermissionDenied if the key lacks the scope of the call, and a call with an invalid API key or bearer
+// token as Unauthenticated. If the "clientid" token is missing or invalid, calls creating data issue a new
+// user and send its token back in the response header, while other calls are rejected as Unauthenticated
+// without storing a user.
+func (server *Server) authInterceptor(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+	if !authMethods[info.FullMethod] {
+		return handler(ctx, req)
+	}
+
+	if authorization := firstMetadataValue(ctx, authorizationKey); authorization != "" {
+		if key, ok := apiKeyFromAuthorization(authorization); ok {
+			return server.authenticateAPIKey(ctx, key, req, info, handler)
+		}
+		tokenString, ok := cookie.BearerToken(authorization)
+		if !ok {
+			return nil, status.Error(codes.Unauthenticated, "malformed authorization")
+		}
+		userID, ok, err := server.tokens.UserIDFromToken(ctx, server.app, tokenString)
+		if err != nil {
+			server.log.Sugar().Errorf("Failed to look up user: %s", err)
+			return nil, status.Error(codes.Unavailable, "server error")
+		}
+		if !ok {
+			return nil, status.Error(codes.Unauthenticated, "invalid token")
+		}
+		return handler(cookie.WithUserID(ctx, userID), req)
+	}
+
+	userID, ok, err := server.tokens.UserIDFromToken(ctx, server.app, firstMetadataValue(ctx, clientIDKey))
+	if err != nil {
+		server.log.Sugar().Errorf("Failed to look up user: %s", err)
+		return nil, status.Error(codes.Unavailable, "server error")
+	}
+	if !ok {
+		if !registeringMethods[info.FullMethod] {
+			return nil, status.Error(codes.Unauthenticated, "unknown user")
+		}
+		var token string
+		userID, token, err = server.tokens.NewUserToken(ctx, server.app)
+		if err != nil {
+			server.log.Sugar().Errorf("Failed to create user token: %s", err)
+			return nil, status.Error(codes.Unavailable, "server error")
+		}
+		if err = grpc.SetHeader(ctx, metadata.Pairs(clientIDKey, token)); err != nil {
+			server.log.Sugar().Errorf("Failed to set user token header: %s", err)
+		}
+	}
+
+	return handler(cookie.WithUserID(ctx, userID), req)
+}
+
+func (server *Server) authenticateAPIKey(ctx context.Context, key string, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+	apiKey, err := server.app.AuthenticateAPIKey(ctx, key)
+	if errors.Is(err, app.ErrInvalidAPIKey) {
+		return nil, status.Error(codes.Unauthenticated, "invalid API key")
+	}
+	if err != nil {
+		server.log.Sugar().Errorf("Failed to look up API key: %s", err)
+		return nil, status.Error(codes.Unavailable, "server error")
+	}
+	if scope := methodScopes[info.FullMethod]; !slices.Contains(apiKey.Scopes, scope) {
+		return nil, status.Errorf(codes.PermissionDenied, "API key lacks the %q scope", scope)
+	}
+	return handler(middleware.WithAPIKey(cookie.WithUserID(ctx, apiKey.UserID), apiKey), req)
+}
+
+// apiKeyFromAuthorization returns the API key of an "ApiKey <key>" value, or of a "Bearer <key>" value
+// whose token has the API key prefix like middleware.APIKeyMiddleware accepts.
+func apiKeyFromAuthorization(authorization string) (key string, ok bool) {
+	if scheme, key, found := strings.Cut(authorization, " "); found && strings.EqualFold(scheme, apiKeyScheme) {
+		return key, key != ""
+	}
+	key, ok = cookie.BearerToken(authorization)
+	return key, ok && strings.HasPrefix(key, app.APIKeyPrefix)
+}
+
+func firstMetadataValue(ctx context.Context, key string) string {
+	md, ok := metadata.FromIncomingContext(ctx)
+	if !ok {
+		return ""
+	}
+	if values := md.Get(key); len(values) > 0 {
+		return values[0]
+	}
+	return ""
+}
+
+func userIDFromContext(ctx context.Context) int {
+	userID, _ := cookie.UserIDFromContext(ctx)
+	return userID
+}
@@ -0,0 +1,94 @@
+// Package grpcserver provides a gRPC API mirroring the HTTP shortener endpoints.
+package grpcserver
+
+import (
+	"context"
+	"crypto/tls"
+	"net"
+	"time"
+
+	"github.com/DariSorokina/go-first-sprint/internal/app"
+	"github.com/DariSorokina/go-first-sprint/internal/config"
+	"github.com/DariSorokina/go-first-sprint/internal/cookie"
+	"github.com/DariSorokina/go-first-sprint/internal/logger"
+	"github.com/DariSorokina/go-first-sprint/internal/middleware"
+	pb "github.com/DariSorokina/go-first-sprint/internal/proto"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+)
+
+// Server represents a gRPC server component backed by the same application as the HTTP server.
+type Server struct {
+	pb.UnimplementedShortenerServer
+	app        *app.App
+	tokens     *cookie.Tokens
+	limiter    *middleware.RateLimiter
+	proxies    []*net.IPNet
+	flagConfig *config.FlagConfig
+	log        *logger.Logger
+}
+
+// NewServer creates a new Server instance with the provided application, user tokens, rate limiter shared with
+// the HTTP server, configuration flags, and logger.
+func NewServer(app *app.App, tokens *cookie.Tokens, limiter *middleware.RateLimiter, flagConfig *config.FlagConfig, l *logger.Logger) *Server {
+	// The proxies were checked when the configuration was parsed, a config built otherwise may trust none.
+	proxies, err := config.ParseTrustedProxies(flagConfig.FlagTrustedProxies)
+	if err != nil {
+		l.Sugar().Errorf("Failed to parse trusted proxies: %s", err)
+	}
+	return &Server{app: app, tokens: tokens, limiter: limiter, proxies: proxies, flagConfig: flagConfig, log: l}
+}
+
+// Run starts the gRPC server on the configured address and serves until ctx is done, then stops accepting
+// calls and returns once in-flight calls finish. Calls still running after the configured shutdown timeout
+// are cancelled. Calls are served over TLS with tlsConfig unless it is nil. It does nothing if no address
+// is configured.
+func Run(ctx context.Context, server *Server, tlsConfig *tls.Config) error {
+	if server.flagConfig.FlagGRPCAddr == "" {
+		return nil
+	}
+
+	listener, err := net.Listen("tcp", server.flagConfig.FlagGRPCAddr)
+	if err != nil {
+		return err
+	}
+
+	var opts []grpc.ServerOption
+	if tlsConfig != nil {
+		opts = append(opts, grpc.Creds(credentials.NewTLS(tlsConfig)))
+	}
+	grpcServer := server.newGRPCServer(opts...)
+
+	stopped := make(chan struct{})
+	go func() {
+		defer close(stopped)
+		<-ctx.Done()
+		server.log.Info("Shutting down gRPC server")
+		timer := time.AfterFunc(server.flagConfig.FlagShutdownTimeout, grpcServer.Stop)
+		defer timer.Stop()
+		grpcServer.GracefulStop()
+	}()
+
+	server.log.Sugar().Infof("Running gRPC server on %s", server.flagConfig.FlagGRPCAddr)
+	if err := grpcServer.Serve(listener); err != nil {
+		return err
+	}
+	// Serve returns nil as soon as the server is stopping, before in-flight calls finish.
+	<-stopped
+	return nil
+}
+
+// newGRPCServer creates a gRPC server serving the shortener with the interceptors of every call.
+// The client IP budget is checked before authentication, so rejected calls can not create users.
+func (server *Server) newGRPCServer(opts ...grpc.ServerOption) *grpc.Server {
+	opts = append(opts, grpc.ChainUnaryInterceptor(
+		server.loggingInterceptor,
+		server.trustedSubnetInterceptor,
+		server.clientRateLimitInterceptor,
+		server.authInterceptor,
+		server.rateLimitInterceptor,
+	))
+	grpcServer := grpc.NewServer(opts...)
+	pb.RegisterShortenerServer(grpcServer, server)
+	return grpcServer
+}
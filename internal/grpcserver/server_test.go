@@ -0,0 +1,293 @@
+package grpcserver
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/DariSorokina/go-first-sprint/internal/app"
+	"github.com/DariSorokina/go-first-sprint/internal/config"
+	"github.com/DariSorokina/go-first-sprint/internal/cookie"
+	"github.com/DariSorokina/go-first-sprint/internal/logger"
+	"github.com/DariSorokina/go-first-sprint/internal/middleware"
+	"github.com/DariSorokina/go-first-sprint/internal/models"
+	pb "github.com/DariSorokina/go-first-sprint/internal/proto"
+	"github.com/DariSorokina/go-first-sprint/internal/server"
+	"github.com/DariSorokina/go-first-sprint/internal/storage"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+func newTestServer(t *testing.T, flagConfig *config.FlagConfig) *Server {
+	l, err := logger.CreateLogger("error")
+	require.NoError(t, err)
+
+	application, err := app.NewApp(storage.NewStorage("", l), flagConfig, l)
+	require.NoError(t, err)
+
+	tokens, err := cookie.NewTokens(flagConfig)
+	require.NoError(t, err)
+
+	userLimits, err := config.ParseRateLimits(flagConfig.FlagRateLimitsUser)
+	require.NoError(t, err)
+	ipLimits, err := config.ParseRateLimits(flagConfig.FlagRateLimitsIP)
+	require.NoError(t, err)
+	limiter := middleware.NewRateLimiter(middleware.NewMemoryRateLimitStore(), userLimits, ipLimits)
+
+	return NewServer(application, tokens, limiter, flagConfig, l)
+}
+
+func newTestClient(t *testing.T, flagConfig *config.FlagConfig) pb.ShortenerClient {
+	return dialTestServer(t, newTestServer(t, flagConfig))
+}
+
+func dialTestServer(t *testing.T, server *Server) pb.ShortenerClient {
+	grpcServer := server.newGRPCServer()
+
+	// A loopback listener gives calls a peer address, which the trusted proxies are matched against.
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	go grpcServer.Serve(listener)
+	t.Cleanup(grpcServer.Stop)
+
+	conn, err := grpc.Dial(listener.Addr().String(), grpc.WithTransportCredentials(insecure.NewCredentials()))
+	require.NoError(t, err)
+	t.Cleanup(func() { conn.Close() })
+
+	return pb.NewShortenerClient(conn)
+}
+
+func TestShortenAndListWithIssuedToken(t *testing.T) {
+	client := newTestClient(t, config.NewFlagConfig())
+	ctx := context.Background()
+
+	var header metadata.MD
+	shortened, err := client.Shorten(ctx, &pb.ShortenRequest{Url: "https://example.com/grpc"}, grpc.Header(&header))
+	require.NoError(t, err)
+	assert.False(t, shortened.GetAlreadyExists())
+	require.Len(t, header.Get(clientIDKey), 1)
+
+	ctx = metadata.AppendToOutgoingContext(ctx, clientIDKey, header.Get(clientIDKey)[0])
+	listed, err := client.ListUserURLs(ctx, &pb.ListUserURLsRequest{})
+	require.NoError(t, err)
+	require.Len(t, listed.GetUrls(), 1)
+	assert.Equal(t, shortened.GetResult(), listed.GetUrls()[0].GetShortUrl())
+	assert.Equal(t, "https://example.com/grpc", listed.GetUrls()[0].GetOriginalUrl())
+
+	header = metadata.MD{}
+	_, err = client.ListUserURLs(context.Background(), &pb.ListUserURLsRequest{}, grpc.Header(&header))
+	assert.Equal(t, codes.Unauthenticated, status.Code(err))
+	assert.Empty(t, header.Get(clientIDKey), "calls that do not create data issue no user")
+}
+
+func TestStatsTrustedSubnet(t *testing.T) {
+	flagConfig := config.NewFlagConfig()
+	flagConfig.FlagTrustedSubnet = "10.0.0.0/8"
+	flagConfig.FlagTrustedProxies = "127.0.0.1/32"
+	client := newTestClient(t, flagConfig)
+
+	_, err := client.Stats(context.Background(), &pb.StatsRequest{})
+	assert.Equal(t, codes.PermissionDenied, status.Code(err))
+
+	_, err = client.Shorten(context.Background(), &pb.ShortenRequest{Url: "https://example.com/stats"})
+	require.NoError(t, err)
+
+	ctx := metadata.AppendToOutgoingContext(context.Background(), realIPKey, "10.1.2.3")
+	stats, err := client.Stats(ctx, &pb.StatsRequest{})
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), stats.GetUrls(), "the default mapping is not counted")
+	assert.Equal(t, int64(1), stats.GetUsers())
+
+	flagConfig.FlagTrustedProxies = ""
+	client = newTestClient(t, flagConfig)
+	_, err = client.Stats(ctx, &pb.StatsRequest{})
+	assert.Equal(t, codes.PermissionDenied, status.Code(err), "x-real-ip only counts from a trusted proxy")
+}
+
+func TestDeleteUserURLsEmpty(t *testing.T) {
+	client := newTestClient(t, config.NewFlagConfig())
+
+	var header metadata.MD
+	_, err := client.Shorten(context.Background(), &pb.ShortenRequest{Url: "https://example.com/delete"}, grpc.Header(&header))
+	require.NoError(t, err)
+	require.Len(t, header.Get(clientIDKey), 1)
+	ctx := metadata.AppendToOutgoingContext(context.Background(), clientIDKey, header.Get(clientIDKey)[0])
+
+	_, err = client.DeleteUserURLs(ctx, &pb.DeleteUserURLsRequest{})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err), "an empty list is rejected like the HTTP 400")
+}
+
+func TestShortenBatchTTL(t *testing.T) {
+	client := newTestClient(t, config.NewFlagConfig())
+	ctx := context.Background()
+
+	_, err := client.ShortenBatch(ctx, &pb.ShortenBatchRequest{Items: []*pb.ShortenBatchRequest_Item{
+		{CorrelationId: "negative", OriginalUrl: "https://example.com/negative", TtlSeconds: -1},
+	}})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+
+	shortened, err := client.ShortenBatch(ctx, &pb.ShortenBatchRequest{Items: []*pb.ShortenBatchRequest_Item{
+		{CorrelationId: "expiring", OriginalUrl: "https://example.com/expiring", TtlSeconds: 1},
+		{CorrelationId: "permanent", OriginalUrl: "https://example.com/permanent"},
+	}})
+	require.NoError(t, err)
+	require.Len(t, shortened.GetItems(), 2)
+
+	time.Sleep(1100 * time.Millisecond)
+
+	shortURL := func(item *pb.ShortenBatchResponse_Item) string {
+		return item.GetShortUrl()[strings.LastIndex(item.GetShortUrl(), "/")+1:]
+	}
+	_, err = client.GetOriginal(ctx, &pb.GetOriginalRequest{ShortUrl: shortURL(shortened.GetItems()[0])})
+	assert.Equal(t, codes.NotFound, status.Code(err), "the item with a time-to-live expired")
+	original, err := client.GetOriginal(ctx, &pb.GetOriginalRequest{ShortUrl: shortURL(shortened.GetItems()[1])})
+	require.NoError(t, err)
+	assert.Equal(t, "https://example.com/permanent", original.GetOriginalUrl())
+}
+
+func TestRateLimit(t *testing.T) {
+	flagConfig := config.NewFlagConfig()
+	flagConfig.FlagRateLimitsIP = "shorten=2/1m"
+	flagConfig.FlagRateLimitsUser = "shorten=1/1m"
+	client := newTestClient(t, flagConfig)
+	ctx := context.Background()
+
+	var header metadata.MD
+	_, err := client.Shorten(ctx, &pb.ShortenRequest{Url: "https://example.com/1"}, grpc.Header(&header))
+	require.NoError(t, err)
+	userCtx := metadata.AppendToOutgoingContext(ctx, clientIDKey, header.Get(clientIDKey)[0])
+
+	header = metadata.MD{}
+	_, err = client.Shorten(userCtx, &pb.ShortenRequest{Url: "https://example.com/2"}, grpc.Header(&header))
+	assert.Equal(t, codes.ResourceExhausted, status.Code(err), "the user budget is spent")
+	assert.Equal(t, []string{"60"}, header.Get(retryAfterKey))
+
+	_, err = client.Shorten(ctx, &pb.ShortenRequest{Url: "https://example.com/3"})
+	require.NoError(t, err, "a new user has its own budget and the denied call spent none of the client IP budget")
+
+	header = metadata.MD{}
+	_, err = client.Shorten(ctx, &pb.ShortenRequest{Url: "https://example.com/4"}, grpc.Header(&header))
+	assert.Equal(t, codes.ResourceExhausted, status.Code(err), "the client IP budget is spent")
+	assert.Empty(t, header.Get(clientIDKey), "calls over the client IP budget issue no user")
+
+	_, err = client.GetOriginal(ctx, &pb.GetOriginalRequest{ShortUrl: "d41d8cd98f"})
+	assert.NoError(t, err, "calls without a budget are not limited")
+}
+
+func TestRunTLS(t *testing.T) {
+	// Run listens on the configured address, so a free loopback port is picked up front.
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	addr := listener.Addr().String()
+	require.NoError(t, listener.Close())
+
+	flagConfig := config.NewFlagConfig()
+	flagConfig.FlagGRPCAddr = addr
+	grpcServer := newTestServer(t, flagConfig)
+	tlsConfig, err := server.NewServer(grpcServer.app, grpcServer.tokens, flagConfig, grpcServer.log).TLSConfig()
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- Run(ctx, grpcServer, tlsConfig) }()
+	defer func() {
+		cancel()
+		assert.NoError(t, <-done)
+	}()
+
+	cert, err := x509.ParseCertificate(tlsConfig.Certificates[0].Certificate[0])
+	require.NoError(t, err)
+	roots := x509.NewCertPool()
+	roots.AddCert(cert)
+	conn, err := grpc.Dial(addr, grpc.WithTransportCredentials(credentials.NewTLS(&tls.Config{RootCAs: roots, ServerName: "localhost"})))
+	require.NoError(t, err)
+	defer conn.Close()
+
+	callCtx, callCancel := context.WithTimeout(ctx, 5*time.Second)
+	defer callCancel()
+	_, err = pb.NewShortenerClient(conn).Shorten(callCtx, &pb.ShortenRequest{Url: "https://example.com/tls"}, grpc.WaitForReady(true))
+	require.NoError(t, err, "calls are served over TLS with the certificate of the HTTPS server")
+}
+
+func TestRunDisabledByDefault(t *testing.T) {
+	assert.NoError(t, Run(context.Background(), newTestServer(t, config.NewFlagConfig()), nil), "no listener is started without an address")
+}
+
+func TestAPIKeyScopes(t *testing.T) {
+	grpcServer := newTestServer(t, config.NewFlagConfig())
+	client := dialTestServer(t, grpcServer)
+	ctx := context.Background()
+
+	var header metadata.MD
+	_, err := client.Shorten(ctx, &pb.ShortenRequest{Url: "https://example.com/api-key"}, grpc.Header(&header))
+	require.NoError(t, err)
+	require.Len(t, header.Get(clientIDKey), 1)
+	userID, ok, err := grpcServer.tokens.UserIDFromToken(ctx, grpcServer.app, header.Get(clientIDKey)[0])
+	require.NoError(t, err)
+	require.True(t, ok)
+	_, key, err := grpcServer.app.CreateAPIKey(ctx, userID, "ci", []string{models.ScopeRead})
+	require.NoError(t, err)
+
+	testCases := []struct {
+		name          string
+		authorization string
+		call          func(ctx context.Context) error
+		wantCode      codes.Code
+	}{
+		{
+			name:          "bearer key with the scope",
+			authorization: "Bearer " + key,
+			call: func(ctx context.Context) error {
+				listed, err := client.ListUserURLs(ctx, &pb.ListUserURLsRequest{})
+				if err == nil && len(listed.GetUrls()) != 1 {
+					return status.Error(codes.Internal, "the key lists the urls of another user")
+				}
+				return err
+			},
+			wantCode: codes.OK,
+		},
+		{
+			name:          "api key scheme with the scope",
+			authorization: "ApiKey " + key,
+			call: func(ctx context.Context) error {
+				_, err := client.ListUserURLs(ctx, &pb.ListUserURLsRequest{})
+				return err
+			},
+			wantCode: codes.OK,
+		},
+		{
+			name:          "key without the scope",
+			authorization: "ApiKey " + key,
+			call: func(ctx context.Context) error {
+				_, err := client.Shorten(ctx, &pb.ShortenRequest{Url: "https://example.com/denied"})
+				return err
+			},
+			wantCode: codes.PermissionDenied,
+		},
+		{
+			name:          "unknown key",
+			authorization: "Bearer sk_unknown",
+			call: func(ctx context.Context) error {
+				_, err := client.ListUserURLs(ctx, &pb.ListUserURLsRequest{})
+				return err
+			},
+			wantCode: codes.Unauthenticated,
+		},
+	}
+	for _, test := range testCases {
+		t.Run(test.name, func(t *testing.T) {
+			err := test.call(metadata.AppendToOutgoingContext(ctx, authorizationKey, test.authorization))
+			assert.Equal(t, test.wantCode, status.Code(err), err)
+		})
+	}
+}
@@ -0,0 +1,151 @@
+package metrics
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/DariSorokina/go-first-sprint/internal/models"
+	"github.com/DariSorokina/go-first-sprint/internal/storage"
+)
+
+// database is a storage.Database observing the latency and errors of each method of the wrapped one.
+type database struct {
+	db storage.Database
+}
+
+// NewDatabase wraps db, so the latency and errors of its methods are exposed as metrics.
+func NewDatabase(db storage.Database) storage.Database {
+	return &database{db: db}
+}
+
+// observe records an operation started at t1 that ended with err.
+func observe(operation string, t1 time.Time, err error) {
+	storageDuration.WithLabelValues(operation).Observe(time.Since(t1).Seconds())
+	if err != nil {
+		storageErrors.WithLabelValues(operation, errorCategory(err)).Inc()
+	}
+}
+
+// errorCategory returns the label of the storage error category err belongs to.
+func errorCategory(err error) string {
+	switch {
+	case errors.Is(err, storage.ErrNotFound):
+		return "not_found"
+	case errors.Is(err, storage.ErrConflict):
+		return "conflict"
+	case errors.Is(err, storage.ErrDeleted):
+		return "deleted"
+	case errors.Is(err, storage.ErrExpired):
+		return "expired"
+	case errors.Is(err, storage.ErrUnavailable):
+		return "unavailable"
+	case errors.Is(err, context.Canceled), errors.Is(err, context.DeadlineExceeded):
+		return "canceled"
+	}
+	return "other"
+}
+
+func (d *database) CreateUser(ctx context.Context) (userID int, err error) {
+	defer func(t1 time.Time) { observe("CreateUser", t1, err) }(time.Now())
+	return d.db.CreateUser(ctx)
+}
+
+func (d *database) UserExists(ctx context.Context, userID int) (exists bool, err error) {
+	defer func(t1 time.Time) { observe("UserExists", t1, err) }(time.Now())
+	return d.db.UserExists(ctx, userID)
+}
+
+func (d *database) CreateAPIKey(ctx context.Context, key models.APIKey) (err error) {
+	defer func(t1 time.Time) { observe("CreateAPIKey", t1, err) }(time.Now())
+	return d.db.CreateAPIKey(ctx, key)
+}
+
+func (d *database) GetAPIKeyByHash(ctx context.Context, hash string) (key models.APIKey, err error) {
+	defer func(t1 time.Time) { observe("GetAPIKeyByHash", t1, err) }(time.Now())
+	return d.db.GetAPIKeyByHash(ctx, hash)
+}
+
+func (d *database) ListAPIKeys(ctx context.Context, userID int) (keys []models.APIKey, err error) {
+	defer func(t1 time.Time) { observe("ListAPIKeys", t1, err) }(time.Now())
+	return d.db.ListAPIKeys(ctx, userID)
+}
+
+func (d *database) RevokeAPIKey(ctx context.Context, id string, userID int, revokedAt time.Time) (err error) {
+	defer func(t1 time.Time) { observe("RevokeAPIKey", t1, err) }(time.Now())
+	return d.db.RevokeAPIKey(ctx, id, userID, revokedAt)
+}
+
+func (d *database) SetValue(ctx context.Context, shortURL, longURL string, userID int, expiresAt time.Time) (storedShortURL string, err error) {
+	defer func(t1 time.Time) { observe("SetValue", t1, err) }(time.Now())
+	return d.db.SetValue(ctx, shortURL, longURL, userID, expiresAt)
+}
+
+func (d *database) SetValues(ctx context.Context, urls []models.BatchURL, userID int) (err error) {
+	defer func(t1 time.Time) { observe("SetValues", t1, err) }(time.Now())
+	return d.db.SetValues(ctx, urls, userID)
+}
+
+func (d *database) SetAlias(ctx context.Context, alias, longURL string, userID int, expiresAt time.Time) (ownerID int, err error) {
+	defer func(t1 time.Time) { observe("SetAlias", t1, err) }(time.Now())
+	return d.db.SetAlias(ctx, alias, longURL, userID, expiresAt)
+}
+
+func (d *database) GetOriginal(ctx context.Context, shortURL string) (longURL string, err error) {
+	defer func(t1 time.Time) { observe("GetOriginal", t1, err) }(time.Now())
+	return d.db.GetOriginal(ctx, shortURL)
+}
+
+func (d *database) GetURLsByUserID(ctx context.Context, userID int) (urls []models.URLPair, err error) {
+	defer func(t1 time.Time) { observe("GetURLsByUserID", t1, err) }(time.Now())
+	return d.db.GetURLsByUserID(ctx, userID)
+}
+
+func (d *database) DeleteURLs(ctx context.Context, deletions []models.URLsClientID) (results []map[string]string, err error) {
+	defer func(t1 time.Time) { observe("DeleteURLs", t1, err) }(time.Now())
+	return d.db.DeleteURLs(ctx, deletions)
+}
+
+func (d *database) RestoreURLs(ctx context.Context, shortURLs []string, userID int, deletedAfter time.Time) (results map[string]string, err error) {
+	defer func(t1 time.Time) { observe("RestoreURLs", t1, err) }(time.Now())
+	return d.db.RestoreURLs(ctx, shortURLs, userID, deletedAfter)
+}
+
+func (d *database) PurgeDeleted(ctx context.Context, deletedBefore time.Time) (purged int, err error) {
+	defer func(t1 time.Time) { observe("PurgeDeleted", t1, err) }(time.Now())
+	return d.db.PurgeDeleted(ctx, deletedBefore)
+}
+
+func (d *database) DeleteExpired(ctx context.Context, now time.Time) (deleted int, err error) {
+	defer func(t1 time.Time) { observe("DeleteExpired", t1, err) }(time.Now())
+	return d.db.DeleteExpired(ctx, now)
+}
+
+func (d *database) CountURLs(ctx context.Context) (count int, err error) {
+	defer func(t1 time.Time) { observe("CountURLs", t1, err) }(time.Now())
+	return d.db.CountURLs(ctx)
+}
+
+func (d *database) CountUsers(ctx context.Context) (count int, err error) {
+	defer func(t1 time.Time) { observe("CountUsers", t1, err) }(time.Now())
+	return d.db.CountUsers(ctx)
+}
+
+func (d *database) SaveClicks(ctx context.Context, clicks []models.ClickEvent) (err error) {
+	defer func(t1 time.Time) { observe("SaveClicks", t1, err) }(time.Now())
+	return d.db.SaveClicks(ctx, clicks)
+}
+
+func (d *database) GetLinkStats(ctx context.Context, shortURL string, userID int) (stats models.LinkStats, err error) {
+	defer func(t1 time.Time) { observe("GetLinkStats", t1, err) }(time.Now())
+	return d.db.GetLinkStats(ctx, shortURL, userID)
+}
+
+func (d *database) Ping(ctx context.Context) (err error) {
+	defer func(t1 time.Time) { observe("Ping", t1, err) }(time.Now())
+	return d.db.Ping(ctx)
+}
+
+func (d *database) Close() error {
+	return d.db.Close()
+}
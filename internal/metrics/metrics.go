@@ -0,0 +1,140 @@
+// Package metrics provides Prometheus metrics of the HTTP server, the storage and the deletion pipeline.
+package metrics
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/DariSorokina/go-first-sprint/internal/logger"
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// registry holds the metrics of this package together with the Go runtime and process metrics.
+var registry = prometheus.NewRegistry()
+
+var (
+	httpRequests = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "shortener_http_requests_total",
+		Help: "HTTP requests by method, chi route pattern and status code.",
+	}, []string{"method", "route", "status"})
+	httpDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "shortener_http_request_duration_seconds",
+		Help:    "HTTP request latency by method, chi route pattern and status code.",
+		Buckets: prometheus.DefBuckets,
+	}, []string{"method", "route", "status"})
+	storageDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "shortener_storage_operation_duration_seconds",
+		Help:    "Storage operation latency by Database method.",
+		Buckets: prometheus.DefBuckets,
+	}, []string{"operation"})
+	storageErrors = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "shortener_storage_errors_total",
+		Help: "Storage operation errors by Database method and error category.",
+	}, []string{"operation", "category"})
+	urlsShortened = prometheus.NewCounter(prometheus.CounterOpts{
+		Name: "shortener_urls_shortened_total",
+		Help: "Short URLs created, including aliases and batch items, but not already shortened URLs.",
+	})
+	redirects = prometheus.NewCounter(prometheus.CounterOpts{
+		Name: "shortener_redirects_total",
+		Help: "Short URLs resolved to their original URLs.",
+	})
+)
+
+func init() {
+	registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		httpRequests, httpDuration, storageDuration, storageErrors, urlsShortened, redirects,
+	)
+}
+
+// URLsShortened counts n newly created short URLs.
+func URLsShortened(n int) {
+	urlsShortened.Add(float64(n))
+}
+
+// Redirected counts a short URL resolved to its original URL.
+func Redirected() {
+	redirects.Inc()
+}
+
+// RegisterDeleteQueue exposes the number of deletion requests waiting in the queue, as reported by depth.
+// It can only be called once.
+func RegisterDeleteQueue(depth func() int) error {
+	return registry.Register(prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+		Name: "shortener_delete_queue_depth",
+		Help: "Deletion requests waiting in the queue.",
+	}, func() float64 { return float64(depth()) }))
+}
+
+// Handler returns the handler serving the metrics in the Prometheus text format.
+func Handler() http.Handler {
+	return promhttp.HandlerFor(registry, promhttp.HandlerOpts{})
+}
+
+// Middleware is a middleware counting requests and observing their latency by the chi route pattern they matched,
+// so paths holding short URLs do not make a label value each. Requests matching no route are labeled "other".
+func Middleware(h http.Handler) http.Handler {
+	fn := func(w http.ResponseWriter, r *http.Request) {
+		ww := middleware.NewWrapResponseWriter(w, r.ProtoMajor)
+		t1 := time.Now()
+		h.ServeHTTP(ww, r)
+
+		route := "other"
+		if routeContext := chi.RouteContext(r.Context()); routeContext != nil && routeContext.RoutePattern() != "" {
+			route = routeContext.RoutePattern()
+		}
+		status := ww.Status()
+		if status == 0 {
+			status = http.StatusOK
+		}
+		labels := prometheus.Labels{"method": r.Method, "route": route, "status": strconv.Itoa(status)}
+		httpRequests.With(labels).Inc()
+		httpDuration.With(labels).Observe(time.Since(t1).Seconds())
+	}
+	return http.HandlerFunc(fn)
+}
+
+// Run serves the metrics on addr until ctx is done, then shuts the listener down within shutdownTimeout.
+// It returns at once if addr is empty.
+func Run(ctx context.Context, addr string, shutdownTimeout time.Duration, l *logger.Logger) error {
+	if addr == "" {
+		return nil
+	}
+
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", Handler())
+	httpServer := &http.Server{Addr: addr, Handler: mux}
+
+	serveErr := make(chan error, 1)
+	go func() {
+		l.Sugar().Infof("Running metrics server on %s", addr)
+		serveErr <- httpServer.ListenAndServe()
+	}()
+
+	select {
+	case err := <-serveErr:
+		return err
+	case <-ctx.Done():
+	}
+
+	l.Info("Shutting down metrics server")
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+
+	if err := httpServer.Shutdown(shutdownCtx); err != nil {
+		return err
+	}
+	if err := <-serveErr; !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,46 @@
+package metrics
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/DariSorokina/go-first-sprint/internal/logger"
+	"github.com/DariSorokina/go-first-sprint/internal/storage"
+	"github.com/go-chi/chi/v5"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMiddlewareLabelsRoutePattern(t *testing.T) {
+	router := chi.NewRouter()
+	router.Use(Middleware)
+	router.Get("/{id}", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusTemporaryRedirect)
+	})
+
+	for _, path := range []string{"/abc", "/def", "/missing/path"} {
+		router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, path, nil))
+	}
+
+	assert.Equal(t, 2.0, testutil.ToFloat64(httpRequests.WithLabelValues(http.MethodGet, "/{id}", "307")))
+	assert.Equal(t, 1.0, testutil.ToFloat64(httpRequests.WithLabelValues(http.MethodGet, "other", "404")))
+}
+
+func TestDatabaseCountsErrorsByCategory(t *testing.T) {
+	l, err := logger.CreateLogger("error")
+	require.NoError(t, err)
+
+	db := NewDatabase(storage.NewStorage("", l))
+	defer db.Close()
+
+	_, err = db.GetOriginal(context.Background(), "missing")
+	require.ErrorIs(t, err, storage.ErrNotFound)
+	_, err = db.GetOriginal(context.Background(), "d41d8cd98f")
+	require.NoError(t, err)
+
+	assert.Equal(t, 1.0, testutil.ToFloat64(storageErrors.WithLabelValues("GetOriginal", "not_found")))
+	assert.Equal(t, 1, testutil.CollectAndCount(storageDuration, "shortener_storage_operation_duration_seconds"))
+}
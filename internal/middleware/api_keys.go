@@ -0,0 +1,98 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net/http"
+	"slices"
+	"strings"
+
+	"github.com/DariSorokina/go-first-sprint/internal/app"
+	"github.com/DariSorokina/go-first-sprint/internal/cookie"
+	"github.com/DariSorokina/go-first-sprint/internal/models"
+)
+
+// APIKeyAuthenticator looks up the API key a request is made with.
+type APIKeyAuthenticator interface {
+	AuthenticateAPIKey(ctx context.Context, key string) (apiKey models.APIKey, err error)
+}
+
+type apiKeyContextKey struct{}
+
+// APIKeyFromContext returns the API key the request was authenticated with by APIKeyMiddleware.
+// It reports false if the request was authenticated otherwise.
+func APIKeyFromContext(ctx context.Context) (apiKey models.APIKey, ok bool) {
+	apiKey, ok = ctx.Value(apiKeyContextKey{}).(models.APIKey)
+	return apiKey, ok
+}
+
+// WithAPIKey returns a copy of ctx carrying the API key a request or call was authenticated with.
+func WithAPIKey(ctx context.Context, apiKey models.APIKey) context.Context {
+	return context.WithValue(ctx, apiKeyContextKey{}, apiKey)
+}
+
+// APIKeyMiddleware returns a middleware that authenticates requests carrying an
+// "Authorization: Bearer sk_..." header with the API key and puts its user and the key into the context.
+// An unknown or revoked key is rejected with 401. All other requests are passed to fallback,
+// usually cookie.Tokens.CookieMiddleware.
+func APIKeyMiddleware(authenticator APIKeyAuthenticator, fallback func(h http.Handler) http.Handler) func(h http.Handler) http.Handler {
+	return func(h http.Handler) http.Handler {
+		next := fallback(h)
+		fn := func(w http.ResponseWriter, r *http.Request) {
+			key, ok := cookie.BearerToken(r.Header.Get("Authorization"))
+			if !ok || !strings.HasPrefix(key, app.APIKeyPrefix) {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			r.Header.Del("ClientID")
+			apiKey, err := authenticator.AuthenticateAPIKey(r.Context(), key)
+			if errors.Is(err, app.ErrInvalidAPIKey) {
+				w.Header().Set("WWW-Authenticate", `Bearer error="invalid_token"`)
+				http.Error(w, "Unauthorized", http.StatusUnauthorized)
+				return
+			}
+			if err != nil {
+				log.Println(err)
+				http.Error(w, "server error", http.StatusServiceUnavailable)
+				return
+			}
+
+			ctx := cookie.WithUserID(r.Context(), apiKey.UserID)
+			h.ServeHTTP(w, r.WithContext(WithAPIKey(ctx, apiKey)))
+		}
+		return http.HandlerFunc(fn)
+	}
+}
+
+// RequireScope returns a middleware that rejects requests authenticated with an API key lacking the scope
+// with 403 Forbidden. Requests authenticated with a cookie or a JWT are allowed everything.
+func RequireScope(scope string) func(h http.Handler) http.Handler {
+	return func(h http.Handler) http.Handler {
+		fn := func(w http.ResponseWriter, r *http.Request) {
+			if apiKey, ok := APIKeyFromContext(r.Context()); ok && !slices.Contains(apiKey.Scopes, scope) {
+				w.Header().Set("WWW-Authenticate", `Bearer error="insufficient_scope", scope="`+scope+`"`)
+				http.Error(w, "Forbidden", http.StatusForbidden)
+				return
+			}
+			h.ServeHTTP(w, r)
+		}
+		return http.HandlerFunc(fn)
+	}
+}
+
+// RequireSession returns a middleware that rejects requests authenticated with an API key with 403 Forbidden,
+// so a leaked key can not be used to issue or revoke keys.
+func RequireSession() func(h http.Handler) http.Handler {
+	return func(h http.Handler) http.Handler {
+		fn := func(w http.ResponseWriter, r *http.Request) {
+			if _, ok := APIKeyFromContext(r.Context()); ok {
+				http.Error(w, "Forbidden", http.StatusForbidden)
+				return
+			}
+			h.ServeHTTP(w, r)
+		}
+		return http.HandlerFunc(fn)
+	}
+}
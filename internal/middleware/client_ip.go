@@ -0,0 +1,78 @@
+package middleware
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"strings"
+)
+
+type clientIPContextKey struct{}
+
+// ClientIPMiddleware returns a middleware that resolves the client address of each request for ClientIP.
+// The "X-Real-IP" and "X-Forwarded-For" headers are only trusted on connections from one of the trusted
+// proxies, as any client can send them; otherwise the address of the connection is the client address.
+// Behind a chain of proxies, the right-most X-Forwarded-For address that is not a trusted proxy is used.
+func ClientIPMiddleware(trustedProxies []*net.IPNet) func(h http.Handler) http.Handler {
+	return func(h http.Handler) http.Handler {
+		fn := func(w http.ResponseWriter, r *http.Request) {
+			ip := ResolveClientIP(r.RemoteAddr, r.Header, trustedProxies)
+			h.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), clientIPContextKey{}, ip)))
+		}
+		return http.HandlerFunc(fn)
+	}
+}
+
+// ClientIP returns the client address resolved by ClientIPMiddleware,
+// or the address of the connection if the middleware did not run.
+func ClientIP(r *http.Request) string {
+	if ip, ok := r.Context().Value(clientIPContextKey{}).(string); ok {
+		return ip
+	}
+	return remoteIP(r.RemoteAddr)
+}
+
+// ResolveClientIP returns the client address of a connection from remoteAddr with the given
+// "X-Real-IP" and "X-Forwarded-For" headers the way ClientIPMiddleware does. Servers other than HTTP,
+// such as the gRPC server, pass the same values from their metadata.
+func ResolveClientIP(remoteAddr string, header http.Header, trustedProxies []*net.IPNet) string {
+	peer := remoteIP(remoteAddr)
+	if !trusted(net.ParseIP(peer), trustedProxies) {
+		return peer
+	}
+
+	if realIP := net.ParseIP(strings.TrimSpace(header.Get("X-Real-IP"))); realIP != nil {
+		return realIP.String()
+	}
+	forwarded := strings.Split(strings.Join(header.Values("X-Forwarded-For"), ","), ",")
+	for i := len(forwarded) - 1; i >= 0; i-- {
+		ip := net.ParseIP(strings.TrimSpace(forwarded[i]))
+		if ip == nil {
+			break
+		}
+		if !trusted(ip, trustedProxies) {
+			return ip.String()
+		}
+	}
+	return peer
+}
+
+func trusted(ip net.IP, trustedProxies []*net.IPNet) bool {
+	if ip == nil {
+		return false
+	}
+	for _, subnet := range trustedProxies {
+		if subnet.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+func remoteIP(remoteAddr string) string {
+	host, _, err := net.SplitHostPort(remoteAddr)
+	if err != nil {
+		return remoteAddr
+	}
+	return host
+}
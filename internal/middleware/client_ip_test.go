@@ -0,0 +1,68 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/DariSorokina/go-first-sprint/internal/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestClientIPMiddleware(t *testing.T) {
+	proxies, err := config.ParseTrustedProxies("10.0.0.0/8")
+	require.NoError(t, err)
+
+	testCases := []struct {
+		name       string
+		remoteAddr string
+		header     http.Header
+		expectedIP string
+	}{
+		{
+			name:       "direct connection",
+			remoteAddr: "192.0.2.1:1000",
+			expectedIP: "192.0.2.1",
+		},
+		{
+			name:       "headers from an untrusted client",
+			remoteAddr: "192.0.2.1:1000",
+			header:     http.Header{"X-Real-Ip": {"198.51.100.7"}, "X-Forwarded-For": {"198.51.100.8"}},
+			expectedIP: "192.0.2.1",
+		},
+		{
+			name:       "X-Real-IP from a trusted proxy",
+			remoteAddr: "10.0.0.2:1000",
+			header:     http.Header{"X-Real-Ip": {"198.51.100.7"}},
+			expectedIP: "198.51.100.7",
+		},
+		{
+			name:       "X-Forwarded-For through a chain of trusted proxies",
+			remoteAddr: "10.0.0.2:1000",
+			header:     http.Header{"X-Forwarded-For": {"203.0.113.9, 198.51.100.7", "10.0.0.3"}},
+			expectedIP: "198.51.100.7",
+		},
+		{
+			name:       "trusted proxy without headers",
+			remoteAddr: "10.0.0.2:1000",
+			expectedIP: "10.0.0.2",
+		},
+	}
+	for _, test := range testCases {
+		t.Run(test.name, func(t *testing.T) {
+			var clientIP string
+			handler := ClientIPMiddleware(proxies)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				clientIP = ClientIP(r)
+			}))
+
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			req.RemoteAddr = test.remoteAddr
+			for key, values := range test.header {
+				req.Header[key] = values
+			}
+			handler.ServeHTTP(httptest.NewRecorder(), req)
+			assert.Equal(t, test.expectedIP, clientIP)
+		})
+	}
+}
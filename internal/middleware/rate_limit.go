@@ -0,0 +1,271 @@
+package middleware
+
+import (
+	"context"
+	"log"
+	"math"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/DariSorokina/go-first-sprint/internal/config"
+	"github.com/DariSorokina/go-first-sprint/internal/cookie"
+)
+
+// RateLimitDecision is the state of a token bucket after a request was counted against it.
+type RateLimitDecision struct {
+	Allowed    bool          // Whether the request fits the budget.
+	Remaining  int           // Requests that can be made right away.
+	Reset      time.Duration // Time until the bucket is full again.
+	RetryAfter time.Duration // Time until the next request is allowed, zero if it is allowed now.
+}
+
+// RateLimitBucket names a token bucket and the budget it is refilled with.
+type RateLimitBucket struct {
+	Key   string
+	Limit config.RateLimit
+}
+
+// RateLimitStore keeps token buckets by key. MemoryRateLimitStore keeps them in the process;
+// a store shared by all replicas, such as one backed by Redis, makes the budgets global.
+type RateLimitStore interface {
+	// Peek returns the decision each of the buckets would make now without taking a token.
+	Peek(ctx context.Context, buckets []RateLimitBucket, now time.Time) (decisions []RateLimitDecision, err error)
+	// Take takes a token from each of the buckets if every one of them has a token, and none otherwise,
+	// so a request denied by one budget does not spend the others. It returns the decision of each bucket.
+	Take(ctx context.Context, buckets []RateLimitBucket, now time.Time) (decisions []RateLimitDecision, err error)
+}
+
+type tokenBucket struct {
+	tokens  float64
+	updated time.Time
+	full    time.Time // Time the bucket is full again, after which it can be forgotten.
+}
+
+// MemoryRateLimitStore is a RateLimitStore keeping token buckets in memory.
+type MemoryRateLimitStore struct {
+	mutex     sync.Mutex
+	buckets   map[string]*tokenBucket
+	lastSweep time.Time
+}
+
+// NewMemoryRateLimitStore creates an empty MemoryRateLimitStore.
+func NewMemoryRateLimitStore() *MemoryRateLimitStore {
+	return &MemoryRateLimitStore{buckets: make(map[string]*tokenBucket)}
+}
+
+// memorySweepInterval is how often full buckets are removed, so idle clients do not hold memory.
+const memorySweepInterval = time.Minute
+
+// Peek refills the buckets for the time passed since their previous request and reports whether each has a token.
+func (store *MemoryRateLimitStore) Peek(ctx context.Context, buckets []RateLimitBucket, now time.Time) (decisions []RateLimitDecision, err error) {
+	return store.decide(buckets, now, false), nil
+}
+
+// Take refills the buckets for the time passed since their previous request and takes a token from each of them
+// if all of them have one.
+func (store *MemoryRateLimitStore) Take(ctx context.Context, buckets []RateLimitBucket, now time.Time) (decisions []RateLimitDecision, err error) {
+	return store.decide(buckets, now, true), nil
+}
+
+func (store *MemoryRateLimitStore) decide(buckets []RateLimitBucket, now time.Time, take bool) []RateLimitDecision {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+
+	if now.Sub(store.lastSweep) >= memorySweepInterval {
+		for bucketKey, bucket := range store.buckets {
+			if !now.Before(bucket.full) {
+				delete(store.buckets, bucketKey)
+			}
+		}
+		store.lastSweep = now
+	}
+
+	states := make([]*tokenBucket, len(buckets))
+	allowed := true
+	for i, b := range buckets {
+		capacity := float64(b.Limit.Requests)
+		bucket, ok := store.buckets[b.Key]
+		if !ok {
+			bucket = &tokenBucket{tokens: capacity, updated: now}
+			store.buckets[b.Key] = bucket
+		}
+		if elapsed := now.Sub(bucket.updated); elapsed > 0 {
+			bucket.tokens = math.Min(capacity, bucket.tokens+float64(elapsed)/perToken(b.Limit))
+			bucket.updated = now
+		}
+		states[i] = bucket
+		allowed = allowed && bucket.tokens >= 1
+	}
+
+	decisions := make([]RateLimitDecision, len(buckets))
+	for i, b := range buckets {
+		bucket, decision := states[i], &decisions[i]
+		if bucket.tokens >= 1 {
+			decision.Allowed = true
+			if take && allowed {
+				bucket.tokens--
+			}
+		} else {
+			decision.RetryAfter = time.Duration((1 - bucket.tokens) * perToken(b.Limit))
+		}
+		decision.Remaining = int(bucket.tokens)
+		decision.Reset = time.Duration((float64(b.Limit.Requests) - bucket.tokens) * perToken(b.Limit))
+		bucket.full = now.Add(decision.Reset)
+	}
+	return decisions
+}
+
+// perToken returns the nanoseconds it takes to refill one token of the budget.
+func perToken(limit config.RateLimit) float64 {
+	return float64(limit.Period) / float64(limit.Requests)
+}
+
+// RateLimiter limits requests to each route with separate token buckets per authenticated user and per client IP.
+type RateLimiter struct {
+	store      RateLimitStore
+	userLimits map[string]config.RateLimit
+	ipLimits   map[string]config.RateLimit
+	now        func() time.Time
+}
+
+// NewRateLimiter creates a RateLimiter keeping its buckets in store with the budgets of each route
+// given per user and per client IP. Routes without a budget are not limited.
+func NewRateLimiter(store RateLimitStore, userLimits, ipLimits map[string]config.RateLimit) *RateLimiter {
+	return &RateLimiter{store: store, userLimits: userLimits, ipLimits: ipLimits, now: time.Now}
+}
+
+// Limit returns a middleware counting requests against the budgets of route. The user is authenticated
+// by authenticate, which may be nil for routes without users; requests from a client IP whose budget is spent
+// are rejected before it runs, so they can not create users. A request exceeding either budget gets
+// 429 Too Many Requests with a Retry-After header and spends neither budget. The RateLimit-Policy, RateLimit-Limit,
+// RateLimit-Remaining and RateLimit-Reset headers describe the budget closest to running out.
+// If the store fails, requests are let through.
+//
+// The client IP is the one resolved by ClientIPMiddleware, so behind a proxy that is not trusted
+// all clients share the budget of the proxy.
+func (limiter *RateLimiter) Limit(route string, authenticate func(h http.Handler) http.Handler) func(h http.Handler) http.Handler {
+	return func(h http.Handler) http.Handler {
+		limited := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			userID, _ := cookie.UserIDFromContext(r.Context())
+			buckets := limiter.buckets(route, userID, ClientIP(r))
+			if len(buckets) == 0 {
+				h.ServeHTTP(w, r)
+				return
+			}
+
+			decisions, err := limiter.store.Take(r.Context(), buckets, limiter.now())
+			if err != nil {
+				log.Println(err)
+				h.ServeHTTP(w, r)
+				return
+			}
+			if limiter.respond(w, buckets, decisions) {
+				h.ServeHTTP(w, r)
+			}
+		})
+		if authenticate == nil {
+			return limited
+		}
+
+		authenticated := authenticate(limited)
+		fn := func(w http.ResponseWriter, r *http.Request) {
+			if buckets := limiter.buckets(route, 0, ClientIP(r)); len(buckets) > 0 {
+				decisions, err := limiter.store.Peek(r.Context(), buckets, limiter.now())
+				if err != nil {
+					log.Println(err)
+				} else if !decisions[0].Allowed {
+					limiter.respond(w, buckets, decisions)
+					return
+				}
+			}
+			authenticated.ServeHTTP(w, r)
+		}
+		return http.HandlerFunc(fn)
+	}
+}
+
+// PeekIP reports whether the budget of the client IP for route allows a request now without spending it,
+// and otherwise the time until it does. Callers outside HTTP, such as the gRPC server, check it before
+// authenticating the user, like Limit does. If the store fails, the request is allowed.
+func (limiter *RateLimiter) PeekIP(ctx context.Context, route, clientIP string) (allowed bool, retryAfter time.Duration) {
+	buckets := limiter.buckets(route, 0, clientIP)
+	if len(buckets) == 0 {
+		return true, 0
+	}
+	decisions, err := limiter.store.Peek(ctx, buckets, limiter.now())
+	if err != nil {
+		log.Println(err)
+		return true, 0
+	}
+	return summarize(decisions)
+}
+
+// Take spends a request of the user and the client IP on route if both budgets allow it, and otherwise
+// reports the time until they do. A userID of zero counts against the client IP budget only.
+// If the store fails, the request is allowed.
+func (limiter *RateLimiter) Take(ctx context.Context, route string, userID int, clientIP string) (allowed bool, retryAfter time.Duration) {
+	buckets := limiter.buckets(route, userID, clientIP)
+	if len(buckets) == 0 {
+		return true, 0
+	}
+	decisions, err := limiter.store.Take(ctx, buckets, limiter.now())
+	if err != nil {
+		log.Println(err)
+		return true, 0
+	}
+	return summarize(decisions)
+}
+
+// buckets returns the token buckets of route for the user, if userID is not zero, and the client IP.
+// Both are keyed the same way for every protocol, so HTTP and gRPC requests share the budgets.
+func (limiter *RateLimiter) buckets(route string, userID int, clientIP string) []RateLimitBucket {
+	var buckets []RateLimitBucket
+	if limit, ok := limiter.userLimits[route]; ok && userID != 0 {
+		buckets = append(buckets, RateLimitBucket{Key: "user:" + route + ":" + strconv.Itoa(userID), Limit: limit})
+	}
+	if limit, ok := limiter.ipLimits[route]; ok {
+		buckets = append(buckets, RateLimitBucket{Key: "ip:" + route + ":" + clientIP, Limit: limit})
+	}
+	return buckets
+}
+
+// summarize reports whether every decision allows the request and otherwise the longest time until they do.
+func summarize(decisions []RateLimitDecision) (allowed bool, retryAfter time.Duration) {
+	allowed = true
+	for _, decision := range decisions {
+		allowed = allowed && decision.Allowed
+		retryAfter = max(retryAfter, decision.RetryAfter)
+	}
+	return allowed, retryAfter
+}
+
+// respond sets the rate limit headers for the decisions of the buckets and reports whether the request is allowed.
+// A denied request gets 429 Too Many Requests.
+func (limiter *RateLimiter) respond(w http.ResponseWriter, buckets []RateLimitBucket, decisions []RateLimitDecision) (allowed bool) {
+	// A denying budget is reported over an allowing one, then the one with fewer requests left.
+	header, headerLimit := decisions[0], buckets[0].Limit
+	allowed = header.Allowed
+	for i, decision := range decisions[1:] {
+		if (!decision.Allowed && header.Allowed) ||
+			(decision.Allowed == header.Allowed && decision.Remaining < header.Remaining) {
+			header, headerLimit = decision, buckets[i+1].Limit
+		}
+		allowed = allowed && decision.Allowed
+	}
+
+	w.Header().Set("RateLimit-Policy", strconv.Itoa(headerLimit.Requests)+";w="+strconv.Itoa(ceilSeconds(headerLimit.Period)))
+	w.Header().Set("RateLimit-Limit", strconv.Itoa(headerLimit.Requests))
+	w.Header().Set("RateLimit-Remaining", strconv.Itoa(header.Remaining))
+	w.Header().Set("RateLimit-Reset", strconv.Itoa(ceilSeconds(header.Reset)))
+	if !allowed {
+		w.Header().Set("Retry-After", strconv.Itoa(max(1, ceilSeconds(header.RetryAfter))))
+		http.Error(w, "Too Many Requests", http.StatusTooManyRequests)
+	}
+	return allowed
+}
+
+func ceilSeconds(d time.Duration) int {
+	return int(math.Ceil(d.Seconds()))
+}
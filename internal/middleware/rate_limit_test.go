@@ -0,0 +1,96 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/DariSorokina/go-first-sprint/internal/config"
+	"github.com/DariSorokina/go-first-sprint/internal/cookie"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRateLimiter(t *testing.T) {
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	limiter := NewRateLimiter(NewMemoryRateLimitStore(),
+		map[string]config.RateLimit{config.RateLimitShorten: {Requests: 2, Period: time.Minute}},
+		map[string]config.RateLimit{config.RateLimitShorten: {Requests: 3, Period: time.Minute}})
+	limiter.now = func() time.Time { return now }
+	handler := limiter.Limit(config.RateLimitShorten, nil)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusCreated)
+	}))
+
+	request := func(userID int, remoteAddr string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/", nil)
+		req.RemoteAddr = remoteAddr
+		if userID != 0 {
+			req = req.WithContext(cookie.WithUserID(req.Context(), userID))
+		}
+		recorder := httptest.NewRecorder()
+		handler.ServeHTTP(recorder, req)
+		return recorder
+	}
+
+	result := request(1, "192.0.2.1:1000")
+	assert.Equal(t, http.StatusCreated, result.Code)
+	assert.Equal(t, "2", result.Header().Get("RateLimit-Limit"), "the user budget is closest to running out")
+	assert.Equal(t, "1", result.Header().Get("RateLimit-Remaining"))
+	assert.Equal(t, "2;w=60", result.Header().Get("RateLimit-Policy"))
+
+	assert.Equal(t, http.StatusCreated, request(1, "192.0.2.1:1001").Code)
+	result = request(1, "192.0.2.1:1002")
+	assert.Equal(t, http.StatusTooManyRequests, result.Code, "the user budget is spent")
+	assert.Equal(t, "30", result.Header().Get("Retry-After"))
+	assert.Equal(t, "0", result.Header().Get("RateLimit-Remaining"))
+
+	assert.Equal(t, http.StatusCreated, request(2, "192.0.2.1:1003").Code, "a denied request does not spend the IP budget")
+	result = request(3, "192.0.2.1:1004")
+	assert.Equal(t, http.StatusTooManyRequests, result.Code, "another user on the same IP hits the IP budget")
+	assert.Equal(t, "3", result.Header().Get("RateLimit-Limit"))
+	assert.Equal(t, http.StatusCreated, request(3, "192.0.2.2:1000").Code, "other IPs have their own budget")
+	assert.Equal(t, http.StatusCreated, request(3, "192.0.2.2:1001").Code, "a denied request does not spend the user budget")
+
+	now = now.Add(30 * time.Second)
+	assert.Equal(t, http.StatusCreated, request(1, "192.0.2.3:1000").Code, "a token is refilled after period/requests")
+	assert.Equal(t, http.StatusTooManyRequests, request(1, "192.0.2.3:1001").Code)
+
+	unlimited := limiter.Limit(config.RateLimitRedirect, nil)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	recorder := httptest.NewRecorder()
+	unlimited.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/abc", nil))
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	assert.Empty(t, recorder.Header().Get("RateLimit-Limit"), "routes without a budget are not limited")
+}
+
+func TestRateLimiterChecksIPBeforeAuthentication(t *testing.T) {
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	limiter := NewRateLimiter(NewMemoryRateLimitStore(),
+		map[string]config.RateLimit{config.RateLimitShorten: {Requests: 5, Period: time.Minute}},
+		map[string]config.RateLimit{config.RateLimitShorten: {Requests: 2, Period: time.Minute}})
+	limiter.now = func() time.Time { return now }
+
+	var authenticated int
+	authenticate := func(h http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			authenticated++
+			h.ServeHTTP(w, r.WithContext(cookie.WithUserID(r.Context(), authenticated)))
+		})
+	}
+	handler := limiter.Limit(config.RateLimitShorten, authenticate)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusCreated)
+	}))
+
+	request := func() int {
+		req := httptest.NewRequest(http.MethodPost, "/", nil)
+		req.RemoteAddr = "192.0.2.1:1000"
+		recorder := httptest.NewRecorder()
+		handler.ServeHTTP(recorder, req)
+		return recorder.Code
+	}
+
+	assert.Equal(t, http.StatusCreated, request())
+	assert.Equal(t, http.StatusCreated, request())
+	assert.Equal(t, http.StatusTooManyRequests, request())
+	assert.Equal(t, http.StatusTooManyRequests, request())
+	assert.Equal(t, 2, authenticated, "requests over the IP budget are not authenticated")
+}
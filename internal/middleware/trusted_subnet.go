@@ -0,0 +1,29 @@
+package middleware
+
+import (
+	"net"
+	"net/http"
+)
+
+// TrustedSubnetMiddleware returns a middleware that only lets through requests whose client IP, as resolved
+// by ClientIPMiddleware, is inside the trusted subnet given in CIDR notation. The "X-Real-IP" header therefore
+// only counts on connections from a trusted proxy. All other requests get 403 Forbidden, and so does every
+// request if the subnet is empty or can not be parsed.
+func TrustedSubnetMiddleware(trustedSubnet string) func(h http.Handler) http.Handler {
+	_, subnet, err := net.ParseCIDR(trustedSubnet)
+	if err != nil {
+		subnet = nil
+	}
+
+	return func(h http.Handler) http.Handler {
+		fn := func(w http.ResponseWriter, r *http.Request) {
+			realIP := net.ParseIP(ClientIP(r))
+			if subnet == nil || realIP == nil || !subnet.Contains(realIP) {
+				http.Error(w, "Forbidden", http.StatusForbidden)
+				return
+			}
+			h.ServeHTTP(w, r)
+		}
+		return http.HandlerFunc(fn)
+	}
+}
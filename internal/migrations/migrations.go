@@ -0,0 +1,230 @@
+// Package migrations provides versioned PostgreSQL schema migrations embedded in the binary.
+package migrations
+
+import (
+	"context"
+	"database/sql"
+	"embed"
+	"fmt"
+	"io/fs"
+	"regexp"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/DariSorokina/go-first-sprint/internal/logger"
+)
+
+//go:embed sql/*.sql
+var migrationFiles embed.FS
+
+// migrationFilePattern matches file names like 0001_create_urls.up.sql.
+var migrationFilePattern = regexp.MustCompile(`^(\d+)_(\w+)\.(up|down)\.sql$`)
+
+// lockKey identifies the advisory lock held while migrations run, so only one instance migrates at a time.
+const lockKey = 727_100_001
+
+const (
+	createVersionsTableQuery = `CREATE TABLE IF NOT EXISTS public.schema_migrations (
+		version INTEGER PRIMARY KEY,
+		name TEXT NOT NULL,
+		appliedAt TIMESTAMPTZ NOT NULL DEFAULT now());`
+	readVersionsQuery  = `SELECT version, appliedAt FROM public.schema_migrations;`
+	writeVersionQuery  = `INSERT INTO public.schema_migrations (version, name) VALUES ($1, $2);`
+	deleteVersionQuery = `DELETE FROM public.schema_migrations WHERE version = $1;`
+	lockQuery          = `SELECT pg_advisory_lock($1);`
+	unlockQuery        = `SELECT pg_advisory_unlock($1);`
+)
+
+// Migration represents a schema change with the SQL to apply and to roll it back.
+type Migration struct {
+	Version int
+	Name    string
+	Up      string
+	Down    string
+}
+
+// Status represents whether a migration is applied and when.
+type Status struct {
+	Migration
+	Applied   bool
+	AppliedAt time.Time
+}
+
+// Migrator applies and rolls back the embedded migrations on a database.
+type Migrator struct {
+	db         *sql.DB
+	migrations []Migration
+	log        *logger.Logger
+}
+
+// NewMigrator creates a new Migrator instance for the database with the embedded migrations.
+func NewMigrator(db *sql.DB, l *logger.Logger) (*Migrator, error) {
+	migrations, err := load(migrationFiles)
+	if err != nil {
+		return nil, err
+	}
+	return &Migrator{db: db, migrations: migrations, log: l}, nil
+}
+
+func load(fsys fs.FS) ([]Migration, error) {
+	fileNames, err := fs.Glob(fsys, "sql/*.sql")
+	if err != nil {
+		return nil, err
+	}
+
+	byVersion := make(map[int]*Migration)
+	for _, fileName := range fileNames {
+		match := migrationFilePattern.FindStringSubmatch(fileName[len("sql/"):])
+		if match == nil {
+			return nil, fmt.Errorf("unexpected migration file name %s", fileName)
+		}
+
+		version, _ := strconv.Atoi(match[1])
+		content, err := fs.ReadFile(fsys, fileName)
+		if err != nil {
+			return nil, err
+		}
+
+		migration, ok := byVersion[version]
+		if !ok {
+			migration = &Migration{Version: version, Name: match[2]}
+			byVersion[version] = migration
+		}
+		if migration.Name != match[2] {
+			return nil, fmt.Errorf("migration %d has files with different names", version)
+		}
+		if match[3] == "up" {
+			migration.Up = string(content)
+		} else {
+			migration.Down = string(content)
+		}
+	}
+
+	migrations := make([]Migration, 0, len(byVersion))
+	for _, migration := range byVersion {
+		if migration.Up == "" || migration.Down == "" {
+			return nil, fmt.Errorf("migration %d must have both up and down files", migration.Version)
+		}
+		migrations = append(migrations, *migration)
+	}
+	sort.Slice(migrations, func(i, j int) bool {
+		return migrations[i].Version < migrations[j].Version
+	})
+	return migrations, nil
+}
+
+// Up applies all pending migrations in version order and returns the applied ones.
+func (migrator *Migrator) Up(ctx context.Context) (applied []Migration, err error) {
+	err = migrator.withLock(ctx, func(conn *sql.Conn, appliedAt map[int]time.Time) error {
+		for _, migration := range migrator.migrations {
+			if _, ok := appliedAt[migration.Version]; ok {
+				continue
+			}
+			if err := migrator.run(ctx, conn, migration.Up, writeVersionQuery, migration.Version, migration.Name); err != nil {
+				return fmt.Errorf("apply migration %d_%s: %w", migration.Version, migration.Name, err)
+			}
+			migrator.log.Sugar().Infof("Applied migration %d_%s", migration.Version, migration.Name)
+			applied = append(applied, migration)
+		}
+		return nil
+	})
+	return applied, err
+}
+
+// Down rolls back up to steps most recently applied migrations and returns the rolled back ones.
+func (migrator *Migrator) Down(ctx context.Context, steps int) (rolledBack []Migration, err error) {
+	err = migrator.withLock(ctx, func(conn *sql.Conn, appliedAt map[int]time.Time) error {
+		for i := len(migrator.migrations) - 1; i >= 0 && len(rolledBack) < steps; i-- {
+			migration := migrator.migrations[i]
+			if _, ok := appliedAt[migration.Version]; !ok {
+				continue
+			}
+			if err := migrator.run(ctx, conn, migration.Down, deleteVersionQuery, migration.Version); err != nil {
+				return fmt.Errorf("roll back migration %d_%s: %w", migration.Version, migration.Name, err)
+			}
+			migrator.log.Sugar().Infof("Rolled back migration %d_%s", migration.Version, migration.Name)
+			rolledBack = append(rolledBack, migration)
+		}
+		return nil
+	})
+	return rolledBack, err
+}
+
+// Status returns every embedded migration with whether and when it was applied.
+func (migrator *Migrator) Status(ctx context.Context) (statuses []Status, err error) {
+	err = migrator.withLock(ctx, func(conn *sql.Conn, appliedAt map[int]time.Time) error {
+		for _, migration := range migrator.migrations {
+			at, ok := appliedAt[migration.Version]
+			statuses = append(statuses, Status{Migration: migration, Applied: ok, AppliedAt: at})
+		}
+		return nil
+	})
+	return statuses, err
+}
+
+// withLock runs fn on a dedicated connection holding the advisory lock,
+// passing the versions already applied with their application time.
+func (migrator *Migrator) withLock(ctx context.Context, fn func(conn *sql.Conn, appliedAt map[int]time.Time) error) error {
+	conn, err := migrator.db.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	if _, err = conn.ExecContext(ctx, lockQuery, lockKey); err != nil {
+		return fmt.Errorf("acquire migration lock: %w", err)
+	}
+	defer func() {
+		if _, err := conn.ExecContext(context.Background(), unlockQuery, lockKey); err != nil {
+			migrator.log.Sugar().Errorf("Failed to release migration lock: %s", err)
+		}
+	}()
+
+	if _, err = conn.ExecContext(ctx, createVersionsTableQuery); err != nil {
+		return err
+	}
+
+	appliedAt, err := readApplied(ctx, conn)
+	if err != nil {
+		return err
+	}
+
+	return fn(conn, appliedAt)
+}
+
+func readApplied(ctx context.Context, conn *sql.Conn) (map[int]time.Time, error) {
+	rows, err := conn.QueryContext(ctx, readVersionsQuery)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	appliedAt := make(map[int]time.Time)
+	for rows.Next() {
+		var version int
+		var at time.Time
+		if err = rows.Scan(&version, &at); err != nil {
+			return nil, err
+		}
+		appliedAt[version] = at
+	}
+	return appliedAt, rows.Err()
+}
+
+// run executes the migration SQL and the version bookkeeping query in one transaction.
+func (migrator *Migrator) run(ctx context.Context, conn *sql.Conn, migrationSQL, versionQuery string, versionArgs ...any) error {
+	tx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err = tx.ExecContext(ctx, migrationSQL); err != nil {
+		return err
+	}
+	if _, err = tx.ExecContext(ctx, versionQuery, versionArgs...); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
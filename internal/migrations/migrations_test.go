@@ -0,0 +1,33 @@
+package migrations
+
+import (
+	"testing"
+	"testing/fstest"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoadEmbedded(t *testing.T) {
+	migrations, err := load(migrationFiles)
+	require.NoError(t, err)
+	require.NotEmpty(t, migrations)
+
+	for i, migration := range migrations {
+		assert.Equal(t, i+1, migration.Version, "migration versions must be consecutive")
+		assert.NotEmpty(t, migration.Up)
+		assert.NotEmpty(t, migration.Down)
+	}
+}
+
+func TestLoadRejectsIncompleteMigration(t *testing.T) {
+	_, err := load(fstest.MapFS{
+		"sql/0001_create.up.sql": {Data: []byte("CREATE TABLE t (id INTEGER);")},
+	})
+	assert.Error(t, err)
+
+	_, err = load(fstest.MapFS{
+		"sql/create.up.sql": {Data: []byte("CREATE TABLE t (id INTEGER);")},
+	})
+	assert.Error(t, err)
+}
@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS content.urls;
+DROP SCHEMA IF EXISTS content;
@@ -0,0 +1,7 @@
+CREATE SCHEMA IF NOT EXISTS content;
+CREATE TABLE IF NOT EXISTS content.urls (
+	originalURL TEXT,
+	shortURL TEXT,
+	userID INTEGER,
+	deletedFlag BOOLEAN);
+CREATE INDEX IF NOT EXISTS originalURL ON content.urls (originalURL);
@@ -0,0 +1 @@
+CREATE INDEX IF NOT EXISTS shortURL ON content.urls (shortURL);
@@ -0,0 +1 @@
+ALTER TABLE content.urls DROP COLUMN IF EXISTS expiresAt;
@@ -0,0 +1 @@
+ALTER TABLE content.urls ADD COLUMN IF NOT EXISTS expiresAt TIMESTAMPTZ;
@@ -0,0 +1,7 @@
+CREATE TABLE IF NOT EXISTS content.clicks (
+	shortURL TEXT,
+	clickedAt TIMESTAMPTZ,
+	referrer TEXT,
+	userAgent TEXT,
+	ipHash TEXT);
+CREATE INDEX IF NOT EXISTS clicksShortURL ON content.clicks (shortURL);
@@ -0,0 +1,5 @@
+DROP INDEX IF EXISTS content.uniqueOriginalURL;
+DROP INDEX IF EXISTS content.uniqueShortURL;
+ALTER TABLE content.urls DROP COLUMN IF EXISTS isAlias;
+CREATE INDEX IF NOT EXISTS originalURL ON content.urls (originalURL);
+CREATE INDEX IF NOT EXISTS shortURL ON content.urls (shortURL);
@@ -0,0 +1,11 @@
+-- Earlier versions could store the same short URL twice under concurrent requests, keep the oldest row.
+DELETE FROM content.urls duplicate USING content.urls kept
+	WHERE duplicate.shortURL = kept.shortURL AND duplicate.ctid > kept.ctid;
+ALTER TABLE content.urls ADD COLUMN IF NOT EXISTS isAlias BOOLEAN NOT NULL DEFAULT False;
+-- An original URL keeps one generated short URL, any other short URL for it is treated as an alias.
+UPDATE content.urls duplicate SET isAlias = True FROM content.urls kept
+	WHERE duplicate.originalURL = kept.originalURL AND duplicate.ctid > kept.ctid;
+DROP INDEX IF EXISTS content.shortURL;
+DROP INDEX IF EXISTS content.originalURL;
+CREATE UNIQUE INDEX IF NOT EXISTS uniqueShortURL ON content.urls (shortURL);
+CREATE UNIQUE INDEX IF NOT EXISTS uniqueOriginalURL ON content.urls (originalURL) WHERE NOT isAlias;
@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS content.urlsDeletedAt;
+ALTER TABLE content.urls DROP COLUMN IF EXISTS deletedAt;
@@ -0,0 +1,4 @@
+ALTER TABLE content.urls ADD COLUMN IF NOT EXISTS deletedAt TIMESTAMPTZ;
+-- The deletion time of rows deleted before this migration is unknown, their grace period starts now.
+UPDATE content.urls SET deletedAt = now() WHERE deletedFlag AND deletedAt IS NULL;
+CREATE INDEX IF NOT EXISTS urlsDeletedAt ON content.urls (deletedAt) WHERE deletedFlag;
@@ -0,0 +1,6 @@
+CREATE TABLE IF NOT EXISTS content.users (
+	userID INTEGER GENERATED BY DEFAULT AS IDENTITY PRIMARY KEY,
+	createdAt TIMESTAMPTZ NOT NULL DEFAULT now());
+-- Owners of existing short URLs keep their IDs, new IDs are issued after the largest one.
+INSERT INTO content.users (userID) SELECT DISTINCT userID FROM content.urls WHERE userID > 0 ON CONFLICT DO NOTHING;
+SELECT setval(pg_get_serial_sequence('content.users', 'userid'), COALESCE(max(userID), 0) + 1, false) FROM content.users;
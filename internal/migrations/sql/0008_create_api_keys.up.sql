@@ -0,0 +1,10 @@
+CREATE TABLE IF NOT EXISTS content.apiKeys (
+	id TEXT PRIMARY KEY,
+	userID INTEGER NOT NULL,
+	name TEXT NOT NULL,
+	scopes TEXT NOT NULL,
+	prefix TEXT NOT NULL,
+	keyHash TEXT NOT NULL UNIQUE,
+	createdAt TIMESTAMPTZ NOT NULL DEFAULT now(),
+	revokedAt TIMESTAMPTZ);
+CREATE INDEX IF NOT EXISTS apiKeysUserID ON content.apiKeys (userID);
@@ -1,14 +1,23 @@
 // Package models defines the data structures used for handling URL shortening requests and responses.
 package models
 
-// Request represents a structure for incoming requests containing the original URL to be shortened.
+import "time"
+
+// Request represents a structure for incoming requests containing the original URL to be shortened,
+// an optional vanity alias to use instead of a generated short URL and an optional expiration
+// given either as an absolute time or as a time-to-live in seconds.
 type Request struct {
-	OriginalURL string `json:"url"`
+	OriginalURL string     `json:"url"`
+	Alias       string     `json:"alias,omitempty"`
+	ExpiresAt   *time.Time `json:"expires_at,omitempty"`
+	TTLSeconds  int64      `json:"ttl_seconds,omitempty"`
 }
 
 // Response represents a structure for outgoing responses containing the shortened URL as a result.
+// Owner is set only when the requested alias is already taken and holds the ID of the user who owns it.
 type Response struct {
 	ShortenURL string `json:"result"`
+	Owner      int    `json:"owner,omitempty"`
 }
 
 // URLPair represents a structure for storing the association between a shortened URL and its corresponding original URL.
@@ -17,8 +26,135 @@ type URLPair struct {
 	OriginalURL string `json:"original_url"`
 }
 
-// URLsClientID represents a structure for storing multiple URLs associated with a specific client identified by a ClientID.
+// URLsClientID represents a structure for storing multiple URLs associated with a specific client identified by a ClientID
+// and the ID of the deletion job they belong to.
 type URLsClientID struct {
 	URLs     []string
 	ClientID int
+	JobID    string
+}
+
+// InternalStats represents a structure for the number of URLs and distinct users held by the service
+// and the number of deletion requests waiting to be processed.
+type InternalStats struct {
+	URLs        int `json:"urls"`
+	Users       int `json:"users"`
+	DeleteQueue int `json:"delete_queue"`
+}
+
+// ClickEvent represents a single redirect through a short URL.
+type ClickEvent struct {
+	ShortURL  string    `json:"short_url"`
+	ClickedAt time.Time `json:"clicked_at"`
+	Referrer  string    `json:"referrer"`
+	UserAgent string    `json:"user_agent"`
+	IPHash    string    `json:"ip_hash"`
+}
+
+// DailyClicks represents the number of clicks on a short URL during one day.
+type DailyClicks struct {
+	Date   string `json:"date"`
+	Clicks int    `json:"clicks"`
+}
+
+// ReferrerClicks represents the number of clicks on a short URL coming from one referrer.
+type ReferrerClicks struct {
+	Referrer string `json:"referrer"`
+	Clicks   int    `json:"clicks"`
+}
+
+// LinkStats represents aggregated click statistics for a short URL.
+type LinkStats struct {
+	ShortURL     string           `json:"short_url"`
+	Total        int              `json:"total"`
+	Daily        []DailyClicks    `json:"daily"`
+	TopReferrers []ReferrerClicks `json:"top_referrers"`
+}
+
+// BatchURL represents one original URL of a batch shortening request with the short URL proposed for it.
+// When OriginalURL is already shortened, the storage replaces ShortURL with the existing one and sets Conflict.
+type BatchURL struct {
+	CorrelationID string    `json:"correlation_id"`
+	OriginalURL   string    `json:"original_url"`
+	ShortURL      string    `json:"short_url"`
+	ExpiresAt     time.Time `json:"expires_at"`
+	Conflict      bool      `json:"conflict"`
+}
+
+// Deletion job statuses.
+const (
+	DeleteJobQueued  = "queued"
+	DeleteJobRunning = "running"
+	DeleteJobDone    = "done"
+	DeleteJobFailed  = "failed"
+)
+
+// Deletion results of a short URL.
+const (
+	DeletionDeleted        = "deleted"
+	DeletionAlreadyDeleted = "already_deleted"
+	DeletionNotOwned       = "not_owned"
+	DeletionNotFound       = "not_found"
+)
+
+// DeleteJob represents the state of a request to delete short URLs with the result for every processed short URL.
+type DeleteJob struct {
+	ID         string            `json:"id"`
+	UserID     int               `json:"-"`
+	Status     string            `json:"status"`
+	Results    map[string]string `json:"results"`
+	Error      string            `json:"error,omitempty"`
+	CreatedAt  time.Time         `json:"created_at"`
+	FinishedAt *time.Time        `json:"finished_at,omitempty"`
+}
+
+// Restoration results of a short URL.
+const (
+	RestorationRestored     = "restored"
+	RestorationNotDeleted   = "not_deleted"
+	RestorationGraceExpired = "grace_period_expired"
+	RestorationNotOwned     = DeletionNotOwned
+	RestorationNotFound     = DeletionNotFound
+)
+
+// RestoreResult represents the result of restoring deleted short URLs for every requested short URL.
+type RestoreResult struct {
+	Results map[string]string `json:"results"`
+}
+
+// API key scopes, each allowing a group of /api routes.
+const (
+	ScopeShorten = "shorten"
+	ScopeRead    = "read"
+	ScopeDelete  = "delete"
+)
+
+// APIKey represents a personal API key of a user. Only the hash of the key is stored,
+// the key itself is shown once when it is created.
+type APIKey struct {
+	ID        string     `json:"id"`
+	UserID    int        `json:"-"`
+	Name      string     `json:"name"`
+	Scopes    []string   `json:"scopes"`
+	Prefix    string     `json:"prefix"`
+	Hash      string     `json:"-"`
+	CreatedAt time.Time  `json:"created_at"`
+	RevokedAt *time.Time `json:"revoked_at,omitempty"`
+}
+
+// APIKeyRequest represents a request to create an API key with the given scopes.
+type APIKeyRequest struct {
+	Name   string   `json:"name"`
+	Scopes []string `json:"scopes"`
 }
+
+// CreatedAPIKey represents a newly created API key together with the key itself.
+type CreatedAPIKey struct {
+	APIKey
+	Key string `json:"key"`
+}
+
+// APIKeys represents the list of API keys of a user.
+//
+//easyjson:json
+type APIKeys []APIKey
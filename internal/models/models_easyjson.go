@@ -7,6 +7,7 @@ import (
 	easyjson "github.com/mailru/easyjson"
 	jlexer "github.com/mailru/easyjson/jlexer"
 	jwriter "github.com/mailru/easyjson/jwriter"
+	time "time"
 )
 
 // suppress unused package warning
@@ -17,7 +18,7 @@ var (
 	_ easyjson.Marshaler
 )
 
-func easyjsonD2b7633eDecodeGithubComDariSorokinaGoFirstSprintGitInternalModels(in *jlexer.Lexer, out *URLsClientID) {
+func easyjsonD2b7633eDecodeGithubComDariSorokinaGoFirstSprintInternalModels(in *jlexer.Lexer, out *URLsClientID) {
 	isTopLevel := in.IsStart()
 	if in.IsNull() {
 		if isTopLevel {
@@ -61,6 +62,8 @@ func easyjsonD2b7633eDecodeGithubComDariSorokinaGoFirstSprintGitInternalModels(i
 			}
 		case "ClientID":
 			out.ClientID = int(in.Int())
+		case "JobID":
+			out.JobID = string(in.String())
 		default:
 			in.SkipRecursive()
 		}
@@ -71,7 +74,7 @@ func easyjsonD2b7633eDecodeGithubComDariSorokinaGoFirstSprintGitInternalModels(i
 		in.Consumed()
 	}
 }
-func easyjsonD2b7633eEncodeGithubComDariSorokinaGoFirstSprintGitInternalModels(out *jwriter.Writer, in URLsClientID) {
+func easyjsonD2b7633eEncodeGithubComDariSorokinaGoFirstSprintInternalModels(out *jwriter.Writer, in URLsClientID) {
 	out.RawByte('{')
 	first := true
 	_ = first
@@ -96,33 +99,38 @@ func easyjsonD2b7633eEncodeGithubComDariSorokinaGoFirstSprintGitInternalModels(o
 		out.RawString(prefix)
 		out.Int(int(in.ClientID))
 	}
+	{
+		const prefix string = ",\"JobID\":"
+		out.RawString(prefix)
+		out.String(string(in.JobID))
+	}
 	out.RawByte('}')
 }
 
 // MarshalJSON supports json.Marshaler interface
 func (v URLsClientID) MarshalJSON() ([]byte, error) {
 	w := jwriter.Writer{}
-	easyjsonD2b7633eEncodeGithubComDariSorokinaGoFirstSprintGitInternalModels(&w, v)
+	easyjsonD2b7633eEncodeGithubComDariSorokinaGoFirstSprintInternalModels(&w, v)
 	return w.Buffer.BuildBytes(), w.Error
 }
 
 // MarshalEasyJSON supports easyjson.Marshaler interface
 func (v URLsClientID) MarshalEasyJSON(w *jwriter.Writer) {
-	easyjsonD2b7633eEncodeGithubComDariSorokinaGoFirstSprintGitInternalModels(w, v)
+	easyjsonD2b7633eEncodeGithubComDariSorokinaGoFirstSprintInternalModels(w, v)
 }
 
 // UnmarshalJSON supports json.Unmarshaler interface
 func (v *URLsClientID) UnmarshalJSON(data []byte) error {
 	r := jlexer.Lexer{Data: data}
-	easyjsonD2b7633eDecodeGithubComDariSorokinaGoFirstSprintGitInternalModels(&r, v)
+	easyjsonD2b7633eDecodeGithubComDariSorokinaGoFirstSprintInternalModels(&r, v)
 	return r.Error()
 }
 
 // UnmarshalEasyJSON supports easyjson.Unmarshaler interface
 func (v *URLsClientID) UnmarshalEasyJSON(l *jlexer.Lexer) {
-	easyjsonD2b7633eDecodeGithubComDariSorokinaGoFirstSprintGitInternalModels(l, v)
+	easyjsonD2b7633eDecodeGithubComDariSorokinaGoFirstSprintInternalModels(l, v)
 }
-func easyjsonD2b7633eDecodeGithubComDariSorokinaGoFirstSprintGitInternalModels1(in *jlexer.Lexer, out *URLPair) {
+func easyjsonD2b7633eDecodeGithubComDariSorokinaGoFirstSprintInternalModels1(in *jlexer.Lexer, out *URLPair) {
 	isTopLevel := in.IsStart()
 	if in.IsNull() {
 		if isTopLevel {
@@ -155,7 +163,7 @@ func easyjsonD2b7633eDecodeGithubComDariSorokinaGoFirstSprintGitInternalModels1(
 		in.Consumed()
 	}
 }
-func easyjsonD2b7633eEncodeGithubComDariSorokinaGoFirstSprintGitInternalModels1(out *jwriter.Writer, in URLPair) {
+func easyjsonD2b7633eEncodeGithubComDariSorokinaGoFirstSprintInternalModels1(out *jwriter.Writer, in URLPair) {
 	out.RawByte('{')
 	first := true
 	_ = first
@@ -175,27 +183,123 @@ func easyjsonD2b7633eEncodeGithubComDariSorokinaGoFirstSprintGitInternalModels1(
 // MarshalJSON supports json.Marshaler interface
 func (v URLPair) MarshalJSON() ([]byte, error) {
 	w := jwriter.Writer{}
-	easyjsonD2b7633eEncodeGithubComDariSorokinaGoFirstSprintGitInternalModels1(&w, v)
+	easyjsonD2b7633eEncodeGithubComDariSorokinaGoFirstSprintInternalModels1(&w, v)
 	return w.Buffer.BuildBytes(), w.Error
 }
 
 // MarshalEasyJSON supports easyjson.Marshaler interface
 func (v URLPair) MarshalEasyJSON(w *jwriter.Writer) {
-	easyjsonD2b7633eEncodeGithubComDariSorokinaGoFirstSprintGitInternalModels1(w, v)
+	easyjsonD2b7633eEncodeGithubComDariSorokinaGoFirstSprintInternalModels1(w, v)
 }
 
 // UnmarshalJSON supports json.Unmarshaler interface
 func (v *URLPair) UnmarshalJSON(data []byte) error {
 	r := jlexer.Lexer{Data: data}
-	easyjsonD2b7633eDecodeGithubComDariSorokinaGoFirstSprintGitInternalModels1(&r, v)
+	easyjsonD2b7633eDecodeGithubComDariSorokinaGoFirstSprintInternalModels1(&r, v)
 	return r.Error()
 }
 
 // UnmarshalEasyJSON supports easyjson.Unmarshaler interface
 func (v *URLPair) UnmarshalEasyJSON(l *jlexer.Lexer) {
-	easyjsonD2b7633eDecodeGithubComDariSorokinaGoFirstSprintGitInternalModels1(l, v)
+	easyjsonD2b7633eDecodeGithubComDariSorokinaGoFirstSprintInternalModels1(l, v)
+}
+func easyjsonD2b7633eDecodeGithubComDariSorokinaGoFirstSprintInternalModels2(in *jlexer.Lexer, out *RestoreResult) {
+	isTopLevel := in.IsStart()
+	if in.IsNull() {
+		if isTopLevel {
+			in.Consumed()
+		}
+		in.Skip()
+		return
+	}
+	in.Delim('{')
+	for !in.IsDelim('}') {
+		key := in.UnsafeFieldName(false)
+		in.WantColon()
+		if in.IsNull() {
+			in.Skip()
+			in.WantComma()
+			continue
+		}
+		switch key {
+		case "results":
+			if in.IsNull() {
+				in.Skip()
+			} else {
+				in.Delim('{')
+				out.Results = make(map[string]string)
+				for !in.IsDelim('}') {
+					key := string(in.String())
+					in.WantColon()
+					var v4 string
+					v4 = string(in.String())
+					(out.Results)[key] = v4
+					in.WantComma()
+				}
+				in.Delim('}')
+			}
+		default:
+			in.SkipRecursive()
+		}
+		in.WantComma()
+	}
+	in.Delim('}')
+	if isTopLevel {
+		in.Consumed()
+	}
+}
+func easyjsonD2b7633eEncodeGithubComDariSorokinaGoFirstSprintInternalModels2(out *jwriter.Writer, in RestoreResult) {
+	out.RawByte('{')
+	first := true
+	_ = first
+	{
+		const prefix string = ",\"results\":"
+		out.RawString(prefix[1:])
+		if in.Results == nil && (out.Flags&jwriter.NilMapAsEmpty) == 0 {
+			out.RawString(`null`)
+		} else {
+			out.RawByte('{')
+			v5First := true
+			for v5Name, v5Value := range in.Results {
+				if v5First {
+					v5First = false
+				} else {
+					out.RawByte(',')
+				}
+				out.String(string(v5Name))
+				out.RawByte(':')
+				out.String(string(v5Value))
+			}
+			out.RawByte('}')
+		}
+	}
+	out.RawByte('}')
+}
+
+// MarshalJSON supports json.Marshaler interface
+func (v RestoreResult) MarshalJSON() ([]byte, error) {
+	w := jwriter.Writer{}
+	easyjsonD2b7633eEncodeGithubComDariSorokinaGoFirstSprintInternalModels2(&w, v)
+	return w.Buffer.BuildBytes(), w.Error
+}
+
+// MarshalEasyJSON supports easyjson.Marshaler interface
+func (v RestoreResult) MarshalEasyJSON(w *jwriter.Writer) {
+	easyjsonD2b7633eEncodeGithubComDariSorokinaGoFirstSprintInternalModels2(w, v)
+}
+
+// UnmarshalJSON supports json.Unmarshaler interface
+func (v *RestoreResult) UnmarshalJSON(data []byte) error {
+	r := jlexer.Lexer{Data: data}
+	easyjsonD2b7633eDecodeGithubComDariSorokinaGoFirstSprintInternalModels2(&r, v)
+	return r.Error()
 }
-func easyjsonD2b7633eDecodeGithubComDariSorokinaGoFirstSprintGitInternalModels2(in *jlexer.Lexer, out *Response) {
+
+// UnmarshalEasyJSON supports easyjson.Unmarshaler interface
+func (v *RestoreResult) UnmarshalEasyJSON(l *jlexer.Lexer) {
+	easyjsonD2b7633eDecodeGithubComDariSorokinaGoFirstSprintInternalModels2(l, v)
+}
+func easyjsonD2b7633eDecodeGithubComDariSorokinaGoFirstSprintInternalModels3(in *jlexer.Lexer, out *Response) {
 	isTopLevel := in.IsStart()
 	if in.IsNull() {
 		if isTopLevel {
@@ -216,6 +320,8 @@ func easyjsonD2b7633eDecodeGithubComDariSorokinaGoFirstSprintGitInternalModels2(
 		switch key {
 		case "result":
 			out.ShortenURL = string(in.String())
+		case "owner":
+			out.Owner = int(in.Int())
 		default:
 			in.SkipRecursive()
 		}
@@ -226,7 +332,7 @@ func easyjsonD2b7633eDecodeGithubComDariSorokinaGoFirstSprintGitInternalModels2(
 		in.Consumed()
 	}
 }
-func easyjsonD2b7633eEncodeGithubComDariSorokinaGoFirstSprintGitInternalModels2(out *jwriter.Writer, in Response) {
+func easyjsonD2b7633eEncodeGithubComDariSorokinaGoFirstSprintInternalModels3(out *jwriter.Writer, in Response) {
 	out.RawByte('{')
 	first := true
 	_ = first
@@ -235,33 +341,38 @@ func easyjsonD2b7633eEncodeGithubComDariSorokinaGoFirstSprintGitInternalModels2(
 		out.RawString(prefix[1:])
 		out.String(string(in.ShortenURL))
 	}
+	if in.Owner != 0 {
+		const prefix string = ",\"owner\":"
+		out.RawString(prefix)
+		out.Int(int(in.Owner))
+	}
 	out.RawByte('}')
 }
 
 // MarshalJSON supports json.Marshaler interface
 func (v Response) MarshalJSON() ([]byte, error) {
 	w := jwriter.Writer{}
-	easyjsonD2b7633eEncodeGithubComDariSorokinaGoFirstSprintGitInternalModels2(&w, v)
+	easyjsonD2b7633eEncodeGithubComDariSorokinaGoFirstSprintInternalModels3(&w, v)
 	return w.Buffer.BuildBytes(), w.Error
 }
 
 // MarshalEasyJSON supports easyjson.Marshaler interface
 func (v Response) MarshalEasyJSON(w *jwriter.Writer) {
-	easyjsonD2b7633eEncodeGithubComDariSorokinaGoFirstSprintGitInternalModels2(w, v)
+	easyjsonD2b7633eEncodeGithubComDariSorokinaGoFirstSprintInternalModels3(w, v)
 }
 
 // UnmarshalJSON supports json.Unmarshaler interface
 func (v *Response) UnmarshalJSON(data []byte) error {
 	r := jlexer.Lexer{Data: data}
-	easyjsonD2b7633eDecodeGithubComDariSorokinaGoFirstSprintGitInternalModels2(&r, v)
+	easyjsonD2b7633eDecodeGithubComDariSorokinaGoFirstSprintInternalModels3(&r, v)
 	return r.Error()
 }
 
 // UnmarshalEasyJSON supports easyjson.Unmarshaler interface
 func (v *Response) UnmarshalEasyJSON(l *jlexer.Lexer) {
-	easyjsonD2b7633eDecodeGithubComDariSorokinaGoFirstSprintGitInternalModels2(l, v)
+	easyjsonD2b7633eDecodeGithubComDariSorokinaGoFirstSprintInternalModels3(l, v)
 }
-func easyjsonD2b7633eDecodeGithubComDariSorokinaGoFirstSprintGitInternalModels3(in *jlexer.Lexer, out *Request) {
+func easyjsonD2b7633eDecodeGithubComDariSorokinaGoFirstSprintInternalModels4(in *jlexer.Lexer, out *Request) {
 	isTopLevel := in.IsStart()
 	if in.IsNull() {
 		if isTopLevel {
@@ -282,6 +393,22 @@ func easyjsonD2b7633eDecodeGithubComDariSorokinaGoFirstSprintGitInternalModels3(
 		switch key {
 		case "url":
 			out.OriginalURL = string(in.String())
+		case "alias":
+			out.Alias = string(in.String())
+		case "expires_at":
+			if in.IsNull() {
+				in.Skip()
+				out.ExpiresAt = nil
+			} else {
+				if out.ExpiresAt == nil {
+					out.ExpiresAt = new(time.Time)
+				}
+				if data := in.Raw(); in.Ok() {
+					in.AddError((*out.ExpiresAt).UnmarshalJSON(data))
+				}
+			}
+		case "ttl_seconds":
+			out.TTLSeconds = int64(in.Int64())
 		default:
 			in.SkipRecursive()
 		}
@@ -292,7 +419,7 @@ func easyjsonD2b7633eDecodeGithubComDariSorokinaGoFirstSprintGitInternalModels3(
 		in.Consumed()
 	}
 }
-func easyjsonD2b7633eEncodeGithubComDariSorokinaGoFirstSprintGitInternalModels3(out *jwriter.Writer, in Request) {
+func easyjsonD2b7633eEncodeGithubComDariSorokinaGoFirstSprintInternalModels4(out *jwriter.Writer, in Request) {
 	out.RawByte('{')
 	first := true
 	_ = first
@@ -301,29 +428,1224 @@ func easyjsonD2b7633eEncodeGithubComDariSorokinaGoFirstSprintGitInternalModels3(
 		out.RawString(prefix[1:])
 		out.String(string(in.OriginalURL))
 	}
+	if in.Alias != "" {
+		const prefix string = ",\"alias\":"
+		out.RawString(prefix)
+		out.String(string(in.Alias))
+	}
+	if in.ExpiresAt != nil {
+		const prefix string = ",\"expires_at\":"
+		out.RawString(prefix)
+		out.Raw((*in.ExpiresAt).MarshalJSON())
+	}
+	if in.TTLSeconds != 0 {
+		const prefix string = ",\"ttl_seconds\":"
+		out.RawString(prefix)
+		out.Int64(int64(in.TTLSeconds))
+	}
 	out.RawByte('}')
 }
 
 // MarshalJSON supports json.Marshaler interface
 func (v Request) MarshalJSON() ([]byte, error) {
 	w := jwriter.Writer{}
-	easyjsonD2b7633eEncodeGithubComDariSorokinaGoFirstSprintGitInternalModels3(&w, v)
+	easyjsonD2b7633eEncodeGithubComDariSorokinaGoFirstSprintInternalModels4(&w, v)
 	return w.Buffer.BuildBytes(), w.Error
 }
 
 // MarshalEasyJSON supports easyjson.Marshaler interface
 func (v Request) MarshalEasyJSON(w *jwriter.Writer) {
-	easyjsonD2b7633eEncodeGithubComDariSorokinaGoFirstSprintGitInternalModels3(w, v)
+	easyjsonD2b7633eEncodeGithubComDariSorokinaGoFirstSprintInternalModels4(w, v)
 }
 
 // UnmarshalJSON supports json.Unmarshaler interface
 func (v *Request) UnmarshalJSON(data []byte) error {
 	r := jlexer.Lexer{Data: data}
-	easyjsonD2b7633eDecodeGithubComDariSorokinaGoFirstSprintGitInternalModels3(&r, v)
+	easyjsonD2b7633eDecodeGithubComDariSorokinaGoFirstSprintInternalModels4(&r, v)
 	return r.Error()
 }
 
 // UnmarshalEasyJSON supports easyjson.Unmarshaler interface
 func (v *Request) UnmarshalEasyJSON(l *jlexer.Lexer) {
-	easyjsonD2b7633eDecodeGithubComDariSorokinaGoFirstSprintGitInternalModels3(l, v)
+	easyjsonD2b7633eDecodeGithubComDariSorokinaGoFirstSprintInternalModels4(l, v)
+}
+func easyjsonD2b7633eDecodeGithubComDariSorokinaGoFirstSprintInternalModels5(in *jlexer.Lexer, out *ReferrerClicks) {
+	isTopLevel := in.IsStart()
+	if in.IsNull() {
+		if isTopLevel {
+			in.Consumed()
+		}
+		in.Skip()
+		return
+	}
+	in.Delim('{')
+	for !in.IsDelim('}') {
+		key := in.UnsafeFieldName(false)
+		in.WantColon()
+		if in.IsNull() {
+			in.Skip()
+			in.WantComma()
+			continue
+		}
+		switch key {
+		case "referrer":
+			out.Referrer = string(in.String())
+		case "clicks":
+			out.Clicks = int(in.Int())
+		default:
+			in.SkipRecursive()
+		}
+		in.WantComma()
+	}
+	in.Delim('}')
+	if isTopLevel {
+		in.Consumed()
+	}
+}
+func easyjsonD2b7633eEncodeGithubComDariSorokinaGoFirstSprintInternalModels5(out *jwriter.Writer, in ReferrerClicks) {
+	out.RawByte('{')
+	first := true
+	_ = first
+	{
+		const prefix string = ",\"referrer\":"
+		out.RawString(prefix[1:])
+		out.String(string(in.Referrer))
+	}
+	{
+		const prefix string = ",\"clicks\":"
+		out.RawString(prefix)
+		out.Int(int(in.Clicks))
+	}
+	out.RawByte('}')
+}
+
+// MarshalJSON supports json.Marshaler interface
+func (v ReferrerClicks) MarshalJSON() ([]byte, error) {
+	w := jwriter.Writer{}
+	easyjsonD2b7633eEncodeGithubComDariSorokinaGoFirstSprintInternalModels5(&w, v)
+	return w.Buffer.BuildBytes(), w.Error
+}
+
+// MarshalEasyJSON supports easyjson.Marshaler interface
+func (v ReferrerClicks) MarshalEasyJSON(w *jwriter.Writer) {
+	easyjsonD2b7633eEncodeGithubComDariSorokinaGoFirstSprintInternalModels5(w, v)
+}
+
+// UnmarshalJSON supports json.Unmarshaler interface
+func (v *ReferrerClicks) UnmarshalJSON(data []byte) error {
+	r := jlexer.Lexer{Data: data}
+	easyjsonD2b7633eDecodeGithubComDariSorokinaGoFirstSprintInternalModels5(&r, v)
+	return r.Error()
+}
+
+// UnmarshalEasyJSON supports easyjson.Unmarshaler interface
+func (v *ReferrerClicks) UnmarshalEasyJSON(l *jlexer.Lexer) {
+	easyjsonD2b7633eDecodeGithubComDariSorokinaGoFirstSprintInternalModels5(l, v)
+}
+func easyjsonD2b7633eDecodeGithubComDariSorokinaGoFirstSprintInternalModels6(in *jlexer.Lexer, out *LinkStats) {
+	isTopLevel := in.IsStart()
+	if in.IsNull() {
+		if isTopLevel {
+			in.Consumed()
+		}
+		in.Skip()
+		return
+	}
+	in.Delim('{')
+	for !in.IsDelim('}') {
+		key := in.UnsafeFieldName(false)
+		in.WantColon()
+		if in.IsNull() {
+			in.Skip()
+			in.WantComma()
+			continue
+		}
+		switch key {
+		case "short_url":
+			out.ShortURL = string(in.String())
+		case "total":
+			out.Total = int(in.Int())
+		case "daily":
+			if in.IsNull() {
+				in.Skip()
+				out.Daily = nil
+			} else {
+				in.Delim('[')
+				if out.Daily == nil {
+					if !in.IsDelim(']') {
+						out.Daily = make([]DailyClicks, 0, 2)
+					} else {
+						out.Daily = []DailyClicks{}
+					}
+				} else {
+					out.Daily = (out.Daily)[:0]
+				}
+				for !in.IsDelim(']') {
+					var v6 DailyClicks
+					(v6).UnmarshalEasyJSON(in)
+					out.Daily = append(out.Daily, v6)
+					in.WantComma()
+				}
+				in.Delim(']')
+			}
+		case "top_referrers":
+			if in.IsNull() {
+				in.Skip()
+				out.TopReferrers = nil
+			} else {
+				in.Delim('[')
+				if out.TopReferrers == nil {
+					if !in.IsDelim(']') {
+						out.TopReferrers = make([]ReferrerClicks, 0, 2)
+					} else {
+						out.TopReferrers = []ReferrerClicks{}
+					}
+				} else {
+					out.TopReferrers = (out.TopReferrers)[:0]
+				}
+				for !in.IsDelim(']') {
+					var v7 ReferrerClicks
+					(v7).UnmarshalEasyJSON(in)
+					out.TopReferrers = append(out.TopReferrers, v7)
+					in.WantComma()
+				}
+				in.Delim(']')
+			}
+		default:
+			in.SkipRecursive()
+		}
+		in.WantComma()
+	}
+	in.Delim('}')
+	if isTopLevel {
+		in.Consumed()
+	}
+}
+func easyjsonD2b7633eEncodeGithubComDariSorokinaGoFirstSprintInternalModels6(out *jwriter.Writer, in LinkStats) {
+	out.RawByte('{')
+	first := true
+	_ = first
+	{
+		const prefix string = ",\"short_url\":"
+		out.RawString(prefix[1:])
+		out.String(string(in.ShortURL))
+	}
+	{
+		const prefix string = ",\"total\":"
+		out.RawString(prefix)
+		out.Int(int(in.Total))
+	}
+	{
+		const prefix string = ",\"daily\":"
+		out.RawString(prefix)
+		if in.Daily == nil && (out.Flags&jwriter.NilSliceAsEmpty) == 0 {
+			out.RawString("null")
+		} else {
+			out.RawByte('[')
+			for v8, v9 := range in.Daily {
+				if v8 > 0 {
+					out.RawByte(',')
+				}
+				(v9).MarshalEasyJSON(out)
+			}
+			out.RawByte(']')
+		}
+	}
+	{
+		const prefix string = ",\"top_referrers\":"
+		out.RawString(prefix)
+		if in.TopReferrers == nil && (out.Flags&jwriter.NilSliceAsEmpty) == 0 {
+			out.RawString("null")
+		} else {
+			out.RawByte('[')
+			for v10, v11 := range in.TopReferrers {
+				if v10 > 0 {
+					out.RawByte(',')
+				}
+				(v11).MarshalEasyJSON(out)
+			}
+			out.RawByte(']')
+		}
+	}
+	out.RawByte('}')
+}
+
+// MarshalJSON supports json.Marshaler interface
+func (v LinkStats) MarshalJSON() ([]byte, error) {
+	w := jwriter.Writer{}
+	easyjsonD2b7633eEncodeGithubComDariSorokinaGoFirstSprintInternalModels6(&w, v)
+	return w.Buffer.BuildBytes(), w.Error
+}
+
+// MarshalEasyJSON supports easyjson.Marshaler interface
+func (v LinkStats) MarshalEasyJSON(w *jwriter.Writer) {
+	easyjsonD2b7633eEncodeGithubComDariSorokinaGoFirstSprintInternalModels6(w, v)
+}
+
+// UnmarshalJSON supports json.Unmarshaler interface
+func (v *LinkStats) UnmarshalJSON(data []byte) error {
+	r := jlexer.Lexer{Data: data}
+	easyjsonD2b7633eDecodeGithubComDariSorokinaGoFirstSprintInternalModels6(&r, v)
+	return r.Error()
+}
+
+// UnmarshalEasyJSON supports easyjson.Unmarshaler interface
+func (v *LinkStats) UnmarshalEasyJSON(l *jlexer.Lexer) {
+	easyjsonD2b7633eDecodeGithubComDariSorokinaGoFirstSprintInternalModels6(l, v)
+}
+func easyjsonD2b7633eDecodeGithubComDariSorokinaGoFirstSprintInternalModels7(in *jlexer.Lexer, out *InternalStats) {
+	isTopLevel := in.IsStart()
+	if in.IsNull() {
+		if isTopLevel {
+			in.Consumed()
+		}
+		in.Skip()
+		return
+	}
+	in.Delim('{')
+	for !in.IsDelim('}') {
+		key := in.UnsafeFieldName(false)
+		in.WantColon()
+		if in.IsNull() {
+			in.Skip()
+			in.WantComma()
+			continue
+		}
+		switch key {
+		case "urls":
+			out.URLs = int(in.Int())
+		case "users":
+			out.Users = int(in.Int())
+		case "delete_queue":
+			out.DeleteQueue = int(in.Int())
+		default:
+			in.SkipRecursive()
+		}
+		in.WantComma()
+	}
+	in.Delim('}')
+	if isTopLevel {
+		in.Consumed()
+	}
+}
+func easyjsonD2b7633eEncodeGithubComDariSorokinaGoFirstSprintInternalModels7(out *jwriter.Writer, in InternalStats) {
+	out.RawByte('{')
+	first := true
+	_ = first
+	{
+		const prefix string = ",\"urls\":"
+		out.RawString(prefix[1:])
+		out.Int(int(in.URLs))
+	}
+	{
+		const prefix string = ",\"users\":"
+		out.RawString(prefix)
+		out.Int(int(in.Users))
+	}
+	{
+		const prefix string = ",\"delete_queue\":"
+		out.RawString(prefix)
+		out.Int(int(in.DeleteQueue))
+	}
+	out.RawByte('}')
+}
+
+// MarshalJSON supports json.Marshaler interface
+func (v InternalStats) MarshalJSON() ([]byte, error) {
+	w := jwriter.Writer{}
+	easyjsonD2b7633eEncodeGithubComDariSorokinaGoFirstSprintInternalModels7(&w, v)
+	return w.Buffer.BuildBytes(), w.Error
+}
+
+// MarshalEasyJSON supports easyjson.Marshaler interface
+func (v InternalStats) MarshalEasyJSON(w *jwriter.Writer) {
+	easyjsonD2b7633eEncodeGithubComDariSorokinaGoFirstSprintInternalModels7(w, v)
+}
+
+// UnmarshalJSON supports json.Unmarshaler interface
+func (v *InternalStats) UnmarshalJSON(data []byte) error {
+	r := jlexer.Lexer{Data: data}
+	easyjsonD2b7633eDecodeGithubComDariSorokinaGoFirstSprintInternalModels7(&r, v)
+	return r.Error()
+}
+
+// UnmarshalEasyJSON supports easyjson.Unmarshaler interface
+func (v *InternalStats) UnmarshalEasyJSON(l *jlexer.Lexer) {
+	easyjsonD2b7633eDecodeGithubComDariSorokinaGoFirstSprintInternalModels7(l, v)
+}
+func easyjsonD2b7633eDecodeGithubComDariSorokinaGoFirstSprintInternalModels8(in *jlexer.Lexer, out *DeleteJob) {
+	isTopLevel := in.IsStart()
+	if in.IsNull() {
+		if isTopLevel {
+			in.Consumed()
+		}
+		in.Skip()
+		return
+	}
+	in.Delim('{')
+	for !in.IsDelim('}') {
+		key := in.UnsafeFieldName(false)
+		in.WantColon()
+		if in.IsNull() {
+			in.Skip()
+			in.WantComma()
+			continue
+		}
+		switch key {
+		case "id":
+			out.ID = string(in.String())
+		case "status":
+			out.Status = string(in.String())
+		case "results":
+			if in.IsNull() {
+				in.Skip()
+			} else {
+				in.Delim('{')
+				out.Results = make(map[string]string)
+				for !in.IsDelim('}') {
+					key := string(in.String())
+					in.WantColon()
+					var v12 string
+					v12 = string(in.String())
+					(out.Results)[key] = v12
+					in.WantComma()
+				}
+				in.Delim('}')
+			}
+		case "error":
+			out.Error = string(in.String())
+		case "created_at":
+			if data := in.Raw(); in.Ok() {
+				in.AddError((out.CreatedAt).UnmarshalJSON(data))
+			}
+		case "finished_at":
+			if in.IsNull() {
+				in.Skip()
+				out.FinishedAt = nil
+			} else {
+				if out.FinishedAt == nil {
+					out.FinishedAt = new(time.Time)
+				}
+				if data := in.Raw(); in.Ok() {
+					in.AddError((*out.FinishedAt).UnmarshalJSON(data))
+				}
+			}
+		default:
+			in.SkipRecursive()
+		}
+		in.WantComma()
+	}
+	in.Delim('}')
+	if isTopLevel {
+		in.Consumed()
+	}
+}
+func easyjsonD2b7633eEncodeGithubComDariSorokinaGoFirstSprintInternalModels8(out *jwriter.Writer, in DeleteJob) {
+	out.RawByte('{')
+	first := true
+	_ = first
+	{
+		const prefix string = ",\"id\":"
+		out.RawString(prefix[1:])
+		out.String(string(in.ID))
+	}
+	{
+		const prefix string = ",\"status\":"
+		out.RawString(prefix)
+		out.String(string(in.Status))
+	}
+	{
+		const prefix string = ",\"results\":"
+		out.RawString(prefix)
+		if in.Results == nil && (out.Flags&jwriter.NilMapAsEmpty) == 0 {
+			out.RawString(`null`)
+		} else {
+			out.RawByte('{')
+			v13First := true
+			for v13Name, v13Value := range in.Results {
+				if v13First {
+					v13First = false
+				} else {
+					out.RawByte(',')
+				}
+				out.String(string(v13Name))
+				out.RawByte(':')
+				out.String(string(v13Value))
+			}
+			out.RawByte('}')
+		}
+	}
+	if in.Error != "" {
+		const prefix string = ",\"error\":"
+		out.RawString(prefix)
+		out.String(string(in.Error))
+	}
+	{
+		const prefix string = ",\"created_at\":"
+		out.RawString(prefix)
+		out.Raw((in.CreatedAt).MarshalJSON())
+	}
+	if in.FinishedAt != nil {
+		const prefix string = ",\"finished_at\":"
+		out.RawString(prefix)
+		out.Raw((*in.FinishedAt).MarshalJSON())
+	}
+	out.RawByte('}')
+}
+
+// MarshalJSON supports json.Marshaler interface
+func (v DeleteJob) MarshalJSON() ([]byte, error) {
+	w := jwriter.Writer{}
+	easyjsonD2b7633eEncodeGithubComDariSorokinaGoFirstSprintInternalModels8(&w, v)
+	return w.Buffer.BuildBytes(), w.Error
+}
+
+// MarshalEasyJSON supports easyjson.Marshaler interface
+func (v DeleteJob) MarshalEasyJSON(w *jwriter.Writer) {
+	easyjsonD2b7633eEncodeGithubComDariSorokinaGoFirstSprintInternalModels8(w, v)
+}
+
+// UnmarshalJSON supports json.Unmarshaler interface
+func (v *DeleteJob) UnmarshalJSON(data []byte) error {
+	r := jlexer.Lexer{Data: data}
+	easyjsonD2b7633eDecodeGithubComDariSorokinaGoFirstSprintInternalModels8(&r, v)
+	return r.Error()
+}
+
+// UnmarshalEasyJSON supports easyjson.Unmarshaler interface
+func (v *DeleteJob) UnmarshalEasyJSON(l *jlexer.Lexer) {
+	easyjsonD2b7633eDecodeGithubComDariSorokinaGoFirstSprintInternalModels8(l, v)
+}
+func easyjsonD2b7633eDecodeGithubComDariSorokinaGoFirstSprintInternalModels9(in *jlexer.Lexer, out *DailyClicks) {
+	isTopLevel := in.IsStart()
+	if in.IsNull() {
+		if isTopLevel {
+			in.Consumed()
+		}
+		in.Skip()
+		return
+	}
+	in.Delim('{')
+	for !in.IsDelim('}') {
+		key := in.UnsafeFieldName(false)
+		in.WantColon()
+		if in.IsNull() {
+			in.Skip()
+			in.WantComma()
+			continue
+		}
+		switch key {
+		case "date":
+			out.Date = string(in.String())
+		case "clicks":
+			out.Clicks = int(in.Int())
+		default:
+			in.SkipRecursive()
+		}
+		in.WantComma()
+	}
+	in.Delim('}')
+	if isTopLevel {
+		in.Consumed()
+	}
+}
+func easyjsonD2b7633eEncodeGithubComDariSorokinaGoFirstSprintInternalModels9(out *jwriter.Writer, in DailyClicks) {
+	out.RawByte('{')
+	first := true
+	_ = first
+	{
+		const prefix string = ",\"date\":"
+		out.RawString(prefix[1:])
+		out.String(string(in.Date))
+	}
+	{
+		const prefix string = ",\"clicks\":"
+		out.RawString(prefix)
+		out.Int(int(in.Clicks))
+	}
+	out.RawByte('}')
+}
+
+// MarshalJSON supports json.Marshaler interface
+func (v DailyClicks) MarshalJSON() ([]byte, error) {
+	w := jwriter.Writer{}
+	easyjsonD2b7633eEncodeGithubComDariSorokinaGoFirstSprintInternalModels9(&w, v)
+	return w.Buffer.BuildBytes(), w.Error
+}
+
+// MarshalEasyJSON supports easyjson.Marshaler interface
+func (v DailyClicks) MarshalEasyJSON(w *jwriter.Writer) {
+	easyjsonD2b7633eEncodeGithubComDariSorokinaGoFirstSprintInternalModels9(w, v)
+}
+
+// UnmarshalJSON supports json.Unmarshaler interface
+func (v *DailyClicks) UnmarshalJSON(data []byte) error {
+	r := jlexer.Lexer{Data: data}
+	easyjsonD2b7633eDecodeGithubComDariSorokinaGoFirstSprintInternalModels9(&r, v)
+	return r.Error()
+}
+
+// UnmarshalEasyJSON supports easyjson.Unmarshaler interface
+func (v *DailyClicks) UnmarshalEasyJSON(l *jlexer.Lexer) {
+	easyjsonD2b7633eDecodeGithubComDariSorokinaGoFirstSprintInternalModels9(l, v)
+}
+func easyjsonD2b7633eDecodeGithubComDariSorokinaGoFirstSprintInternalModels10(in *jlexer.Lexer, out *CreatedAPIKey) {
+	isTopLevel := in.IsStart()
+	if in.IsNull() {
+		if isTopLevel {
+			in.Consumed()
+		}
+		in.Skip()
+		return
+	}
+	in.Delim('{')
+	for !in.IsDelim('}') {
+		key := in.UnsafeFieldName(false)
+		in.WantColon()
+		if in.IsNull() {
+			in.Skip()
+			in.WantComma()
+			continue
+		}
+		switch key {
+		case "key":
+			out.Key = string(in.String())
+		case "id":
+			out.ID = string(in.String())
+		case "name":
+			out.Name = string(in.String())
+		case "scopes":
+			if in.IsNull() {
+				in.Skip()
+				out.Scopes = nil
+			} else {
+				in.Delim('[')
+				if out.Scopes == nil {
+					if !in.IsDelim(']') {
+						out.Scopes = make([]string, 0, 4)
+					} else {
+						out.Scopes = []string{}
+					}
+				} else {
+					out.Scopes = (out.Scopes)[:0]
+				}
+				for !in.IsDelim(']') {
+					var v14 string
+					v14 = string(in.String())
+					out.Scopes = append(out.Scopes, v14)
+					in.WantComma()
+				}
+				in.Delim(']')
+			}
+		case "prefix":
+			out.Prefix = string(in.String())
+		case "created_at":
+			if data := in.Raw(); in.Ok() {
+				in.AddError((out.CreatedAt).UnmarshalJSON(data))
+			}
+		case "revoked_at":
+			if in.IsNull() {
+				in.Skip()
+				out.RevokedAt = nil
+			} else {
+				if out.RevokedAt == nil {
+					out.RevokedAt = new(time.Time)
+				}
+				if data := in.Raw(); in.Ok() {
+					in.AddError((*out.RevokedAt).UnmarshalJSON(data))
+				}
+			}
+		default:
+			in.SkipRecursive()
+		}
+		in.WantComma()
+	}
+	in.Delim('}')
+	if isTopLevel {
+		in.Consumed()
+	}
+}
+func easyjsonD2b7633eEncodeGithubComDariSorokinaGoFirstSprintInternalModels10(out *jwriter.Writer, in CreatedAPIKey) {
+	out.RawByte('{')
+	first := true
+	_ = first
+	{
+		const prefix string = ",\"key\":"
+		out.RawString(prefix[1:])
+		out.String(string(in.Key))
+	}
+	{
+		const prefix string = ",\"id\":"
+		out.RawString(prefix)
+		out.String(string(in.ID))
+	}
+	{
+		const prefix string = ",\"name\":"
+		out.RawString(prefix)
+		out.String(string(in.Name))
+	}
+	{
+		const prefix string = ",\"scopes\":"
+		out.RawString(prefix)
+		if in.Scopes == nil && (out.Flags&jwriter.NilSliceAsEmpty) == 0 {
+			out.RawString("null")
+		} else {
+			out.RawByte('[')
+			for v15, v16 := range in.Scopes {
+				if v15 > 0 {
+					out.RawByte(',')
+				}
+				out.String(string(v16))
+			}
+			out.RawByte(']')
+		}
+	}
+	{
+		const prefix string = ",\"prefix\":"
+		out.RawString(prefix)
+		out.String(string(in.Prefix))
+	}
+	{
+		const prefix string = ",\"created_at\":"
+		out.RawString(prefix)
+		out.Raw((in.CreatedAt).MarshalJSON())
+	}
+	if in.RevokedAt != nil {
+		const prefix string = ",\"revoked_at\":"
+		out.RawString(prefix)
+		out.Raw((*in.RevokedAt).MarshalJSON())
+	}
+	out.RawByte('}')
+}
+
+// MarshalJSON supports json.Marshaler interface
+func (v CreatedAPIKey) MarshalJSON() ([]byte, error) {
+	w := jwriter.Writer{}
+	easyjsonD2b7633eEncodeGithubComDariSorokinaGoFirstSprintInternalModels10(&w, v)
+	return w.Buffer.BuildBytes(), w.Error
+}
+
+// MarshalEasyJSON supports easyjson.Marshaler interface
+func (v CreatedAPIKey) MarshalEasyJSON(w *jwriter.Writer) {
+	easyjsonD2b7633eEncodeGithubComDariSorokinaGoFirstSprintInternalModels10(w, v)
+}
+
+// UnmarshalJSON supports json.Unmarshaler interface
+func (v *CreatedAPIKey) UnmarshalJSON(data []byte) error {
+	r := jlexer.Lexer{Data: data}
+	easyjsonD2b7633eDecodeGithubComDariSorokinaGoFirstSprintInternalModels10(&r, v)
+	return r.Error()
+}
+
+// UnmarshalEasyJSON supports easyjson.Unmarshaler interface
+func (v *CreatedAPIKey) UnmarshalEasyJSON(l *jlexer.Lexer) {
+	easyjsonD2b7633eDecodeGithubComDariSorokinaGoFirstSprintInternalModels10(l, v)
+}
+func easyjsonD2b7633eDecodeGithubComDariSorokinaGoFirstSprintInternalModels11(in *jlexer.Lexer, out *ClickEvent) {
+	isTopLevel := in.IsStart()
+	if in.IsNull() {
+		if isTopLevel {
+			in.Consumed()
+		}
+		in.Skip()
+		return
+	}
+	in.Delim('{')
+	for !in.IsDelim('}') {
+		key := in.UnsafeFieldName(false)
+		in.WantColon()
+		if in.IsNull() {
+			in.Skip()
+			in.WantComma()
+			continue
+		}
+		switch key {
+		case "short_url":
+			out.ShortURL = string(in.String())
+		case "clicked_at":
+			if data := in.Raw(); in.Ok() {
+				in.AddError((out.ClickedAt).UnmarshalJSON(data))
+			}
+		case "referrer":
+			out.Referrer = string(in.String())
+		case "user_agent":
+			out.UserAgent = string(in.String())
+		case "ip_hash":
+			out.IPHash = string(in.String())
+		default:
+			in.SkipRecursive()
+		}
+		in.WantComma()
+	}
+	in.Delim('}')
+	if isTopLevel {
+		in.Consumed()
+	}
+}
+func easyjsonD2b7633eEncodeGithubComDariSorokinaGoFirstSprintInternalModels11(out *jwriter.Writer, in ClickEvent) {
+	out.RawByte('{')
+	first := true
+	_ = first
+	{
+		const prefix string = ",\"short_url\":"
+		out.RawString(prefix[1:])
+		out.String(string(in.ShortURL))
+	}
+	{
+		const prefix string = ",\"clicked_at\":"
+		out.RawString(prefix)
+		out.Raw((in.ClickedAt).MarshalJSON())
+	}
+	{
+		const prefix string = ",\"referrer\":"
+		out.RawString(prefix)
+		out.String(string(in.Referrer))
+	}
+	{
+		const prefix string = ",\"user_agent\":"
+		out.RawString(prefix)
+		out.String(string(in.UserAgent))
+	}
+	{
+		const prefix string = ",\"ip_hash\":"
+		out.RawString(prefix)
+		out.String(string(in.IPHash))
+	}
+	out.RawByte('}')
+}
+
+// MarshalJSON supports json.Marshaler interface
+func (v ClickEvent) MarshalJSON() ([]byte, error) {
+	w := jwriter.Writer{}
+	easyjsonD2b7633eEncodeGithubComDariSorokinaGoFirstSprintInternalModels11(&w, v)
+	return w.Buffer.BuildBytes(), w.Error
+}
+
+// MarshalEasyJSON supports easyjson.Marshaler interface
+func (v ClickEvent) MarshalEasyJSON(w *jwriter.Writer) {
+	easyjsonD2b7633eEncodeGithubComDariSorokinaGoFirstSprintInternalModels11(w, v)
+}
+
+// UnmarshalJSON supports json.Unmarshaler interface
+func (v *ClickEvent) UnmarshalJSON(data []byte) error {
+	r := jlexer.Lexer{Data: data}
+	easyjsonD2b7633eDecodeGithubComDariSorokinaGoFirstSprintInternalModels11(&r, v)
+	return r.Error()
+}
+
+// UnmarshalEasyJSON supports easyjson.Unmarshaler interface
+func (v *ClickEvent) UnmarshalEasyJSON(l *jlexer.Lexer) {
+	easyjsonD2b7633eDecodeGithubComDariSorokinaGoFirstSprintInternalModels11(l, v)
+}
+func easyjsonD2b7633eDecodeGithubComDariSorokinaGoFirstSprintInternalModels12(in *jlexer.Lexer, out *BatchURL) {
+	isTopLevel := in.IsStart()
+	if in.IsNull() {
+		if isTopLevel {
+			in.Consumed()
+		}
+		in.Skip()
+		return
+	}
+	in.Delim('{')
+	for !in.IsDelim('}') {
+		key := in.UnsafeFieldName(false)
+		in.WantColon()
+		if in.IsNull() {
+			in.Skip()
+			in.WantComma()
+			continue
+		}
+		switch key {
+		case "correlation_id":
+			out.CorrelationID = string(in.String())
+		case "original_url":
+			out.OriginalURL = string(in.String())
+		case "short_url":
+			out.ShortURL = string(in.String())
+		case "expires_at":
+			if data := in.Raw(); in.Ok() {
+				in.AddError((out.ExpiresAt).UnmarshalJSON(data))
+			}
+		case "conflict":
+			out.Conflict = bool(in.Bool())
+		default:
+			in.SkipRecursive()
+		}
+		in.WantComma()
+	}
+	in.Delim('}')
+	if isTopLevel {
+		in.Consumed()
+	}
+}
+func easyjsonD2b7633eEncodeGithubComDariSorokinaGoFirstSprintInternalModels12(out *jwriter.Writer, in BatchURL) {
+	out.RawByte('{')
+	first := true
+	_ = first
+	{
+		const prefix string = ",\"correlation_id\":"
+		out.RawString(prefix[1:])
+		out.String(string(in.CorrelationID))
+	}
+	{
+		const prefix string = ",\"original_url\":"
+		out.RawString(prefix)
+		out.String(string(in.OriginalURL))
+	}
+	{
+		const prefix string = ",\"short_url\":"
+		out.RawString(prefix)
+		out.String(string(in.ShortURL))
+	}
+	{
+		const prefix string = ",\"expires_at\":"
+		out.RawString(prefix)
+		out.Raw((in.ExpiresAt).MarshalJSON())
+	}
+	{
+		const prefix string = ",\"conflict\":"
+		out.RawString(prefix)
+		out.Bool(bool(in.Conflict))
+	}
+	out.RawByte('}')
+}
+
+// MarshalJSON supports json.Marshaler interface
+func (v BatchURL) MarshalJSON() ([]byte, error) {
+	w := jwriter.Writer{}
+	easyjsonD2b7633eEncodeGithubComDariSorokinaGoFirstSprintInternalModels12(&w, v)
+	return w.Buffer.BuildBytes(), w.Error
+}
+
+// MarshalEasyJSON supports easyjson.Marshaler interface
+func (v BatchURL) MarshalEasyJSON(w *jwriter.Writer) {
+	easyjsonD2b7633eEncodeGithubComDariSorokinaGoFirstSprintInternalModels12(w, v)
+}
+
+// UnmarshalJSON supports json.Unmarshaler interface
+func (v *BatchURL) UnmarshalJSON(data []byte) error {
+	r := jlexer.Lexer{Data: data}
+	easyjsonD2b7633eDecodeGithubComDariSorokinaGoFirstSprintInternalModels12(&r, v)
+	return r.Error()
+}
+
+// UnmarshalEasyJSON supports easyjson.Unmarshaler interface
+func (v *BatchURL) UnmarshalEasyJSON(l *jlexer.Lexer) {
+	easyjsonD2b7633eDecodeGithubComDariSorokinaGoFirstSprintInternalModels12(l, v)
+}
+func easyjsonD2b7633eDecodeGithubComDariSorokinaGoFirstSprintInternalModels13(in *jlexer.Lexer, out *APIKeys) {
+	isTopLevel := in.IsStart()
+	if in.IsNull() {
+		in.Skip()
+		*out = nil
+	} else {
+		in.Delim('[')
+		if *out == nil {
+			if !in.IsDelim(']') {
+				*out = make(APIKeys, 0, 0)
+			} else {
+				*out = APIKeys{}
+			}
+		} else {
+			*out = (*out)[:0]
+		}
+		for !in.IsDelim(']') {
+			var v17 APIKey
+			(v17).UnmarshalEasyJSON(in)
+			*out = append(*out, v17)
+			in.WantComma()
+		}
+		in.Delim(']')
+	}
+	if isTopLevel {
+		in.Consumed()
+	}
+}
+func easyjsonD2b7633eEncodeGithubComDariSorokinaGoFirstSprintInternalModels13(out *jwriter.Writer, in APIKeys) {
+	if in == nil && (out.Flags&jwriter.NilSliceAsEmpty) == 0 {
+		out.RawString("null")
+	} else {
+		out.RawByte('[')
+		for v18, v19 := range in {
+			if v18 > 0 {
+				out.RawByte(',')
+			}
+			(v19).MarshalEasyJSON(out)
+		}
+		out.RawByte(']')
+	}
+}
+
+// MarshalJSON supports json.Marshaler interface
+func (v APIKeys) MarshalJSON() ([]byte, error) {
+	w := jwriter.Writer{}
+	easyjsonD2b7633eEncodeGithubComDariSorokinaGoFirstSprintInternalModels13(&w, v)
+	return w.Buffer.BuildBytes(), w.Error
+}
+
+// MarshalEasyJSON supports easyjson.Marshaler interface
+func (v APIKeys) MarshalEasyJSON(w *jwriter.Writer) {
+	easyjsonD2b7633eEncodeGithubComDariSorokinaGoFirstSprintInternalModels13(w, v)
+}
+
+// UnmarshalJSON supports json.Unmarshaler interface
+func (v *APIKeys) UnmarshalJSON(data []byte) error {
+	r := jlexer.Lexer{Data: data}
+	easyjsonD2b7633eDecodeGithubComDariSorokinaGoFirstSprintInternalModels13(&r, v)
+	return r.Error()
+}
+
+// UnmarshalEasyJSON supports easyjson.Unmarshaler interface
+func (v *APIKeys) UnmarshalEasyJSON(l *jlexer.Lexer) {
+	easyjsonD2b7633eDecodeGithubComDariSorokinaGoFirstSprintInternalModels13(l, v)
+}
+func easyjsonD2b7633eDecodeGithubComDariSorokinaGoFirstSprintInternalModels14(in *jlexer.Lexer, out *APIKeyRequest) {
+	isTopLevel := in.IsStart()
+	if in.IsNull() {
+		if isTopLevel {
+			in.Consumed()
+		}
+		in.Skip()
+		return
+	}
+	in.Delim('{')
+	for !in.IsDelim('}') {
+		key := in.UnsafeFieldName(false)
+		in.WantColon()
+		if in.IsNull() {
+			in.Skip()
+			in.WantComma()
+			continue
+		}
+		switch key {
+		case "name":
+			out.Name = string(in.String())
+		case "scopes":
+			if in.IsNull() {
+				in.Skip()
+				out.Scopes = nil
+			} else {
+				in.Delim('[')
+				if out.Scopes == nil {
+					if !in.IsDelim(']') {
+						out.Scopes = make([]string, 0, 4)
+					} else {
+						out.Scopes = []string{}
+					}
+				} else {
+					out.Scopes = (out.Scopes)[:0]
+				}
+				for !in.IsDelim(']') {
+					var v20 string
+					v20 = string(in.String())
+					out.Scopes = append(out.Scopes, v20)
+					in.WantComma()
+				}
+				in.Delim(']')
+			}
+		default:
+			in.SkipRecursive()
+		}
+		in.WantComma()
+	}
+	in.Delim('}')
+	if isTopLevel {
+		in.Consumed()
+	}
+}
+func easyjsonD2b7633eEncodeGithubComDariSorokinaGoFirstSprintInternalModels14(out *jwriter.Writer, in APIKeyRequest) {
+	out.RawByte('{')
+	first := true
+	_ = first
+	{
+		const prefix string = ",\"name\":"
+		out.RawString(prefix[1:])
+		out.String(string(in.Name))
+	}
+	{
+		const prefix string = ",\"scopes\":"
+		out.RawString(prefix)
+		if in.Scopes == nil && (out.Flags&jwriter.NilSliceAsEmpty) == 0 {
+			out.RawString("null")
+		} else {
+			out.RawByte('[')
+			for v21, v22 := range in.Scopes {
+				if v21 > 0 {
+					out.RawByte(',')
+				}
+				out.String(string(v22))
+			}
+			out.RawByte(']')
+		}
+	}
+	out.RawByte('}')
+}
+
+// MarshalJSON supports json.Marshaler interface
+func (v APIKeyRequest) MarshalJSON() ([]byte, error) {
+	w := jwriter.Writer{}
+	easyjsonD2b7633eEncodeGithubComDariSorokinaGoFirstSprintInternalModels14(&w, v)
+	return w.Buffer.BuildBytes(), w.Error
+}
+
+// MarshalEasyJSON supports easyjson.Marshaler interface
+func (v APIKeyRequest) MarshalEasyJSON(w *jwriter.Writer) {
+	easyjsonD2b7633eEncodeGithubComDariSorokinaGoFirstSprintInternalModels14(w, v)
+}
+
+// UnmarshalJSON supports json.Unmarshaler interface
+func (v *APIKeyRequest) UnmarshalJSON(data []byte) error {
+	r := jlexer.Lexer{Data: data}
+	easyjsonD2b7633eDecodeGithubComDariSorokinaGoFirstSprintInternalModels14(&r, v)
+	return r.Error()
+}
+
+// UnmarshalEasyJSON supports easyjson.Unmarshaler interface
+func (v *APIKeyRequest) UnmarshalEasyJSON(l *jlexer.Lexer) {
+	easyjsonD2b7633eDecodeGithubComDariSorokinaGoFirstSprintInternalModels14(l, v)
+}
+func easyjsonD2b7633eDecodeGithubComDariSorokinaGoFirstSprintInternalModels15(in *jlexer.Lexer, out *APIKey) {
+	isTopLevel := in.IsStart()
+	if in.IsNull() {
+		if isTopLevel {
+			in.Consumed()
+		}
+		in.Skip()
+		return
+	}
+	in.Delim('{')
+	for !in.IsDelim('}') {
+		key := in.UnsafeFieldName(false)
+		in.WantColon()
+		if in.IsNull() {
+			in.Skip()
+			in.WantComma()
+			continue
+		}
+		switch key {
+		case "id":
+			out.ID = string(in.String())
+		case "name":
+			out.Name = string(in.String())
+		case "scopes":
+			if in.IsNull() {
+				in.Skip()
+				out.Scopes = nil
+			} else {
+				in.Delim('[')
+				if out.Scopes == nil {
+					if !in.IsDelim(']') {
+						out.Scopes = make([]string, 0, 4)
+					} else {
+						out.Scopes = []string{}
+					}
+				} else {
+					out.Scopes = (out.Scopes)[:0]
+				}
+				for !in.IsDelim(']') {
+					var v23 string
+					v23 = string(in.String())
+					out.Scopes = append(out.Scopes, v23)
+					in.WantComma()
+				}
+				in.Delim(']')
+			}
+		case "prefix":
+			out.Prefix = string(in.String())
+		case "created_at":
+			if data := in.Raw(); in.Ok() {
+				in.AddError((out.CreatedAt).UnmarshalJSON(data))
+			}
+		case "revoked_at":
+			if in.IsNull() {
+				in.Skip()
+				out.RevokedAt = nil
+			} else {
+				if out.RevokedAt == nil {
+					out.RevokedAt = new(time.Time)
+				}
+				if data := in.Raw(); in.Ok() {
+					in.AddError((*out.RevokedAt).UnmarshalJSON(data))
+				}
+			}
+		default:
+			in.SkipRecursive()
+		}
+		in.WantComma()
+	}
+	in.Delim('}')
+	if isTopLevel {
+		in.Consumed()
+	}
+}
+func easyjsonD2b7633eEncodeGithubComDariSorokinaGoFirstSprintInternalModels15(out *jwriter.Writer, in APIKey) {
+	out.RawByte('{')
+	first := true
+	_ = first
+	{
+		const prefix string = ",\"id\":"
+		out.RawString(prefix[1:])
+		out.String(string(in.ID))
+	}
+	{
+		const prefix string = ",\"name\":"
+		out.RawString(prefix)
+		out.String(string(in.Name))
+	}
+	{
+		const prefix string = ",\"scopes\":"
+		out.RawString(prefix)
+		if in.Scopes == nil && (out.Flags&jwriter.NilSliceAsEmpty) == 0 {
+			out.RawString("null")
+		} else {
+			out.RawByte('[')
+			for v24, v25 := range in.Scopes {
+				if v24 > 0 {
+					out.RawByte(',')
+				}
+				out.String(string(v25))
+			}
+			out.RawByte(']')
+		}
+	}
+	{
+		const prefix string = ",\"prefix\":"
+		out.RawString(prefix)
+		out.String(string(in.Prefix))
+	}
+	{
+		const prefix string = ",\"created_at\":"
+		out.RawString(prefix)
+		out.Raw((in.CreatedAt).MarshalJSON())
+	}
+	if in.RevokedAt != nil {
+		const prefix string = ",\"revoked_at\":"
+		out.RawString(prefix)
+		out.Raw((*in.RevokedAt).MarshalJSON())
+	}
+	out.RawByte('}')
+}
+
+// MarshalJSON supports json.Marshaler interface
+func (v APIKey) MarshalJSON() ([]byte, error) {
+	w := jwriter.Writer{}
+	easyjsonD2b7633eEncodeGithubComDariSorokinaGoFirstSprintInternalModels15(&w, v)
+	return w.Buffer.BuildBytes(), w.Error
+}
+
+// MarshalEasyJSON supports easyjson.Marshaler interface
+func (v APIKey) MarshalEasyJSON(w *jwriter.Writer) {
+	easyjsonD2b7633eEncodeGithubComDariSorokinaGoFirstSprintInternalModels15(w, v)
+}
+
+// UnmarshalJSON supports json.Unmarshaler interface
+func (v *APIKey) UnmarshalJSON(data []byte) error {
+	r := jlexer.Lexer{Data: data}
+	easyjsonD2b7633eDecodeGithubComDariSorokinaGoFirstSprintInternalModels15(&r, v)
+	return r.Error()
+}
+
+// UnmarshalEasyJSON supports easyjson.Unmarshaler interface
+func (v *APIKey) UnmarshalEasyJSON(l *jlexer.Lexer) {
+	easyjsonD2b7633eDecodeGithubComDariSorokinaGoFirstSprintInternalModels15(l, v)
 }
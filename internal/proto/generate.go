@@ -0,0 +1,4 @@
+// Package proto contains the protobuf definition of the shortener gRPC API and the code generated from it.
+package proto
+
+//go:generate protoc --go_out=. --go_opt=paths=source_relative --go-grpc_out=. --go-grpc_opt=paths=source_relative shortener.proto
@@ -8,8 +8,6 @@ import (
 	"log"
 	"net/http"
 	"net/http/httptest"
-
-	"github.com/DariSorokina/go-first-sprint/internal/cookie"
 )
 
 func exampleRequest(ts *httptest.Server, method, path string, requestBody io.Reader) (*http.Response, string) {
@@ -27,7 +25,10 @@ func exampleRequest(ts *httptest.Server, method, path string, requestBody io.Rea
 		},
 	}
 
-	_, clientIDcookie := cookie.СreateCookieClientID("test")
+	clientIDcookie, err := newTestTokens().NewCookie(1)
+	if err != nil {
+		log.Println(err)
+	}
 	req.AddCookie(clientIDcookie)
 
 	result, err := client.Do(req)
@@ -4,15 +4,17 @@ import (
 	"context"
 	"encoding/json"
 	"errors"
+	"fmt"
 	"io"
 	"net/http"
 	"net/url"
-	"strconv"
 	"time"
 
 	"github.com/DariSorokina/go-first-sprint/internal/app"
 	"github.com/DariSorokina/go-first-sprint/internal/config"
+	"github.com/DariSorokina/go-first-sprint/internal/cookie"
 	"github.com/DariSorokina/go-first-sprint/internal/logger"
+	"github.com/DariSorokina/go-first-sprint/internal/middleware"
 	"github.com/DariSorokina/go-first-sprint/internal/models"
 	"github.com/DariSorokina/go-first-sprint/internal/storage"
 	"github.com/go-chi/chi/v5"
@@ -20,13 +22,16 @@ import (
 )
 
 type originalURL struct {
-	CorrelationID string `json:"correlation_id"`
-	OriginalURL   string `json:"original_url"`
+	CorrelationID string     `json:"correlation_id"`
+	OriginalURL   string     `json:"original_url"`
+	ExpiresAt     *time.Time `json:"expires_at,omitempty"`
+	TTLSeconds    int64      `json:"ttl_seconds,omitempty"`
 }
 
 type shortURL struct {
 	CorrelationID string `json:"correlation_id"`
 	ShortURL      string `json:"short_url"`
+	Conflict      bool   `json:"conflict,omitempty"`
 }
 
 type handlers struct {
@@ -57,14 +62,20 @@ func (handlers *handlers) originalHandler(res http.ResponseWriter, req *http.Req
 
 	idValue := chi.URLParam(req, "id")
 	correspondingURL, getOriginalErr := handlers.app.ToOriginalURL(ctx, idValue)
-	if errors.Is(getOriginalErr, storage.ErrDeletedURL) {
-		res.Header().Set("Location", correspondingURL)
+	switch {
+	case errors.Is(getOriginalErr, storage.ErrExpired):
+		http.Error(res, "Short URL has expired", http.StatusGone)
+	case errors.Is(getOriginalErr, storage.ErrDeleted):
 		res.WriteHeader(http.StatusGone)
-	} else {
+	case errors.Is(getOriginalErr, storage.ErrNotFound):
+		http.Error(res, "Short URL not found", http.StatusNotFound)
+	case getOriginalErr != nil:
+		handlers.storageError(res, "Failed to get original URL", getOriginalErr)
+	default:
 		res.Header().Set("Location", correspondingURL)
 		res.WriteHeader(http.StatusTemporaryRedirect)
+		handlers.app.RecordClick(idValue, req.Referer(), req.UserAgent(), middleware.ClientIP(req))
 	}
-
 }
 
 func (handlers *handlers) shortenerHandler(res http.ResponseWriter, req *http.Request) {
@@ -78,13 +89,17 @@ func (handlers *handlers) shortenerHandler(res http.ResponseWriter, req *http.Re
 		return
 	}
 
-	userID := req.Header.Get("ClientID")
-	userIDInt, err := strconv.Atoi(userID)
-	if err != nil {
-		handlers.log.Sugar().Errorf("Failed to parse client ID: %s", err)
+	userIDInt, ok := cookie.UserIDFromContext(req.Context())
+	if !ok {
+		http.Error(res, "Unauthorized", http.StatusUnauthorized)
+		return
 	}
 
-	shortenedURL, errShortURL := handlers.app.ToShortenURL(ctx, string(requestBody), userIDInt)
+	shortenedURL, errShortURL := handlers.app.ToShortenURL(ctx, string(requestBody), userIDInt, time.Time{})
+	if errShortURL != nil && !errors.Is(errShortURL, storage.ErrShortURLAlreadyExist) {
+		handlers.storageError(res, "Failed to shorten URL", errShortURL)
+		return
+	}
 	response, err = url.JoinPath(handlers.flagConfig.FlagBaseURL, shortenedURL)
 	if err != nil {
 		http.Error(res, "Bad URL path provided", http.StatusInternalServerError)
@@ -121,13 +136,28 @@ func (handlers *handlers) shortenerHandlerJSON(res http.ResponseWriter, req *htt
 		return
 	}
 
-	userID := req.Header.Get("ClientID")
-	userIDInt, err := strconv.Atoi(userID)
+	userIDInt, ok := cookie.UserIDFromContext(req.Context())
+	if !ok {
+		http.Error(res, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	expiresAt, err := app.ExpirationTime(request.ExpiresAt, request.TTLSeconds)
 	if err != nil {
-		handlers.log.Sugar().Errorf("Failed to parse client ID: %s", err)
+		http.Error(res, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if request.Alias != "" {
+		handlers.aliasHandler(ctx, res, request, userIDInt, expiresAt)
+		return
 	}
 
-	shortenedURL, errShortURL := handlers.app.ToShortenURL(ctx, string(request.OriginalURL), userIDInt)
+	shortenedURL, errShortURL := handlers.app.ToShortenURL(ctx, string(request.OriginalURL), userIDInt, expiresAt)
+	if errShortURL != nil && !errors.Is(errShortURL, storage.ErrShortURLAlreadyExist) {
+		handlers.storageError(res, "Failed to shorten URL", errShortURL)
+		return
+	}
 
 	response.ShortenURL, err = url.JoinPath(handlers.flagConfig.FlagBaseURL, shortenedURL)
 	if err != nil {
@@ -153,6 +183,46 @@ func (handlers *handlers) shortenerHandlerJSON(res http.ResponseWriter, req *htt
 	res.Write(resp)
 }
 
+func (handlers *handlers) aliasHandler(ctx context.Context, res http.ResponseWriter, request models.Request, userID int, expiresAt time.Time) {
+	var response models.Response
+
+	ownerID, errAlias := handlers.app.ToAliasURL(ctx, request.Alias, request.OriginalURL, userID, expiresAt)
+	switch {
+	case errors.Is(errAlias, app.ErrInvalidAlias):
+		http.Error(res, errAlias.Error(), http.StatusBadRequest)
+		return
+	case errors.Is(errAlias, storage.ErrAliasAlreadyExist):
+		response.Owner = ownerID
+	case errAlias != nil:
+		handlers.storageError(res, "Failed to shorten URL", errAlias)
+		return
+	}
+
+	var err error
+	response.ShortenURL, err = url.JoinPath(handlers.flagConfig.FlagBaseURL, request.Alias)
+	if err != nil {
+		http.Error(res, "Bad URL path provided", http.StatusInternalServerError)
+		handlers.log.Sugar().Errorf("Failed to join provided URL path with alias: %s", err)
+		return
+	}
+
+	resp, err := easyjson.Marshal(response)
+	if err != nil {
+		http.Error(res, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	res.Header().Set("Content-Type", "application/json")
+
+	if errors.Is(errAlias, storage.ErrAliasAlreadyExist) {
+		res.WriteHeader(http.StatusConflict)
+	} else {
+		res.WriteHeader(http.StatusCreated)
+	}
+
+	res.Write(resp)
+}
+
 func (handlers *handlers) shortenerBatchHandler(res http.ResponseWriter, req *http.Request) {
 	ctx, cancel := context.WithTimeout(req.Context(), 10*time.Second)
 	defer cancel()
@@ -172,23 +242,36 @@ func (handlers *handlers) shortenerBatchHandler(res http.ResponseWriter, req *ht
 		return
 	}
 
-	userID := req.Header.Get("ClientID")
-	userIDInt, err := strconv.Atoi(userID)
-	if err != nil {
-		handlers.log.Sugar().Errorf("Failed to parse client ID: %s", err)
+	userIDInt, ok := cookie.UserIDFromContext(req.Context())
+	if !ok {
+		http.Error(res, "Unauthorized", http.StatusUnauthorized)
+		return
 	}
 
+	batch := make([]models.BatchURL, 0, len(input))
 	for _, inputSample := range input {
-		shortenedURL, _ := handlers.app.ToShortenURL(ctx, inputSample.OriginalURL, userIDInt)
+		expiresAt, err := app.ExpirationTime(inputSample.ExpiresAt, inputSample.TTLSeconds)
+		if err != nil {
+			http.Error(res, fmt.Sprintf("%s: %s", inputSample.CorrelationID, err), http.StatusBadRequest)
+			return
+		}
+		batch = append(batch, models.BatchURL{CorrelationID: inputSample.CorrelationID, OriginalURL: inputSample.OriginalURL, ExpiresAt: expiresAt})
+	}
 
-		response, err = url.JoinPath(handlers.flagConfig.FlagBaseURL, shortenedURL)
+	if err = handlers.app.ToShortenBatch(ctx, batch, userIDInt); err != nil {
+		handlers.storageError(res, "Failed to shorten URLs", err)
+		return
+	}
+
+	// Conflicts are reported per item, so the batch as a whole is always created.
+	for _, item := range batch {
+		response, err = url.JoinPath(handlers.flagConfig.FlagBaseURL, item.ShortURL)
 		if err != nil {
 			http.Error(res, "Bad URL path provided", http.StatusInternalServerError)
 			handlers.log.Sugar().Errorf("Failed to join provided URL path with short URL: %s", err)
 			return
 		}
-
-		output = append(output, shortURL{CorrelationID: inputSample.CorrelationID, ShortURL: response})
+		output = append(output, shortURL{CorrelationID: item.CorrelationID, ShortURL: response, Conflict: item.Conflict})
 	}
 
 	resp, err := json.Marshal(output)
@@ -206,14 +289,17 @@ func (handlers *handlers) urlsByIDHandler(res http.ResponseWriter, req *http.Req
 	ctx, cancel := context.WithTimeout(req.Context(), 10*time.Second)
 	defer cancel()
 
-	userID := req.Header.Get("ClientID")
-	userIDInt, err := strconv.Atoi(userID)
-	if err != nil {
-		handlers.log.Sugar().Errorf("Failed to parse client ID: %s", err)
+	userIDInt, ok := cookie.UserIDFromContext(req.Context())
+	if !ok {
+		http.Error(res, "Unauthorized", http.StatusUnauthorized)
 		return
 	}
 
-	urlPairs := handlers.app.GetURLsByUserID(ctx, userIDInt)
+	urlPairs, err := handlers.app.GetURLsByUserID(ctx, userIDInt)
+	if err != nil {
+		handlers.storageError(res, "Failed to get user URLs", err)
+		return
+	}
 
 	if len(urlPairs) == 0 {
 		res.WriteHeader(http.StatusNoContent)
@@ -246,11 +332,59 @@ func (handlers *handlers) urlsByIDHandler(res http.ResponseWriter, req *http.Req
 
 }
 
+func (handlers *handlers) urlStatsHandler(res http.ResponseWriter, req *http.Request) {
+	ctx, cancel := context.WithTimeout(req.Context(), 10*time.Second)
+	defer cancel()
+
+	userIDInt, ok := cookie.UserIDFromContext(req.Context())
+	if !ok {
+		http.Error(res, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	stats, err := handlers.app.GetLinkStats(ctx, chi.URLParam(req, "id"), userIDInt)
+	if errors.Is(err, storage.ErrNotFound) {
+		http.Error(res, "Short URL not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		handlers.storageError(res, "Failed to get link statistics", err)
+		return
+	}
+
+	resp, err := easyjson.Marshal(stats)
+	if err != nil {
+		http.Error(res, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	res.Header().Set("Content-Type", "application/json")
+	res.Write(resp)
+}
+
+func (handlers *handlers) internalStatsHandler(res http.ResponseWriter, req *http.Request) {
+	ctx, cancel := context.WithTimeout(req.Context(), 10*time.Second)
+	defer cancel()
+
+	stats, err := handlers.app.GetInternalStats(ctx)
+	if err != nil {
+		handlers.storageError(res, "Failed to get statistics", err)
+		return
+	}
+
+	resp, err := easyjson.Marshal(stats)
+	if err != nil {
+		http.Error(res, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	res.Header().Set("Content-Type", "application/json")
+	res.Write(resp)
+}
+
 func (handlers *handlers) deleteURLsHandler(res http.ResponseWriter, req *http.Request) {
 
 	var urls []string
-	deleteURLsChannel := make(chan models.URLsClientID, 1)
-	defer close(deleteURLsChannel)
 
 	requestBody, err := io.ReadAll(req.Body)
 	if err != nil {
@@ -258,33 +392,211 @@ func (handlers *handlers) deleteURLsHandler(res http.ResponseWriter, req *http.R
 		return
 	}
 
-	err = json.Unmarshal(requestBody, &urls)
+	if err = json.Unmarshal(requestBody, &urls); err != nil {
+		http.Error(res, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if len(urls) == 0 {
+		http.Error(res, "No short URLs provided", http.StatusBadRequest)
+		return
+	}
+
+	userIDInt, ok := cookie.UserIDFromContext(req.Context())
+	if !ok {
+		http.Error(res, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	jobID, err := handlers.app.DeleteURLs(urls, userIDInt)
+	if errors.Is(err, app.ErrDeleteQueueFull) {
+		res.Header().Set("Retry-After", "1")
+		http.Error(res, "Too many pending deletions, retry later", http.StatusServiceUnavailable)
+		handlers.log.Sugar().Warnf("Rejected deletion of %d urls: %s", len(urls), err)
+		return
+	}
 	if err != nil {
-		handlers.log.Sugar().Errorf("An error occurred while parsing the data: %s", err)
+		handlers.log.Sugar().Errorf("Failed to create deletion job: %s", err)
+		http.Error(res, "Failed to create deletion job", http.StatusInternalServerError)
+		return
 	}
 
-	userID := req.Header.Get("ClientID")
-	userIDInt, err := strconv.Atoi(userID)
+	location, err := url.JoinPath(handlers.flagConfig.FlagBaseURL, "api/user/urls/delete-jobs", jobID)
 	if err != nil {
-		handlers.log.Sugar().Errorf("Failed to parse client ID: %s", err)
+		http.Error(res, err.Error(), http.StatusInternalServerError)
+		return
 	}
 
-	go handlers.app.DeleteURLs(deleteURLsChannel)
+	res.Header().Set("Location", location)
+	res.WriteHeader(http.StatusAccepted)
+	res.Write([]byte{})
 
-	batchSize := 2
-	for i := 0; i < len(urls); i += batchSize {
-		end := i + batchSize
-		if end > len(urls) {
-			end = len(urls)
-		}
+}
+
+func (handlers *handlers) restoreURLsHandler(res http.ResponseWriter, req *http.Request) {
+	ctx, cancel := context.WithTimeout(req.Context(), 10*time.Second)
+	defer cancel()
+
+	var urls []string
+	if err := json.NewDecoder(req.Body).Decode(&urls); err != nil {
+		http.Error(res, "Bad request body", http.StatusBadRequest)
+		return
+	}
 
-		var urlsClientID models.URLsClientID
-		urlsClientID.URLs = urls[i:end]
-		urlsClientID.ClientID = userIDInt
-		deleteURLsChannel <- urlsClientID
+	userIDInt, ok := cookie.UserIDFromContext(req.Context())
+	if !ok {
+		http.Error(res, "Unauthorized", http.StatusUnauthorized)
+		return
 	}
 
-	res.WriteHeader(http.StatusAccepted)
-	res.Write([]byte{})
+	results, err := handlers.app.RestoreURLs(ctx, urls, userIDInt)
+	if err != nil {
+		handlers.storageError(res, "Failed to restore urls", err)
+		return
+	}
+
+	resp, err := easyjson.Marshal(models.RestoreResult{Results: results})
+	if err != nil {
+		http.Error(res, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	res.Header().Set("Content-Type", "application/json")
+	res.Write(resp)
+}
+
+func (handlers *handlers) deleteJobHandler(res http.ResponseWriter, req *http.Request) {
+	ctx, cancel := context.WithTimeout(req.Context(), 10*time.Second)
+	defer cancel()
 
+	userIDInt, ok := cookie.UserIDFromContext(req.Context())
+	if !ok {
+		http.Error(res, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	job, err := handlers.app.GetDeleteJob(ctx, chi.URLParam(req, "id"), userIDInt)
+	if errors.Is(err, app.ErrDeleteJobNotFound) {
+		http.Error(res, "Deletion job not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		handlers.storageError(res, "Failed to get deletion job", err)
+		return
+	}
+
+	resp, err := easyjson.Marshal(job)
+	if err != nil {
+		http.Error(res, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	res.Header().Set("Content-Type", "application/json")
+	res.Write(resp)
+}
+
+func (handlers *handlers) createAPIKeyHandler(res http.ResponseWriter, req *http.Request) {
+	ctx, cancel := context.WithTimeout(req.Context(), 10*time.Second)
+	defer cancel()
+
+	var request models.APIKeyRequest
+	requestBody, err := io.ReadAll(req.Body)
+	if err != nil {
+		http.Error(res, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err = easyjson.Unmarshal(requestBody, &request); err != nil {
+		http.Error(res, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	userIDInt, ok := cookie.UserIDFromContext(req.Context())
+	if !ok {
+		http.Error(res, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	apiKey, key, err := handlers.app.CreateAPIKey(ctx, userIDInt, request.Name, request.Scopes)
+	if errors.Is(err, app.ErrInvalidAPIKeyScopes) {
+		http.Error(res, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err != nil {
+		handlers.storageError(res, "Failed to create API key", err)
+		return
+	}
+
+	resp, err := easyjson.Marshal(models.CreatedAPIKey{APIKey: apiKey, Key: key})
+	if err != nil {
+		http.Error(res, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	res.Header().Set("Content-Type", "application/json")
+	res.Header().Set("Cache-Control", "no-store")
+	res.WriteHeader(http.StatusCreated)
+	res.Write(resp)
+}
+
+func (handlers *handlers) apiKeysHandler(res http.ResponseWriter, req *http.Request) {
+	ctx, cancel := context.WithTimeout(req.Context(), 10*time.Second)
+	defer cancel()
+
+	userIDInt, ok := cookie.UserIDFromContext(req.Context())
+	if !ok {
+		http.Error(res, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	keys, err := handlers.app.ListAPIKeys(ctx, userIDInt)
+	if err != nil {
+		handlers.storageError(res, "Failed to list API keys", err)
+		return
+	}
+
+	resp, err := easyjson.Marshal(append(models.APIKeys{}, keys...))
+	if err != nil {
+		http.Error(res, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	res.Header().Set("Content-Type", "application/json")
+	res.Write(resp)
+}
+
+func (handlers *handlers) revokeAPIKeyHandler(res http.ResponseWriter, req *http.Request) {
+	ctx, cancel := context.WithTimeout(req.Context(), 10*time.Second)
+	defer cancel()
+
+	userIDInt, ok := cookie.UserIDFromContext(req.Context())
+	if !ok {
+		http.Error(res, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	if err := handlers.app.RevokeAPIKey(ctx, chi.URLParam(req, "id"), userIDInt); err != nil {
+		handlers.storageError(res, "Failed to revoke API key", err)
+		return
+	}
+	res.WriteHeader(http.StatusNoContent)
+}
+
+// storageError responds with the status matching the category of a storage error and logs server-side failures.
+// A storage outage is reported as 503 rather than a made-up result, so clients know to retry.
+func (handlers *handlers) storageError(res http.ResponseWriter, message string, err error) {
+	status := http.StatusInternalServerError
+	switch {
+	case errors.Is(err, storage.ErrNotFound):
+		status = http.StatusNotFound
+	case errors.Is(err, storage.ErrConflict):
+		status = http.StatusConflict
+	case errors.Is(err, storage.ErrDeleted), errors.Is(err, storage.ErrExpired):
+		status = http.StatusGone
+	case errors.Is(err, storage.ErrUnavailable), errors.Is(err, context.DeadlineExceeded):
+		status = http.StatusServiceUnavailable
+	}
+
+	if status >= http.StatusInternalServerError {
+		handlers.log.Sugar().Errorf("%s: %s", message, err)
+	}
+	http.Error(res, message, status)
 }
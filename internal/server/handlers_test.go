@@ -2,18 +2,22 @@ package server
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"io"
 	"log"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/DariSorokina/go-first-sprint/internal/app"
 	"github.com/DariSorokina/go-first-sprint/internal/config"
 	"github.com/DariSorokina/go-first-sprint/internal/cookie"
 	"github.com/DariSorokina/go-first-sprint/internal/logger"
+	"github.com/DariSorokina/go-first-sprint/internal/models"
 	"github.com/DariSorokina/go-first-sprint/internal/storage"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -33,7 +37,8 @@ func testRequest(t *testing.T, ts *httptest.Server, method, path string, clientI
 	}
 
 	if clientID != 0 {
-		_, clientIDcookie := cookie.СreateCookieClientID("test")
+		clientIDcookie, err := newTestTokens().NewCookie(clientID)
+		require.NoError(t, err)
 		req.AddCookie(clientIDcookie)
 	}
 
@@ -47,10 +52,19 @@ func testRequest(t *testing.T, ts *httptest.Server, method, path string, clientI
 	return result, string(resultBody)
 }
 
+// newTestTokens returns user tokens with the default configuration shared by the test servers and requests.
+func newTestTokens() *cookie.Tokens {
+	tokens, err := cookie.NewTokens(config.NewFlagConfig())
+	if err != nil {
+		panic(err)
+	}
+	return tokens
+}
+
 func TestRouter(t *testing.T) {
-	flagConfig := config.ParseFlags()
+	flagConfig, err := config.ParseFlags()
+	require.NoError(t, err)
 	var l *logger.Logger
-	var err error
 	if l, err = logger.CreateLogger(flagConfig.FlagLogLevel); err != nil {
 		log.Fatal("Failed to create logger:", err)
 	}
@@ -64,8 +78,13 @@ func TestRouter(t *testing.T) {
 		defer storage.Close()
 	}
 
-	app := app.NewApp(storage, l)
-	serv := NewServer(app, flagConfig, l)
+	// The test requests act as user 1, the first user issued by a new storage.
+	_, err = storage.CreateUser(context.Background())
+	require.NoError(t, err)
+
+	app, err := app.NewApp(storage, flagConfig, l)
+	require.NoError(t, err)
+	serv := NewServer(app, newTestTokens(), flagConfig, l)
 	testServer := httptest.NewServer(serv.newRouter())
 	defer testServer.Close()
 
@@ -126,6 +145,19 @@ func TestRouter(t *testing.T) {
 				expectedLocation:    "https://practicum.yandex.ru/",
 			},
 		},
+		{
+			name:        "handler: OriginalHandler, test: StatusNotFound",
+			method:      http.MethodGet,
+			clientID:    1,
+			requestBody: nil,
+			requestPath: "/unknown123",
+			expectedData: expectedData{
+				expectedContentType: "text/plain; charset=utf-8",
+				expectedStatusCode:  http.StatusNotFound,
+				expectedBody:        "Short URL not found\n",
+				expectedLocation:    "",
+			},
+		},
 		{
 			name:        "handler: shortenerHandlerJSON, test: StatusCreated",
 			method:      http.MethodPost,
@@ -139,6 +171,97 @@ func TestRouter(t *testing.T) {
 				expectedLocation:    "",
 			},
 		},
+		{
+			name:        "handler: shortenerHandlerJSON, test: reserved alias",
+			method:      http.MethodPost,
+			clientID:    1,
+			requestBody: bytes.NewBuffer([]byte("{\"url\":\"https://practicum.yandex.ru/\",\"alias\":\"ping\"} ")),
+			requestPath: "/api/shorten",
+			expectedData: expectedData{
+				expectedContentType: "text/plain; charset=utf-8",
+				expectedStatusCode:  http.StatusBadRequest,
+				expectedBody:        "invalid alias: alias \"ping\" is reserved\n",
+				expectedLocation:    "",
+			},
+		},
+		{
+			name:        "handler: shortenerHandlerJSON, test: invalid alias",
+			method:      http.MethodPost,
+			clientID:    1,
+			requestBody: bytes.NewBuffer([]byte("{\"url\":\"https://practicum.yandex.ru/\",\"alias\":\"spring/sale\"} ")),
+			requestPath: "/api/shorten",
+			expectedData: expectedData{
+				expectedContentType: "text/plain; charset=utf-8",
+				expectedStatusCode:  http.StatusBadRequest,
+				expectedBody:        "invalid alias: alias must be 3 to 64 characters of letters, digits, '-' or '_'\n",
+				expectedLocation:    "",
+			},
+		},
+		{
+			name:        "handler: internalStatsHandler, test: StatusForbidden",
+			method:      http.MethodGet,
+			clientID:    0,
+			requestBody: nil,
+			requestPath: "/api/internal/stats",
+			expectedData: expectedData{
+				expectedContentType: "text/plain; charset=utf-8",
+				expectedStatusCode:  http.StatusForbidden,
+				expectedBody:        "Forbidden\n",
+				expectedLocation:    "",
+			},
+		},
+		{
+			name:        "handler: deleteJobHandler, test: StatusNotFound",
+			method:      http.MethodGet,
+			clientID:    1,
+			requestBody: nil,
+			requestPath: "/api/user/urls/delete-jobs/unknown",
+			expectedData: expectedData{
+				expectedContentType: "text/plain; charset=utf-8",
+				expectedStatusCode:  http.StatusNotFound,
+				expectedBody:        "Deletion job not found\n",
+				expectedLocation:    "",
+			},
+		},
+		{
+			name:        "handler: deleteURLsHandler, test: StatusBadRequest (malformed JSON)",
+			method:      http.MethodDelete,
+			clientID:    1,
+			requestBody: bytes.NewBufferString(`["d41d8cd98f"`),
+			requestPath: "/api/user/urls",
+			expectedData: expectedData{
+				expectedContentType: "text/plain; charset=utf-8",
+				expectedStatusCode:  http.StatusBadRequest,
+				expectedBody:        "unexpected end of JSON input\n",
+				expectedLocation:    "",
+			},
+		},
+		{
+			name:        "handler: deleteURLsHandler, test: StatusBadRequest (empty list)",
+			method:      http.MethodDelete,
+			clientID:    1,
+			requestBody: bytes.NewBufferString(`[]`),
+			requestPath: "/api/user/urls",
+			expectedData: expectedData{
+				expectedContentType: "text/plain; charset=utf-8",
+				expectedStatusCode:  http.StatusBadRequest,
+				expectedBody:        "No short URLs provided\n",
+				expectedLocation:    "",
+			},
+		},
+		{
+			name:        "handler: restoreURLsHandler, test: StatusOK",
+			method:      http.MethodPost,
+			clientID:    1,
+			requestBody: bytes.NewBufferString(`["d41d8cd98f"]`),
+			requestPath: "/api/user/urls/restore",
+			expectedData: expectedData{
+				expectedContentType: "application/json",
+				expectedStatusCode:  http.StatusOK,
+				expectedBody:        `{"results":{"d41d8cd98f":"not_owned"}}`,
+				expectedLocation:    "",
+			},
+		},
 		{
 			name:        "handler: shortenerBatchHandler, test: StatusCreated",
 			method:      http.MethodPost,
@@ -148,7 +271,7 @@ func TestRouter(t *testing.T) {
 			expectedData: expectedData{
 				expectedContentType: "application/json",
 				expectedStatusCode:  http.StatusCreated,
-				expectedBody:        "[{\"correlation_id\":\"qwerty\",\"short_url\":\"http://localhost:8080/d41d8cd98f\"}]",
+				expectedBody:        "[{\"correlation_id\":\"qwerty\",\"short_url\":\"http://localhost:8080/d41d8cd98f\",\"conflict\":true}]",
 				expectedLocation:    "",
 			},
 		},
@@ -165,11 +288,69 @@ func TestRouter(t *testing.T) {
 	}
 }
 
+// unavailableStorage is a storage whose database can not be reached.
+type unavailableStorage struct {
+	storage.Database
+}
+
+func (unavailableStorage) SetValue(ctx context.Context, shortURL, longURL string, userID int, expiresAt time.Time) (string, error) {
+	return "", fmt.Errorf("%w: connection refused", storage.ErrUnavailable)
+}
+
+func (unavailableStorage) UserExists(ctx context.Context, userID int) (bool, error) {
+	return true, nil
+}
+
+func (unavailableStorage) GetOriginal(ctx context.Context, shortURL string) (string, error) {
+	return "", fmt.Errorf("%w: connection refused", storage.ErrUnavailable)
+}
+
+func TestStorageUnavailable(t *testing.T) {
+	flagConfig := config.NewFlagConfig()
+	l, err := logger.CreateLogger("fatal")
+	require.NoError(t, err)
+
+	app, err := app.NewApp(unavailableStorage{}, flagConfig, l)
+	require.NoError(t, err)
+	testServer := httptest.NewServer(NewServer(app, newTestTokens(), flagConfig, l).newRouter())
+	defer testServer.Close()
+
+	result, resultBody := testRequest(t, testServer, http.MethodPost, "/", 1, bytes.NewBufferString("https://practicum.yandex.ru/"))
+	assert.Equal(t, http.StatusServiceUnavailable, result.StatusCode)
+	assert.Equal(t, "Failed to shorten URL\n", resultBody)
+
+	result, _ = testRequest(t, testServer, http.MethodGet, "/d41d8cd98f", 0, nil)
+	assert.Equal(t, http.StatusServiceUnavailable, result.StatusCode)
+	assert.Empty(t, result.Header.Get("Location"))
+}
+
+func TestDeleteJobLookupFailure(t *testing.T) {
+	flagConfig := config.NewFlagConfig()
+	l, err := logger.CreateLogger("fatal")
+	require.NoError(t, err)
+
+	app, err := app.NewApp(storage.NewStorage("", l), flagConfig, l)
+	require.NoError(t, err)
+	serv := NewServer(app, newTestTokens(), flagConfig, l)
+
+	ctx, cancel := context.WithDeadline(context.Background(), time.Now().Add(-time.Second))
+	defer cancel()
+	req := httptest.NewRequest(http.MethodGet, "/api/user/urls/delete-jobs/unknown", nil)
+	req = req.WithContext(cookie.WithUserID(ctx, 1))
+	res := httptest.NewRecorder()
+	serv.handlers.deleteJobHandler(res, req)
+
+	assert.Equal(t, http.StatusServiceUnavailable, res.Code)
+	assert.Equal(t, "Failed to get deletion job\n", res.Body.String())
+}
+
 func getTestServer() (flagConfig *config.FlagConfig, storageFile storage.Database, serv *Server) {
-	flagConfig = config.ParseFlags()
+	flagConfig, err := config.ParseFlags()
+	if err != nil {
+		log.Fatal("Failed to parse configuration:", err)
+	}
 
 	var l *logger.Logger
-	var err error
 	if l, err = logger.CreateLogger(flagConfig.FlagLogLevel); err != nil {
 		log.Fatal("Failed to create logger:", err)
 	}
@@ -179,8 +360,16 @@ func getTestServer() (flagConfig *config.FlagConfig, storageFile storage.Databas
 		panic(err)
 	}
 
-	app := app.NewApp(storageFile, l)
-	serv = NewServer(app, flagConfig, l)
+	// The example requests act as user 1, the first user issued by a new storage.
+	if _, err = storageFile.CreateUser(context.Background()); err != nil {
+		panic(err)
+	}
+
+	app, err := app.NewApp(storageFile, flagConfig, l)
+	if err != nil {
+		panic(err)
+	}
+	serv = NewServer(app, newTestTokens(), flagConfig, l)
 
 	return flagConfig, storageFile, serv
 }
@@ -219,7 +408,7 @@ func BenchmarkHandlers(b *testing.B) {
 			requestBody := bytes.NewBuffer([]byte(""))
 			httpRequest := httptest.NewRequest(httpMethod, testServer.URL+requestPath, requestBody)
 			responseRecorder := httptest.NewRecorder()
-			httpRequest.Header.Set("ClientID", "1")
+			httpRequest = httpRequest.WithContext(cookie.WithUserID(httpRequest.Context(), 1))
 			b.StartTimer()
 			serv.handlers.originalHandler(responseRecorder, httpRequest)
 		}
@@ -233,7 +422,7 @@ func BenchmarkHandlers(b *testing.B) {
 			requestBody := bytes.NewBuffer([]byte("{\"url\":\"https://practicum.yandex.ru/\"} "))
 			httpRequest := httptest.NewRequest(httpMethod, testServer.URL+requestPath, requestBody)
 			responseRecorder := httptest.NewRecorder()
-			httpRequest.Header.Set("ClientID", "1")
+			httpRequest = httpRequest.WithContext(cookie.WithUserID(httpRequest.Context(), 1))
 			b.StartTimer()
 			serv.handlers.shortenerHandler(responseRecorder, httpRequest)
 		}
@@ -247,7 +436,7 @@ func BenchmarkHandlers(b *testing.B) {
 			requestBody := bytes.NewBuffer([]byte("https://practicum.yandex.ru/"))
 			httpRequest := httptest.NewRequest(httpMethod, testServer.URL+requestPath, requestBody)
 			responseRecorder := httptest.NewRecorder()
-			httpRequest.Header.Set("ClientID", "1")
+			httpRequest = httpRequest.WithContext(cookie.WithUserID(httpRequest.Context(), 1))
 			b.StartTimer()
 			serv.handlers.shortenerHandlerJSON(responseRecorder, httpRequest)
 		}
@@ -261,9 +450,138 @@ func BenchmarkHandlers(b *testing.B) {
 			requestBody := bytes.NewBuffer([]byte(batchJSONData))
 			httpRequest := httptest.NewRequest(httpMethod, testServer.URL+requestPath, requestBody)
 			responseRecorder := httptest.NewRecorder()
-			httpRequest.Header.Set("ClientID", "1")
+			httpRequest = httpRequest.WithContext(cookie.WithUserID(httpRequest.Context(), 1))
 			b.StartTimer()
 			serv.handlers.shortenerBatchHandler(responseRecorder, httpRequest)
 		}
 	})
 }
+
+// apiKeyRequest sends a request authenticated with an API key rather than a cookie.
+func apiKeyRequest(t *testing.T, ts *httptest.Server, method, path, key string, requestBody io.Reader) (*http.Response, string) {
+	req, err := http.NewRequest(method, ts.URL+path, requestBody)
+	require.NoError(t, err)
+	req.Header.Set("Authorization", "Bearer "+key)
+
+	result, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	defer result.Body.Close()
+
+	resultBody, err := io.ReadAll(result.Body)
+	require.NoError(t, err)
+	require.Empty(t, result.Cookies(), "requests with an API key get no session cookie")
+
+	return result, string(resultBody)
+}
+
+func TestAPIKeys(t *testing.T) {
+	flagConfig := config.NewFlagConfig()
+	l, err := logger.CreateLogger("fatal")
+	require.NoError(t, err)
+
+	storageMap := storage.NewStorage("", l)
+	_, err = storageMap.CreateUser(context.Background())
+	require.NoError(t, err)
+	app, err := app.NewApp(storageMap, flagConfig, l)
+	require.NoError(t, err)
+	testServer := httptest.NewServer(NewServer(app, newTestTokens(), flagConfig, l).newRouter())
+	defer testServer.Close()
+
+	result, _ := testRequest(t, testServer, http.MethodPost, "/api/user/api-keys", 1, bytes.NewBufferString(`{"name":"ci","scopes":["admin"]}`))
+	assert.Equal(t, http.StatusBadRequest, result.StatusCode)
+
+	result, resultBody := testRequest(t, testServer, http.MethodPost, "/api/user/api-keys", 1, bytes.NewBufferString(`{"name":"ci","scopes":["shorten"]}`))
+	require.Equal(t, http.StatusCreated, result.StatusCode)
+	var created models.CreatedAPIKey
+	require.NoError(t, json.Unmarshal([]byte(resultBody), &created))
+	assert.Equal(t, "ci", created.Name)
+	assert.Equal(t, []string{models.ScopeShorten}, created.Scopes)
+	assert.True(t, strings.HasPrefix(created.Key, created.Prefix))
+	assert.NotContains(t, resultBody, "hash")
+
+	result, _ = apiKeyRequest(t, testServer, http.MethodPost, "/api/shorten", created.Key, bytes.NewBufferString(`{"url":"https://example.com/release"}`))
+	assert.Equal(t, http.StatusCreated, result.StatusCode)
+	result, _ = apiKeyRequest(t, testServer, http.MethodGet, "/api/user/urls", created.Key, nil)
+	assert.Equal(t, http.StatusForbidden, result.StatusCode, "the key has no read scope")
+	result, _ = apiKeyRequest(t, testServer, http.MethodPost, "/api/user/api-keys", created.Key, bytes.NewBufferString(`{"scopes":["read"]}`))
+	assert.Equal(t, http.StatusForbidden, result.StatusCode, "keys can not issue keys")
+
+	result, resultBody = testRequest(t, testServer, http.MethodGet, "/api/user/urls", 1, nil)
+	assert.Equal(t, http.StatusOK, result.StatusCode)
+	assert.Contains(t, resultBody, "https://example.com/release", "the key acts on behalf of its user")
+
+	result, resultBody = testRequest(t, testServer, http.MethodGet, "/api/user/api-keys", 1, nil)
+	assert.Equal(t, http.StatusOK, result.StatusCode)
+	assert.Contains(t, resultBody, created.Prefix)
+	assert.NotContains(t, resultBody, created.Key)
+
+	result, _ = testRequest(t, testServer, http.MethodDelete, "/api/user/api-keys/unknown", 1, nil)
+	assert.Equal(t, http.StatusNotFound, result.StatusCode)
+	result, _ = testRequest(t, testServer, http.MethodDelete, "/api/user/api-keys/"+created.ID, 1, nil)
+	assert.Equal(t, http.StatusNoContent, result.StatusCode)
+
+	result, _ = apiKeyRequest(t, testServer, http.MethodPost, "/api/shorten", created.Key, bytes.NewBufferString(`{"url":"https://example.com/next"}`))
+	assert.Equal(t, http.StatusUnauthorized, result.StatusCode, "revoked keys are rejected")
+	result, _ = apiKeyRequest(t, testServer, http.MethodPost, "/api/shorten", "sk_unknown", bytes.NewBufferString(`{"url":"https://example.com/next"}`))
+	assert.Equal(t, http.StatusUnauthorized, result.StatusCode)
+}
+
+func TestInternalStats(t *testing.T) {
+	flagConfig := config.NewFlagConfig()
+	flagConfig.FlagTrustedSubnet = "192.168.0.0/24"
+	flagConfig.FlagTrustedProxies = "127.0.0.1/32"
+	l, err := logger.CreateLogger("fatal")
+	require.NoError(t, err)
+
+	storageMap := storage.NewStorage("", l)
+	app, err := app.NewApp(storageMap, flagConfig, l)
+	require.NoError(t, err)
+	testServer := httptest.NewServer(NewServer(app, newTestTokens(), flagConfig, l).newRouter())
+	defer testServer.Close()
+
+	internalStats := func() models.InternalStats {
+		req, err := http.NewRequest(http.MethodGet, testServer.URL+"/api/internal/stats", nil)
+		require.NoError(t, err)
+		req.Header.Set("X-Real-IP", "192.168.0.10")
+		client := &http.Client{Transport: &http.Transport{DisableCompression: true}}
+		result, err := client.Do(req)
+		require.NoError(t, err)
+		defer result.Body.Close()
+		require.Equal(t, http.StatusOK, result.StatusCode)
+
+		var stats models.InternalStats
+		require.NoError(t, json.NewDecoder(result.Body).Decode(&stats))
+		return stats
+	}
+
+	assert.Equal(t, models.InternalStats{}, internalStats(), "an empty storage has no URLs and no users")
+
+	result, _ := testRequest(t, testServer, http.MethodPost, "/", 1, bytes.NewBufferString("https://example.com/a"))
+	require.Equal(t, http.StatusCreated, result.StatusCode)
+	result, _ = testRequest(t, testServer, http.MethodPost, "/api/shorten", 2, bytes.NewBufferString(`{"url":"https://example.com/b"}`))
+	require.Equal(t, http.StatusCreated, result.StatusCode)
+	_, err = storageMap.SetValue(context.Background(), "expired", "https://example.com/expired", 3, time.Now().Add(-time.Minute))
+	require.NoError(t, err)
+
+	assert.Equal(t, models.InternalStats{URLs: 2, Users: 2}, internalStats())
+}
+
+func TestInternalStatsUntrustedRealIP(t *testing.T) {
+	flagConfig := config.NewFlagConfig()
+	flagConfig.FlagTrustedSubnet = "192.168.0.0/24"
+	l, err := logger.CreateLogger("fatal")
+	require.NoError(t, err)
+
+	app, err := app.NewApp(storage.NewStorage("", l), flagConfig, l)
+	require.NoError(t, err)
+	testServer := httptest.NewServer(NewServer(app, newTestTokens(), flagConfig, l).newRouter())
+	defer testServer.Close()
+
+	req, err := http.NewRequest(http.MethodGet, testServer.URL+"/api/internal/stats", nil)
+	require.NoError(t, err)
+	req.Header.Set("X-Real-IP", "192.168.0.10")
+	result, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	defer result.Body.Close()
+	assert.Equal(t, http.StatusForbidden, result.StatusCode, "X-Real-IP only counts from a trusted proxy")
+}
@@ -1,13 +1,19 @@
 package server
 
 import (
+	"context"
+	"errors"
+	"net"
 	"net/http"
 
 	"github.com/DariSorokina/go-first-sprint/internal/app"
 	"github.com/DariSorokina/go-first-sprint/internal/config"
 	"github.com/DariSorokina/go-first-sprint/internal/cookie"
 	"github.com/DariSorokina/go-first-sprint/internal/logger"
+	"github.com/DariSorokina/go-first-sprint/internal/metrics"
 	"github.com/DariSorokina/go-first-sprint/internal/middleware"
+	"github.com/DariSorokina/go-first-sprint/internal/models"
+	"github.com/DariSorokina/go-first-sprint/internal/tracing"
 	"github.com/go-chi/chi/v5"
 )
 
@@ -15,35 +21,121 @@ import (
 type Server struct {
 	handlers   *handlers
 	app        *app.App
+	tokens     *cookie.Tokens
+	limiter    *middleware.RateLimiter
+	proxies    []*net.IPNet
+	tls        serverTLS
 	flagConfig *config.FlagConfig
 	log        *logger.Logger
 }
 
-// NewServer creates a new Server instance with the provided application, configuration flags, and logger.
-func NewServer(app *app.App, flagConfig *config.FlagConfig, l *logger.Logger) *Server {
+// NewServer creates a new Server instance with the provided application, user tokens, configuration flags, and logger.
+func NewServer(app *app.App, tokens *cookie.Tokens, flagConfig *config.FlagConfig, l *logger.Logger) *Server {
 	handlers := newHandlers(app, flagConfig, l)
-	return &Server{handlers: handlers, app: app, flagConfig: flagConfig, log: l}
+
+	// The budgets were checked when the configuration was parsed, a config built otherwise may disable them.
+	userLimits, err := config.ParseRateLimits(flagConfig.FlagRateLimitsUser)
+	if err != nil {
+		l.Sugar().Errorf("Failed to parse user rate limits: %s", err)
+	}
+	ipLimits, err := config.ParseRateLimits(flagConfig.FlagRateLimitsIP)
+	if err != nil {
+		l.Sugar().Errorf("Failed to parse client IP rate limits: %s", err)
+	}
+	limiter := middleware.NewRateLimiter(middleware.NewMemoryRateLimitStore(), userLimits, ipLimits)
+	proxies, err := config.ParseTrustedProxies(flagConfig.FlagTrustedProxies)
+	if err != nil {
+		l.Sugar().Errorf("Failed to parse trusted proxies: %s", err)
+	}
+
+	return &Server{handlers: handlers, app: app, tokens: tokens, limiter: limiter, proxies: proxies, flagConfig: flagConfig, log: l}
+}
+
+// RateLimiter returns the rate limiter of the server, so the gRPC server can count its calls against the same budgets.
+func (server *Server) RateLimiter() *middleware.RateLimiter {
+	return server.limiter
 }
 
 func (server *Server) newRouter() chi.Router {
 	router := chi.NewRouter()
+	router.Use(tracing.Middleware)
 	router.Use(server.log.WithLogging())
+	router.Use(metrics.Middleware)
+	router.Use(middleware.ClientIPMiddleware(server.proxies))
 	router.Use(middleware.CompressorMiddleware())
 	router.Get("/ping", server.handlers.pingPostgresqlHandler)
-	router.Get("/{id}", server.handlers.originalHandler)
-	router.Route("/", func(r chi.Router) {
-		r.Use(cookie.CookieMiddleware())
-		r.Post("/", server.handlers.shortenerHandler)
-		r.Post("/api/shorten", server.handlers.shortenerHandlerJSON)
-		r.Post("/api/shorten/batch", server.handlers.shortenerBatchHandler)
-		r.Get("/api/user/urls", server.handlers.urlsByIDHandler)
-		r.Delete("/api/user/urls", server.handlers.deleteURLsHandler)
+	router.With(server.limiter.Limit(config.RateLimitRedirect, nil)).Get("/{id}", server.handlers.originalHandler)
+	router.With(middleware.TrustedSubnetMiddleware(server.flagConfig.FlagTrustedSubnet)).
+		Get("/api/internal/stats", server.handlers.internalStatsHandler)
+	router.With(server.limiter.Limit(config.RateLimitShorten, server.tokens.CookieMiddleware(server.app))).
+		Post("/", server.handlers.shortenerHandler)
+	router.Route("/api", func(r chi.Router) {
+		// Rate limited routes authenticate inside the limiter, after the client IP budget is checked.
+		// Only routes creating data register a user for a request without one.
+		authenticate := middleware.APIKeyMiddleware(server.app, server.tokens.AuthMiddleware(server.app))
+		register := middleware.APIKeyMiddleware(server.app, server.tokens.CookieMiddleware(server.app))
+		shorten := middleware.RequireScope(models.ScopeShorten)
+		r.With(server.limiter.Limit(config.RateLimitShorten, register), shorten).Post("/shorten", server.handlers.shortenerHandlerJSON)
+		r.With(server.limiter.Limit(config.RateLimitBatch, register), shorten).Post("/shorten/batch", server.handlers.shortenerBatchHandler)
+		r.Group(func(r chi.Router) {
+			r.Use(authenticate, middleware.RequireScope(models.ScopeRead))
+			r.Get("/user/urls", server.handlers.urlsByIDHandler)
+			r.Get("/user/urls/{id}/stats", server.handlers.urlStatsHandler)
+			r.Get("/user/urls/delete-jobs/{id}", server.handlers.deleteJobHandler)
+		})
+		remove := middleware.RequireScope(models.ScopeDelete)
+		r.With(server.limiter.Limit(config.RateLimitDelete, authenticate), remove).Delete("/user/urls", server.handlers.deleteURLsHandler)
+		r.With(authenticate, remove).Post("/user/urls/restore", server.handlers.restoreURLsHandler)
+		r.With(register, middleware.RequireSession()).Post("/user/api-keys", server.handlers.createAPIKeyHandler)
+		r.Group(func(r chi.Router) {
+			r.Use(authenticate, middleware.RequireSession())
+			r.Get("/user/api-keys", server.handlers.apiKeysHandler)
+			r.Delete("/user/api-keys/{id}", server.handlers.revokeAPIKeyHandler)
+		})
 	})
 	return router
 }
 
-// Run starts the server and listens for incoming HTTP requests on the specified address.
-func Run(server *Server) error {
-	server.log.Sugar().Infof("Running server on %s", server.flagConfig.FlagRunAddr)
-	return http.ListenAndServe(server.flagConfig.FlagRunAddr, server.newRouter())
+// Run starts the server and listens for incoming HTTP requests on the specified address until ctx is done.
+// It then stops accepting connections and waits, within the configured shutdown timeout,
+// for in-flight requests to finish.
+func Run(ctx context.Context, server *Server) error {
+	httpServer := &http.Server{Addr: server.flagConfig.FlagRunAddr, Handler: server.newRouter()}
+
+	if server.flagConfig.FlagEnableHTTPS {
+		var err error
+		if httpServer.TLSConfig, err = server.TLSConfig(); err != nil {
+			return err
+		}
+	}
+
+	serveErr := make(chan error, 1)
+	go func() {
+		if server.flagConfig.FlagEnableHTTPS {
+			server.log.Sugar().Infof("Running HTTPS server on %s", server.flagConfig.FlagRunAddr)
+			// The certificate is loaded into the TLS configuration already.
+			serveErr <- httpServer.ListenAndServeTLS("", "")
+			return
+		}
+		server.log.Sugar().Infof("Running server on %s", server.flagConfig.FlagRunAddr)
+		serveErr <- httpServer.ListenAndServe()
+	}()
+
+	select {
+	case err := <-serveErr:
+		return err
+	case <-ctx.Done():
+	}
+
+	server.log.Sugar().Infof("Shutting down server, timeout %s", server.flagConfig.FlagShutdownTimeout)
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), server.flagConfig.FlagShutdownTimeout)
+	defer cancel()
+
+	if err := httpServer.Shutdown(shutdownCtx); err != nil {
+		return err
+	}
+	if err := <-serveErr; !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	return nil
 }
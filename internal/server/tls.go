@@ -0,0 +1,90 @@
+package server
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"errors"
+	"math/big"
+	"net"
+	"sync"
+	"time"
+)
+
+// selfSignedCertValidity is the lifetime of a certificate generated when no certificate files are configured.
+const selfSignedCertValidity = 365 * 24 * time.Hour
+
+// serverTLS holds the TLS configuration of a server, built once so every listener serves the same certificate.
+type serverTLS struct {
+	once   sync.Once
+	config *tls.Config
+	err    error
+}
+
+// TLSConfig returns the TLS configuration for the HTTPS mode with the configured certificate and key files
+// loaded. If no files are configured, a self-signed certificate is generated in memory. The configuration is
+// built on the first call and shared by later ones, so the gRPC server can serve the same certificate.
+func (server *Server) TLSConfig() (*tls.Config, error) {
+	server.tls.once.Do(func() {
+		server.tls.config, server.tls.err = server.newTLSConfig()
+	})
+	return server.tls.config, server.tls.err
+}
+
+func (server *Server) newTLSConfig() (*tls.Config, error) {
+	certFile, keyFile := server.flagConfig.FlagTLSCertFile, server.flagConfig.FlagTLSKeyFile
+	switch {
+	case certFile != "" && keyFile != "":
+		cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+		if err != nil {
+			return nil, err
+		}
+		return &tls.Config{MinVersion: tls.VersionTLS12, Certificates: []tls.Certificate{cert}}, nil
+	case certFile != "" || keyFile != "":
+		return nil, errors.New("both TLS certificate and key files must be provided")
+	}
+
+	server.log.Info("No TLS certificate provided, generating a self-signed one")
+	cert, err := selfSignedCertificate()
+	if err != nil {
+		return nil, err
+	}
+	return &tls.Config{MinVersion: tls.VersionTLS12, Certificates: []tls.Certificate{cert}}, nil
+}
+
+// selfSignedCertificate generates an ECDSA P-256 certificate for localhost.
+func selfSignedCertificate() (tls.Certificate, error) {
+	privateKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return tls.Certificate{}, err
+	}
+
+	serialNumber, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
+	if err != nil {
+		return tls.Certificate{}, err
+	}
+
+	template := &x509.Certificate{
+		SerialNumber: serialNumber,
+		Subject: pkix.Name{
+			Organization: []string{"go-first-sprint shortener"},
+		},
+		NotBefore:             time.Now().Add(-time.Minute),
+		NotAfter:              time.Now().Add(selfSignedCertValidity),
+		KeyUsage:              x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		BasicConstraintsValid: true,
+		DNSNames:              []string{"localhost"},
+		IPAddresses:           []net.IP{net.IPv4(127, 0, 0, 1), net.IPv6loopback},
+	}
+
+	certDER, err := x509.CreateCertificate(rand.Reader, template, template, &privateKey.PublicKey, privateKey)
+	if err != nil {
+		return tls.Certificate{}, err
+	}
+
+	return tls.Certificate{Certificate: [][]byte{certDER}, PrivateKey: privateKey}, nil
+}
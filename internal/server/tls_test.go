@@ -0,0 +1,94 @@
+package server
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/pem"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/DariSorokina/go-first-sprint/internal/config"
+	"github.com/DariSorokina/go-first-sprint/internal/logger"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTLSTestServer(t *testing.T, certFile, keyFile string) *Server {
+	l, err := logger.CreateLogger("fatal")
+	require.NoError(t, err)
+
+	flagConfig := config.NewFlagConfig()
+	flagConfig.FlagTLSCertFile = certFile
+	flagConfig.FlagTLSKeyFile = keyFile
+	return &Server{flagConfig: flagConfig, log: l}
+}
+
+func TestTLSConfigSelfSigned(t *testing.T) {
+	server := newTLSTestServer(t, "", "")
+	tlsConfig, err := server.TLSConfig()
+	require.NoError(t, err)
+	require.Len(t, tlsConfig.Certificates, 1)
+
+	shared, err := server.TLSConfig()
+	require.NoError(t, err)
+	assert.Same(t, tlsConfig, shared, "every listener serves the same generated certificate")
+
+	cert, err := x509.ParseCertificate(tlsConfig.Certificates[0].Certificate[0])
+	require.NoError(t, err)
+	assert.NoError(t, cert.VerifyHostname("localhost"))
+
+	testServer := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	testServer.TLS = tlsConfig
+	testServer.StartTLS()
+	defer testServer.Close()
+
+	roots := x509.NewCertPool()
+	roots.AddCert(cert)
+	client := &http.Client{Transport: &http.Transport{TLSClientConfig: &tls.Config{RootCAs: roots}}}
+	result, err := client.Get(testServer.URL)
+	require.NoError(t, err, "the generated certificate is served and verifies for the loopback address")
+	defer result.Body.Close()
+	assert.Equal(t, http.StatusNoContent, result.StatusCode)
+}
+
+func TestTLSConfigFiles(t *testing.T) {
+	cert, err := selfSignedCertificate()
+	require.NoError(t, err)
+	privateKey, err := x509.MarshalPKCS8PrivateKey(cert.PrivateKey)
+	require.NoError(t, err)
+
+	dir := t.TempDir()
+	certFile, keyFile := filepath.Join(dir, "cert.pem"), filepath.Join(dir, "key.pem")
+	require.NoError(t, os.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: cert.Certificate[0]}), 0600))
+	require.NoError(t, os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: privateKey}), 0600))
+
+	testCases := []struct {
+		name     string
+		certFile string
+		keyFile  string
+		wantErr  bool
+	}{
+		{name: "certificate and key", certFile: certFile, keyFile: keyFile},
+		{name: "certificate only", certFile: certFile, wantErr: true},
+		{name: "key only", keyFile: keyFile, wantErr: true},
+		{name: "missing files", certFile: filepath.Join(dir, "missing.pem"), keyFile: keyFile, wantErr: true},
+	}
+	for _, test := range testCases {
+		t.Run(test.name, func(t *testing.T) {
+			tlsConfig, err := newTLSTestServer(t, test.certFile, test.keyFile).TLSConfig()
+			if test.wantErr {
+				assert.Error(t, err)
+				assert.Nil(t, tlsConfig)
+				return
+			}
+			require.NoError(t, err)
+			require.Len(t, tlsConfig.Certificates, 1)
+			assert.Equal(t, cert.Certificate[0], tlsConfig.Certificates[0].Certificate[0], "the configured certificate is loaded")
+		})
+	}
+}
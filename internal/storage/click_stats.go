@@ -0,0 +1,64 @@
+// Package storage provides primitives for connecting to data storages.
+package storage
+
+import (
+	"sort"
+
+	"github.com/DariSorokina/go-first-sprint/internal/models"
+)
+
+// topReferrersLimit is the number of referrers returned in link statistics.
+const topReferrersLimit = 10
+
+// dateLayout is the format of days in the daily clicks time series.
+const dateLayout = "2006-01-02"
+
+// clickStats holds aggregated clicks on a short URL kept by the map storage.
+type clickStats struct {
+	total     int
+	daily     map[string]int
+	referrers map[string]int
+}
+
+func newClickStats() *clickStats {
+	return &clickStats{daily: make(map[string]int), referrers: make(map[string]int)}
+}
+
+func (stats *clickStats) add(click models.ClickEvent) {
+	stats.total++
+	stats.daily[click.ClickedAt.UTC().Format(dateLayout)]++
+	if click.Referrer != "" {
+		stats.referrers[click.Referrer]++
+	}
+}
+
+func (stats *clickStats) linkStats(shortURL string) models.LinkStats {
+	linkStats := models.LinkStats{
+		ShortURL:     shortURL,
+		Total:        stats.total,
+		Daily:        []models.DailyClicks{},
+		TopReferrers: []models.ReferrerClicks{},
+	}
+
+	for date, clicks := range stats.daily {
+		linkStats.Daily = append(linkStats.Daily, models.DailyClicks{Date: date, Clicks: clicks})
+	}
+	sort.Slice(linkStats.Daily, func(i, j int) bool {
+		return linkStats.Daily[i].Date < linkStats.Daily[j].Date
+	})
+
+	for referrer, clicks := range stats.referrers {
+		linkStats.TopReferrers = append(linkStats.TopReferrers, models.ReferrerClicks{Referrer: referrer, Clicks: clicks})
+	}
+	sort.Slice(linkStats.TopReferrers, func(i, j int) bool {
+		if linkStats.TopReferrers[i].Clicks != linkStats.TopReferrers[j].Clicks {
+			return linkStats.TopReferrers[i].Clicks > linkStats.TopReferrers[j].Clicks
+		}
+		return linkStats.TopReferrers[i].Referrer < linkStats.TopReferrers[j].Referrer
+	})
+	if len(linkStats.TopReferrers) > topReferrersLimit {
+		linkStats.TopReferrers = linkStats.TopReferrers[:topReferrersLimit]
+	}
+
+	return linkStats
+}
@@ -0,0 +1,69 @@
+// Package storage provides primitives for connecting to data storages.
+package storage
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Error categories of the storage. Every error returned by a Database method matches one of them
+// with errors.Is, so callers can tell a missing record from a storage outage.
+var (
+	// ErrNotFound indicates that the requested record does not exist.
+	ErrNotFound = errors.New("not found")
+	// ErrConflict indicates that the record clashes with an existing one.
+	ErrConflict = errors.New("conflict")
+	// ErrDeleted indicates that the requested record was deleted.
+	ErrDeleted = errors.New("deleted")
+	// ErrExpired indicates that the requested record has expired.
+	ErrExpired = errors.New("expired")
+	// ErrUnavailable indicates that the storage could not be reached or failed to execute the operation.
+	ErrUnavailable = errors.New("storage unavailable")
+)
+
+// ErrShortURLAlreadyExist indicates that a corresponding short URL already exists.
+var ErrShortURLAlreadyExist = newError(ErrConflict, "corresponding short URL already exists")
+
+// ErrShortURLTaken indicates that the short URL is already used for another original URL.
+var ErrShortURLTaken = newError(ErrConflict, "short URL is already taken")
+
+// ErrAliasAlreadyExist indicates that the requested alias is already used as a short URL.
+var ErrAliasAlreadyExist = newError(ErrConflict, "requested alias already exists")
+
+// ErrURLNotFound indicates that the requested short URL does not exist or is not owned by the user.
+var ErrURLNotFound = newError(ErrNotFound, "requested url was not found")
+
+// ErrAPIKeyNotFound indicates that the requested API key does not exist or is not owned by the user.
+var ErrAPIKeyNotFound = newError(ErrNotFound, "requested API key was not found")
+
+// ErrDeletedURL indicates that requested url was deleted.
+var ErrDeletedURL = newError(ErrDeleted, "requested url was deleted")
+
+// ErrExpiredURL indicates that requested url has expired.
+var ErrExpiredURL = newError(ErrExpired, "requested url has expired")
+
+// categoryError is a specific storage error that belongs to one of the error categories.
+type categoryError struct {
+	message  string
+	category error
+}
+
+func newError(category error, message string) error {
+	return &categoryError{message: message, category: category}
+}
+
+func (err *categoryError) Error() string {
+	return err.message
+}
+
+func (err *categoryError) Unwrap() error {
+	return err.category
+}
+
+// unavailable wraps an error of the underlying database into ErrUnavailable, keeping nil as is.
+func unavailable(err error) error {
+	if err == nil {
+		return nil
+	}
+	return fmt.Errorf("%w: %w", ErrUnavailable, err)
+}
@@ -2,10 +2,13 @@
 package storage
 
 import (
+	"bytes"
 	"encoding/json"
 	"os"
+	"time"
 
 	"github.com/DariSorokina/go-first-sprint/internal/logger"
+	"github.com/DariSorokina/go-first-sprint/internal/models"
 )
 
 type fileStorage struct {
@@ -35,13 +38,40 @@ func newFileStorage(fileName string, l *logger.Logger) *fileStorage {
 }
 
 type fileLine struct {
-	ShortURL    string `json:"short_url"`
-	OriginalURL string `json:"original_url"`
+	ShortURL    string      `json:"short_url"`
+	OriginalURL string      `json:"original_url"`
+	UserID      int         `json:"user_id"`
+	DeletedFlag bool        `json:"is_deleted"`
+	DeletedAt   *time.Time  `json:"deleted_at,omitempty"`
+	ExpiresAt   *time.Time  `json:"expires_at,omitempty"`
+	Alias       bool        `json:"is_alias,omitempty"`
+	User        bool        `json:"is_user,omitempty"`   // The line only records an issued user ID.
+	Purged      bool        `json:"is_purged,omitempty"` // The line only records that the short URL was removed.
+	APIKey      *apiKeyLine `json:"api_key,omitempty"`   // The line only records the state of an API key.
+	Click       *clickLine  `json:"click,omitempty"`     // The line only records a click on the short URL.
+}
+
+// apiKeyLine is the state of an API key written to the file storage on creation and on revocation.
+type apiKeyLine struct {
+	ID        string     `json:"id"`
+	Name      string     `json:"name"`
+	Scopes    []string   `json:"scopes"`
+	Prefix    string     `json:"prefix"`
+	Hash      string     `json:"hash"`
+	CreatedAt time.Time  `json:"created_at"`
+	RevokedAt *time.Time `json:"revoked_at,omitempty"`
+}
+
+// clickLine is a redirect through a short URL written to the file storage.
+type clickLine struct {
+	ClickedAt time.Time `json:"clicked_at"`
+	Referrer  string    `json:"referrer,omitempty"`
+	UserAgent string    `json:"user_agent,omitempty"`
+	IPHash    string    `json:"ip_hash,omitempty"`
 }
 
 type producer struct {
-	file    *os.File
-	encoder *json.Encoder
+	file *os.File
 }
 
 func newProducer(filename string) (*producer, error) {
@@ -51,16 +81,31 @@ func newProducer(filename string) (*producer, error) {
 	}
 
 	return &producer{
-		file:    file,
-		encoder: json.NewEncoder(file),
+		file: file,
 	}, nil
 }
 
-func (p *producer) writeURL(url *fileLine) error {
-	return p.encoder.Encode(&url)
+// writeURLs encodes the lines into a buffer and appends them to the file with a single write.
+func (p *producer) writeURLs(urls []*fileLine) error {
+	var buf bytes.Buffer
+	encoder := json.NewEncoder(&buf)
+	for _, url := range urls {
+		if err := encoder.Encode(url); err != nil {
+			return err
+		}
+	}
+	_, err := p.file.Write(buf.Bytes())
+	return err
+}
+
+func (p *producer) sync() error {
+	return p.file.Sync()
 }
 
 func (p *producer) close() error {
+	if err := p.file.Sync(); err != nil {
+		return err
+	}
 	return p.file.Close()
 }
 
@@ -81,7 +126,14 @@ func newConsumer(filename string) (*consumer, error) {
 	}, nil
 }
 
+// readURLs reads all lines of the file. Deleted lines written before deletion times were recorded
+// take the modification time of the file, so their grace period does not restart on every load.
 func (c *consumer) readURLs() ([]*fileLine, error) {
+	info, err := c.file.Stat()
+	if err != nil {
+		return nil, err
+	}
+	modTime := info.ModTime()
 
 	var urls []*fileLine
 	for c.decoder.More() {
@@ -89,6 +141,9 @@ func (c *consumer) readURLs() ([]*fileLine, error) {
 		if err := c.decoder.Decode(&url); err != nil {
 			return nil, err
 		}
+		if url.DeletedFlag && url.DeletedAt == nil {
+			url.DeletedAt = &modTime
+		}
 		urls = append(urls, url)
 	}
 	return urls, nil
@@ -98,10 +153,71 @@ func (c *consumer) close() error {
 	return c.file.Close()
 }
 
-func addURLsToMap(urls []*fileLine, originalToShort, shortToOriginal map[string]string) (map[string]string, map[string]string) {
+// addURLsToMap applies file lines to the storage maps in order, so a later line for the same short URL
+// (for example, the one written on deletion) overrides an earlier one. Aliases are not used to look up
+// the short URL of an original URL. Owners of short URLs are registered as users too, so files written
+// before user lines existed keep their users. A later line for the same API key replaces its state,
+// click lines add up to the statistics of their short URL, and a purge line removes the short URL.
+func (storage *Storage) addURLsToMap(urls []*fileLine) {
 	for _, url := range urls {
-		originalToShort[url.OriginalURL] = url.ShortURL
-		shortToOriginal[url.ShortURL] = url.OriginalURL
+		if url.Purged {
+			storage.forget(url.ShortURL)
+			continue
+		}
+		if url.Click != nil {
+			stats, ok := storage.shortToClicks[url.ShortURL]
+			if !ok {
+				stats = newClickStats()
+				storage.shortToClicks[url.ShortURL] = stats
+			}
+			stats.add(models.ClickEvent{
+				ShortURL:  url.ShortURL,
+				ClickedAt: url.Click.ClickedAt,
+				Referrer:  url.Click.Referrer,
+				UserAgent: url.Click.UserAgent,
+				IPHash:    url.Click.IPHash,
+			})
+			continue
+		}
+		if url.APIKey != nil {
+			key := models.APIKey{
+				ID:        url.APIKey.ID,
+				UserID:    url.UserID,
+				Name:      url.APIKey.Name,
+				Scopes:    url.APIKey.Scopes,
+				Prefix:    url.APIKey.Prefix,
+				Hash:      url.APIKey.Hash,
+				CreatedAt: url.APIKey.CreatedAt,
+				RevokedAt: url.APIKey.RevokedAt,
+			}
+			storage.apiKeys[key.ID] = key
+			storage.apiKeyIDsByHash[key.Hash] = key.ID
+			continue
+		}
+		if url.UserID > 0 {
+			storage.users[url.UserID] = struct{}{}
+			storage.lastUserID = max(storage.lastUserID, url.UserID)
+		}
+		if url.User {
+			continue
+		}
+
+		if url.Alias {
+			storage.aliasShortURLs[url.ShortURL] = true
+		} else {
+			storage.originalToShort[url.OriginalURL] = url.ShortURL
+		}
+		storage.shortToOriginal[url.ShortURL] = url.OriginalURL
+		storage.shortToUserID[url.ShortURL] = url.UserID
+		if url.ExpiresAt != nil {
+			storage.shortToExpiresAt[url.ShortURL] = *url.ExpiresAt
+		} else {
+			delete(storage.shortToExpiresAt, url.ShortURL)
+		}
+		if url.DeletedFlag && url.DeletedAt != nil {
+			storage.deletedShortURLs[url.ShortURL] = *url.DeletedAt
+		} else {
+			delete(storage.deletedShortURLs, url.ShortURL)
+		}
 	}
-	return originalToShort, shortToOriginal
 }
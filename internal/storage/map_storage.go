@@ -3,8 +3,11 @@ package storage
 
 import (
 	"context"
+	"errors"
 	"log"
+	"sort"
 	"sync"
+	"time"
 
 	"github.com/DariSorokina/go-first-sprint/internal/logger"
 	"github.com/DariSorokina/go-first-sprint/internal/models"
@@ -13,82 +16,302 @@ import (
 // Storage represents a storage structure for managing file storage, mappings between original and short URLs,
 // synchronization with a mutex, and logging functionality.
 type Storage struct {
-	fileStorage     *fileStorage      // File storage instance.
-	originalToShort map[string]string // Mapping of original URLs to short URLs.
-	shortToOriginal map[string]string // Mapping of short URLs to original URLs.
-	mutex           sync.RWMutex      // Mutex for synchronization.
-	log             *logger.Logger    // Logger for recording events and errors.
+	fileStorage      *fileStorage             // File storage instance.
+	originalToShort  map[string]string        // Mapping of original URLs to their generated short URLs.
+	shortToOriginal  map[string]string        // Mapping of short URLs to original URLs.
+	shortToUserID    map[string]int           // Mapping of short URLs to the ID of the user who created them.
+	shortToExpiresAt map[string]time.Time     // Mapping of short URLs to their expiration time, if any.
+	deletedShortURLs map[string]time.Time     // Mapping of short URLs marked as deleted to their deletion time.
+	aliasShortURLs   map[string]bool          // Set of short URLs chosen by users rather than generated.
+	shortToClicks    map[string]*clickStats   // Aggregated clicks per short URL.
+	users            map[int]struct{}         // Set of issued user IDs.
+	lastUserID       int                      // Largest issued user ID.
+	apiKeys          map[string]models.APIKey // Mapping of API key IDs to the keys.
+	apiKeyIDsByHash  map[string]string        // Mapping of API key hashes to their IDs.
+	mutex            sync.RWMutex             // Mutex for synchronization.
+	log              *logger.Logger           // Logger for recording events and errors.
 }
 
 // NewStorage creates a new Storage instance with the provided file name and logger.
+// If fileName is empty, the storage keeps its data in memory only.
 func NewStorage(fileName string, l *logger.Logger) *Storage {
-	fileStorage := newFileStorage(fileName, l)
-	if fileName != "" {
-		var url = []*fileLine{
-			{
-				ShortURL:    "d41d8cd98f",
-				OriginalURL: "https://practicum.yandex.ru/",
-			},
-		}
+	storage := &Storage{
+		originalToShort:  make(map[string]string),
+		shortToOriginal:  make(map[string]string),
+		shortToUserID:    make(map[string]int),
+		shortToExpiresAt: make(map[string]time.Time),
+		deletedShortURLs: make(map[string]time.Time),
+		aliasShortURLs:   make(map[string]bool),
+		shortToClicks:    make(map[string]*clickStats),
+		users:            make(map[int]struct{}),
+		apiKeys:          make(map[string]models.APIKey),
+		apiKeyIDsByHash:  make(map[string]string),
+		log:              l,
+	}
+
+	// Default mapping available in every storage.
+	storage.addURLsToMap([]*fileLine{
+		{
+			ShortURL:    "d41d8cd98f",
+			OriginalURL: "https://practicum.yandex.ru/",
+		},
+	})
 
-		readURLs, err := fileStorage.consumer.readURLs()
+	if fileName == "" {
+		return storage
+	}
+
+	storage.fileStorage = newFileStorage(fileName, l)
+	if storage.fileStorage.consumer != nil {
+		readURLs, err := storage.fileStorage.consumer.readURLs()
 		if err != nil {
 			log.Println(err)
 		}
-		obtainedUrls := append(url, readURLs...)
+		storage.addURLsToMap(readURLs)
+	}
+
+	return storage
+}
 
-		originalToShort := make(map[string]string)
-		shortToOriginal := make(map[string]string)
-		originalToShort, shortToOriginal = addURLsToMap(obtainedUrls, originalToShort, shortToOriginal)
+// writeToFile appends lines to the file storage if it is in use.
+func (storage *Storage) writeToFile(urls []*fileLine) error {
+	if storage.fileStorage == nil || storage.fileStorage.producer == nil || len(urls) == 0 {
+		return nil
+	}
+	if err := storage.fileStorage.producer.writeURLs(urls); err != nil {
+		storage.log.Sugar().Errorf("Failed to write urls to file storage: %s", err)
+		return unavailable(err)
+	}
+	return nil
+}
+
+// syncFile flushes the file storage to disk if it is in use.
+func (storage *Storage) syncFile() error {
+	if storage.fileStorage == nil || storage.fileStorage.producer == nil {
+		return nil
+	}
+	if err := storage.fileStorage.producer.sync(); err != nil {
+		storage.log.Sugar().Errorf("Failed to sync file storage: %s", err)
+		return unavailable(err)
+	}
+	return nil
+}
+
+// CreateUser issues the next user ID and writes it to the file storage.
+func (storage *Storage) CreateUser(ctx context.Context) (userID int, err error) {
+	storage.mutex.Lock()
+	defer storage.mutex.Unlock()
 
-		return &Storage{
-			fileStorage:     fileStorage,
-			originalToShort: originalToShort,
-			shortToOriginal: shortToOriginal,
-			log:             l,
+	var user = []*fileLine{{UserID: storage.lastUserID + 1, User: true}}
+	if err = storage.writeToFile(user); err != nil {
+		return 0, err
+	}
+	storage.addURLsToMap(user)
+	return storage.lastUserID, nil
+}
+
+// UserExists reports whether the user ID was issued by CreateUser or owns short URLs in the map storage.
+func (storage *Storage) UserExists(ctx context.Context, userID int) (exists bool, err error) {
+	storage.mutex.RLock()
+	defer storage.mutex.RUnlock()
+
+	_, exists = storage.users[userID]
+	return exists, nil
+}
+
+// CreateAPIKey stores the API key and writes it to the file storage.
+func (storage *Storage) CreateAPIKey(ctx context.Context, key models.APIKey) error {
+	storage.mutex.Lock()
+	defer storage.mutex.Unlock()
+
+	var keys = []*fileLine{apiKeyFileLine(key)}
+	if err := storage.writeToFile(keys); err != nil {
+		return err
+	}
+	storage.addURLsToMap(keys)
+	return nil
+}
+
+// GetAPIKeyByHash retrieves the API key with the given hash, including a revoked one.
+// It returns ErrAPIKeyNotFound if there is no such key.
+func (storage *Storage) GetAPIKeyByHash(ctx context.Context, hash string) (key models.APIKey, err error) {
+	storage.mutex.RLock()
+	defer storage.mutex.RUnlock()
+
+	id, ok := storage.apiKeyIDsByHash[hash]
+	if !ok {
+		return models.APIKey{}, ErrAPIKeyNotFound
+	}
+	return storage.apiKeys[id], nil
+}
+
+// ListAPIKeys retrieves the API keys of the user, including revoked ones, in the order they were created.
+func (storage *Storage) ListAPIKeys(ctx context.Context, userID int) (keys []models.APIKey, err error) {
+	storage.mutex.RLock()
+	defer storage.mutex.RUnlock()
+
+	for _, key := range storage.apiKeys {
+		if key.UserID == userID {
+			keys = append(keys, key)
+		}
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if !keys[i].CreatedAt.Equal(keys[j].CreatedAt) {
+			return keys[i].CreatedAt.Before(keys[j].CreatedAt)
 		}
+		return keys[i].ID < keys[j].ID
+	})
+	return keys, nil
+}
+
+// RevokeAPIKey marks the user's API key as revoked at revokedAt, keeping the time of an earlier revocation.
+// It returns ErrAPIKeyNotFound if the key does not exist or is owned by another user.
+func (storage *Storage) RevokeAPIKey(ctx context.Context, id string, userID int, revokedAt time.Time) error {
+	storage.mutex.Lock()
+	defer storage.mutex.Unlock()
+
+	key, ok := storage.apiKeys[id]
+	if !ok || key.UserID != userID {
+		return ErrAPIKeyNotFound
+	}
+	if key.RevokedAt != nil {
+		return nil
 	}
 
-	// Default mappings if fileName is empty.
-	return &Storage{
-		originalToShort: map[string]string{"https://practicum.yandex.ru/": "d41d8cd98f"},
-		shortToOriginal: map[string]string{"d41d8cd98f": "https://practicum.yandex.ru/"},
-		log:             l,
+	key.RevokedAt = &revokedAt
+	var keys = []*fileLine{apiKeyFileLine(key)}
+	if err := storage.writeToFile(keys); err != nil {
+		return err
 	}
+	storage.addURLsToMap(keys)
+	return nil
 }
 
-// SetValue sets a value in the map storage for a given shortURL, longURL, and userID.
-func (storage *Storage) SetValue(ctx context.Context, shortURL, longURL string, userID int) {
+// apiKeyFileLine returns the file line recording the state of the API key.
+func apiKeyFileLine(key models.APIKey) *fileLine {
+	return &fileLine{
+		UserID: key.UserID,
+		APIKey: &apiKeyLine{
+			ID:        key.ID,
+			Name:      key.Name,
+			Scopes:    key.Scopes,
+			Prefix:    key.Prefix,
+			Hash:      key.Hash,
+			CreatedAt: key.CreatedAt,
+			RevokedAt: key.RevokedAt,
+		},
+	}
+}
+
+// SetValue stores longURL under shortURL in one step under the mutex.
+// If longURL is already shortened and the short URL has not expired, it returns the existing short URL and
+// ErrShortURLAlreadyExist; if shortURL is used for another URL, it returns ErrShortURLTaken.
+// A zero expiresAt means the short URL never expires.
+func (storage *Storage) SetValue(ctx context.Context, shortURL, longURL string, userID int, expiresAt time.Time) (storedShortURL string, err error) {
 	storage.mutex.Lock()
 	defer storage.mutex.Unlock()
 
+	if existing, ok := storage.originalToShort[longURL]; ok && !storage.expired(existing, time.Now()) {
+		return existing, ErrShortURLAlreadyExist
+	}
+	if _, ok := storage.shortToOriginal[shortURL]; ok {
+		return "", ErrShortURLTaken
+	}
+
 	var url = []*fileLine{
 		{
 			ShortURL:    shortURL,
 			OriginalURL: longURL,
+			UserID:      userID,
+			ExpiresAt:   expiresAtPointer(expiresAt),
 		},
 	}
 
-	if storage.fileStorage.fileName != "" {
-		err := storage.fileStorage.producer.writeURL(url[0])
-		if err != nil {
-			log.Println(err)
+	if err = storage.writeToFile(url); err != nil {
+		return "", err
+	}
+	storage.addURLsToMap(url)
+	return shortURL, nil
+}
+
+// SetValues stores a batch of URLs for the user at once: either all new URLs are stored, written to the file
+// and synced with a single write, or none are. Items whose original URL is already shortened under a short URL
+// that has not expired, also earlier in the same batch, get the existing short URL and Conflict set. If a proposed short URL is used for
+// another URL, nothing is stored and ErrShortURLTaken is returned.
+func (storage *Storage) SetValues(ctx context.Context, urls []models.BatchURL, userID int) error {
+	storage.mutex.Lock()
+	defer storage.mutex.Unlock()
+
+	now := time.Now()
+	batchOriginalToShort := make(map[string]string, len(urls))
+	batchShortURLs := make(map[string]bool, len(urls))
+	var lines []*fileLine
+	for i := range urls {
+		url := &urls[i]
+		existing, ok := storage.originalToShort[url.OriginalURL]
+		if ok && storage.expired(existing, now) {
+			ok = false
+		}
+		if !ok {
+			existing, ok = batchOriginalToShort[url.OriginalURL]
+		}
+		if ok {
+			url.ShortURL, url.Conflict = existing, true
+			continue
+		}
+
+		if _, ok := storage.shortToOriginal[url.ShortURL]; ok || batchShortURLs[url.ShortURL] {
+			return ErrShortURLTaken
 		}
+		batchOriginalToShort[url.OriginalURL] = url.ShortURL
+		batchShortURLs[url.ShortURL] = true
+
+		lines = append(lines, &fileLine{
+			ShortURL:    url.ShortURL,
+			OriginalURL: url.OriginalURL,
+			UserID:      userID,
+			ExpiresAt:   expiresAtPointer(url.ExpiresAt),
+		})
 	}
 
-	storage.originalToShort, storage.shortToOriginal = addURLsToMap(url, storage.originalToShort, storage.shortToOriginal)
+	if len(lines) == 0 {
+		return nil
+	}
+	if err := storage.writeToFile(lines); err != nil {
+		return err
+	}
+	if err := storage.syncFile(); err != nil {
+		return err
+	}
+	storage.addURLsToMap(lines)
+	return nil
 }
 
-// GetShort retrieves the short URL corresponding to a given long URL from the map storage.
-func (storage *Storage) GetShort(ctx context.Context, longURL string) (shortURL string, err error) {
-	storage.mutex.RLock()
-	defer storage.mutex.RUnlock()
+// SetAlias stores longURL under the alias unless the alias is already taken,
+// in which case it returns the ID of the user owning the alias and ErrAliasAlreadyExist.
+func (storage *Storage) SetAlias(ctx context.Context, alias, longURL string, userID int, expiresAt time.Time) (ownerID int, err error) {
+	storage.mutex.Lock()
+	defer storage.mutex.Unlock()
+
+	if _, ok := storage.shortToOriginal[alias]; ok {
+		return storage.shortToUserID[alias], ErrAliasAlreadyExist
+	}
+
+	var url = []*fileLine{
+		{
+			ShortURL:    alias,
+			OriginalURL: longURL,
+			UserID:      userID,
+			ExpiresAt:   expiresAtPointer(expiresAt),
+			Alias:       true,
+		},
+	}
 
-	if value, ok := storage.originalToShort[longURL]; ok {
-		shortURL = value
-		return shortURL, ErrShortURLAlreadyExist
+	if err = storage.writeToFile(url); err != nil {
+		return 0, err
 	}
-	return "", nil
+	storage.addURLsToMap(url)
+	return userID, nil
 }
 
 // GetOriginal retrieves the original long URL corresponding to a given short URL from the map storage.
@@ -96,20 +319,270 @@ func (storage *Storage) GetOriginal(ctx context.Context, shortURL string) (longU
 	storage.mutex.RLock()
 	defer storage.mutex.RUnlock()
 
+	if _, ok := storage.deletedShortURLs[shortURL]; ok {
+		return "", ErrDeletedURL
+	}
+
+	if storage.expired(shortURL, time.Now()) {
+		return "", ErrExpiredURL
+	}
+
 	if value, ok := storage.shortToOriginal[shortURL]; ok {
 		longURL = value
 		return
 	}
-	return "", nil
+	return "", ErrURLNotFound
 }
 
-// GetURLsByUserID retrieves URLs associated with a given user ID from the mp storage.
-func (storage *Storage) GetURLsByUserID(ctx context.Context, userID int) (urls []models.URLPair) {
+// GetURLsByUserID retrieves URLs associated with a given user ID from the map storage.
+func (storage *Storage) GetURLsByUserID(ctx context.Context, userID int) (urls []models.URLPair, err error) {
+	storage.mutex.RLock()
+	defer storage.mutex.RUnlock()
+
+	for shortURL, ownerID := range storage.shortToUserID {
+		if ownerID != userID {
+			continue
+		}
+		urls = append(urls, models.URLPair{ShortenURL: shortURL, OriginalURL: storage.shortToOriginal[shortURL]})
+	}
 	return
 }
 
-// DeleteURLsWorker updates the delete flag for a set of short URLs associated with a user ID.
-func (storage *Storage) DeleteURLsWorker(shortURLs []string, userID int) {
+// DeleteURLs sets the delete flag for the short URLs of every deletion request that are owned by its user.
+// It returns, for every request, the deletion result of each of its short URLs, such as models.DeletionNotOwned.
+func (storage *Storage) DeleteURLs(ctx context.Context, deletions []models.URLsClientID) (results []map[string]string, err error) {
+	storage.mutex.Lock()
+	defer storage.mutex.Unlock()
+
+	var deletedURLs []*fileLine
+	deleting := make(map[string]bool)
+	now := time.Now()
+	results = make([]map[string]string, len(deletions))
+	for i, deletion := range deletions {
+		results[i] = make(map[string]string, len(deletion.URLs))
+		for _, shortURL := range deletion.URLs {
+			ownerID, ok := storage.shortToUserID[shortURL]
+			switch {
+			case !ok:
+				results[i][shortURL] = models.DeletionNotFound
+			case ownerID != deletion.ClientID:
+				results[i][shortURL] = models.DeletionNotOwned
+			case deleting[shortURL]:
+				results[i][shortURL] = models.DeletionDeleted
+			default:
+				if _, ok := storage.deletedShortURLs[shortURL]; ok {
+					results[i][shortURL] = models.DeletionAlreadyDeleted
+					continue
+				}
+				results[i][shortURL] = models.DeletionDeleted
+				deleting[shortURL] = true
+				line := storage.currentLine(shortURL)
+				line.DeletedFlag, line.DeletedAt = true, &now
+				deletedURLs = append(deletedURLs, line)
+			}
+		}
+	}
+
+	if err = storage.writeToFile(deletedURLs); err != nil {
+		return nil, err
+	}
+	storage.addURLsToMap(deletedURLs)
+	return results, nil
+}
+
+// RestoreURLs clears the delete flag of the user's short URLs deleted after deletedAfter.
+// It returns the restoration result of each short URL, such as models.RestorationGraceExpired.
+func (storage *Storage) RestoreURLs(ctx context.Context, shortURLs []string, userID int, deletedAfter time.Time) (results map[string]string, err error) {
+	storage.mutex.Lock()
+	defer storage.mutex.Unlock()
+
+	var restoredURLs []*fileLine
+	results = make(map[string]string, len(shortURLs))
+	for _, shortURL := range shortURLs {
+		ownerID, ok := storage.shortToUserID[shortURL]
+		if !ok {
+			results[shortURL] = models.RestorationNotFound
+			continue
+		}
+		if ownerID != userID {
+			results[shortURL] = models.RestorationNotOwned
+			continue
+		}
+		if results[shortURL] == models.RestorationRestored {
+			continue
+		}
+
+		deletedAt, ok := storage.deletedShortURLs[shortURL]
+		switch {
+		case !ok:
+			results[shortURL] = models.RestorationNotDeleted
+		case !deletedAt.After(deletedAfter):
+			results[shortURL] = models.RestorationGraceExpired
+		default:
+			results[shortURL] = models.RestorationRestored
+			restoredURLs = append(restoredURLs, storage.currentLine(shortURL))
+		}
+	}
+
+	if err = storage.writeToFile(restoredURLs); err != nil {
+		return nil, err
+	}
+	storage.addURLsToMap(restoredURLs)
+	return results, nil
+}
+
+// PurgeDeleted removes short URLs deleted before deletedBefore from the map storage.
+// The file storage is append-only, so a purge line is appended for each of them to keep them removed
+// after a restart.
+func (storage *Storage) PurgeDeleted(ctx context.Context, deletedBefore time.Time) (purged int, err error) {
+	storage.mutex.Lock()
+	defer storage.mutex.Unlock()
+
+	var purgedURLs []*fileLine
+	for shortURL, deletedAt := range storage.deletedShortURLs {
+		if deletedAt.Before(deletedBefore) {
+			purgedURLs = append(purgedURLs, &fileLine{ShortURL: shortURL, Purged: true})
+		}
+	}
+	return storage.purge(purgedURLs)
+}
+
+// DeleteExpired removes short URLs that expired before expiredBefore from the map storage.
+// The file storage is append-only, so a purge line is appended for each of them to keep them removed
+// after a restart.
+func (storage *Storage) DeleteExpired(ctx context.Context, expiredBefore time.Time) (deleted int, err error) {
+	storage.mutex.Lock()
+	defer storage.mutex.Unlock()
+
+	var expiredURLs []*fileLine
+	for shortURL, expiresAt := range storage.shortToExpiresAt {
+		if !expiredBefore.Before(expiresAt) {
+			expiredURLs = append(expiredURLs, &fileLine{ShortURL: shortURL, Purged: true})
+		}
+	}
+	return storage.purge(expiredURLs)
+}
+
+// purge writes the purge lines to the file storage and then applies them to the maps.
+// The caller must hold the write lock.
+func (storage *Storage) purge(purgedURLs []*fileLine) (purged int, err error) {
+	if err = storage.writeToFile(purgedURLs); err != nil {
+		return 0, err
+	}
+	storage.addURLsToMap(purgedURLs)
+	return len(purgedURLs), nil
+}
+
+// forget removes every trace of the short URL from the maps.
+func (storage *Storage) forget(shortURL string) {
+	if longURL := storage.shortToOriginal[shortURL]; storage.originalToShort[longURL] == shortURL {
+		delete(storage.originalToShort, longURL)
+	}
+	delete(storage.shortToOriginal, shortURL)
+	delete(storage.shortToUserID, shortURL)
+	delete(storage.shortToExpiresAt, shortURL)
+	delete(storage.deletedShortURLs, shortURL)
+	delete(storage.aliasShortURLs, shortURL)
+	delete(storage.shortToClicks, shortURL)
+}
+
+// currentLine returns the file line describing the current state of a stored short URL that is not deleted.
+func (storage *Storage) currentLine(shortURL string) *fileLine {
+	return &fileLine{
+		ShortURL:    shortURL,
+		OriginalURL: storage.shortToOriginal[shortURL],
+		UserID:      storage.shortToUserID[shortURL],
+		ExpiresAt:   expiresAtPointer(storage.shortToExpiresAt[shortURL]),
+		Alias:       storage.aliasShortURLs[shortURL],
+	}
+}
+
+// CountURLs returns the number of short URLs of users that are neither deleted nor expired in the map storage.
+// The built-in default mapping has no owner and is not counted.
+func (storage *Storage) CountURLs(ctx context.Context) (count int, err error) {
+	storage.mutex.RLock()
+	defer storage.mutex.RUnlock()
+
+	now := time.Now()
+	for shortURL, userID := range storage.shortToUserID {
+		if storage.counted(shortURL, userID, now) {
+			count++
+		}
+	}
+	return count, nil
+}
+
+// CountUsers returns the number of distinct users owning short URLs that are neither deleted nor expired in the map storage.
+func (storage *Storage) CountUsers(ctx context.Context) (count int, err error) {
+	storage.mutex.RLock()
+	defer storage.mutex.RUnlock()
+
+	now := time.Now()
+	users := make(map[int]struct{})
+	for shortURL, userID := range storage.shortToUserID {
+		if storage.counted(shortURL, userID, now) {
+			users[userID] = struct{}{}
+		}
+	}
+	return len(users), nil
+}
+
+// counted reports whether the short URL is included in the statistics at now.
+func (storage *Storage) counted(shortURL string, userID int, now time.Time) bool {
+	if userID <= 0 {
+		return false
+	}
+	if _, ok := storage.deletedShortURLs[shortURL]; ok {
+		return false
+	}
+	return !storage.expired(shortURL, now)
+}
+
+// expired reports whether the short URL has expired at now.
+func (storage *Storage) expired(shortURL string, now time.Time) bool {
+	expiresAt, ok := storage.shortToExpiresAt[shortURL]
+	return ok && !now.Before(expiresAt)
+}
+
+// SaveClicks adds a batch of click events to the aggregated statistics in the map storage
+// and writes them to the file storage with a single write.
+func (storage *Storage) SaveClicks(ctx context.Context, clicks []models.ClickEvent) error {
+	storage.mutex.Lock()
+	defer storage.mutex.Unlock()
+
+	lines := make([]*fileLine, 0, len(clicks))
+	for _, click := range clicks {
+		lines = append(lines, &fileLine{
+			ShortURL: click.ShortURL,
+			Click: &clickLine{
+				ClickedAt: click.ClickedAt,
+				Referrer:  click.Referrer,
+				UserAgent: click.UserAgent,
+				IPHash:    click.IPHash,
+			},
+		})
+	}
+	if err := storage.writeToFile(lines); err != nil {
+		return err
+	}
+	storage.addURLsToMap(lines)
+	return nil
+}
+
+// GetLinkStats retrieves click statistics for a short URL owned by the user from the map storage.
+func (storage *Storage) GetLinkStats(ctx context.Context, shortURL string, userID int) (stats models.LinkStats, err error) {
+	storage.mutex.RLock()
+	defer storage.mutex.RUnlock()
+
+	if ownerID, ok := storage.shortToUserID[shortURL]; !ok || ownerID != userID {
+		return stats, ErrURLNotFound
+	}
+
+	clicks, ok := storage.shortToClicks[shortURL]
+	if !ok {
+		clicks = newClickStats()
+	}
+	return clicks.linkStats(shortURL), nil
 }
 
 // Ping checks the connection.
@@ -118,11 +591,23 @@ func (storage *Storage) Ping(ctx context.Context) error {
 }
 
 // Close closes the file storage producer and consumer.
-func (storage *Storage) Close() {
+func (storage *Storage) Close() error {
+	if storage.fileStorage == nil {
+		return nil
+	}
+	var errs []error
 	if storage.fileStorage.producer != nil {
-		storage.fileStorage.producer.close()
+		errs = append(errs, storage.fileStorage.producer.close())
 	}
 	if storage.fileStorage.consumer != nil {
-		storage.fileStorage.consumer.close()
+		errs = append(errs, storage.fileStorage.consumer.close())
+	}
+	return errors.Join(errs...)
+}
+
+func expiresAtPointer(expiresAt time.Time) *time.Time {
+	if expiresAt.IsZero() {
+		return nil
 	}
+	return &expiresAt
 }
@@ -0,0 +1,513 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/DariSorokina/go-first-sprint/internal/logger"
+	"github.com/DariSorokina/go-first-sprint/internal/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStorageUserURLs(t *testing.T) {
+	l, err := logger.CreateLogger("error")
+	require.NoError(t, err)
+
+	ctx := context.Background()
+	fileName := filepath.Join(t.TempDir(), "short-url-db.json")
+
+	storage := NewStorage(fileName, l)
+	storage.SetValue(ctx, "aaaaa", "https://example.com/a", 1, time.Time{})
+	storage.SetValue(ctx, "bbbbb", "https://example.com/b", 1, time.Time{})
+	storage.SetValue(ctx, "ccccc", "https://example.com/c", 2, time.Time{})
+
+	urls, err := storage.GetURLsByUserID(ctx, 1)
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []models.URLPair{
+		{ShortenURL: "aaaaa", OriginalURL: "https://example.com/a"},
+		{ShortenURL: "bbbbb", OriginalURL: "https://example.com/b"},
+	}, urls)
+
+	results, err := storage.DeleteURLs(ctx, []models.URLsClientID{
+		{URLs: []string{"aaaaa", "ccccc", "zzzzz"}, ClientID: 1},
+		{URLs: []string{"aaaaa"}, ClientID: 1},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []map[string]string{
+		{"aaaaa": models.DeletionDeleted, "ccccc": models.DeletionNotOwned, "zzzzz": models.DeletionNotFound},
+		{"aaaaa": models.DeletionDeleted},
+	}, results, "a short URL repeated within one call is reported as deleted by every request")
+
+	results, err = storage.DeleteURLs(ctx, []models.URLsClientID{{URLs: []string{"aaaaa"}, ClientID: 1}})
+	require.NoError(t, err)
+	assert.Equal(t, []map[string]string{{"aaaaa": models.DeletionAlreadyDeleted}}, results)
+
+	_, err = storage.GetOriginal(ctx, "aaaaa")
+	assert.ErrorIs(t, err, ErrDeletedURL)
+
+	longURL, err := storage.GetOriginal(ctx, "ccccc")
+	assert.NoError(t, err)
+	assert.Equal(t, "https://example.com/c", longURL)
+	storage.Close()
+
+	restored := NewStorage(fileName, l)
+	defer restored.Close()
+
+	_, err = restored.GetOriginal(ctx, "aaaaa")
+	assert.ErrorIs(t, err, ErrDeletedURL)
+
+	longURL, err = restored.GetOriginal(ctx, "bbbbb")
+	assert.NoError(t, err)
+	assert.Equal(t, "https://example.com/b", longURL)
+
+	urls, err = restored.GetURLsByUserID(ctx, 2)
+	require.NoError(t, err)
+	assert.Len(t, urls, 1)
+
+	_, err = restored.GetOriginal(ctx, "zzzzz")
+	assert.ErrorIs(t, err, ErrNotFound)
+}
+
+func TestStorageDeleteURLsMixedOwners(t *testing.T) {
+	l, err := logger.CreateLogger("error")
+	require.NoError(t, err)
+
+	ctx := context.Background()
+	storage := NewStorage("", l)
+	storage.SetValue(ctx, "aaaaa", "https://example.com/a", 1, time.Time{})
+
+	results, err := storage.DeleteURLs(ctx, []models.URLsClientID{
+		{URLs: []string{"aaaaa"}, ClientID: 1},
+		{URLs: []string{"aaaaa"}, ClientID: 2},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []map[string]string{
+		{"aaaaa": models.DeletionDeleted},
+		{"aaaaa": models.DeletionNotOwned},
+	}, results, "a user asking to delete a short URL of another user in the same batch is told it is not owned")
+}
+
+func TestStorageUsers(t *testing.T) {
+	l, err := logger.CreateLogger("error")
+	require.NoError(t, err)
+
+	ctx := context.Background()
+	fileName := filepath.Join(t.TempDir(), "short-url-db.json")
+	storage := NewStorage(fileName, l)
+
+	first, err := storage.CreateUser(ctx)
+	require.NoError(t, err)
+	second, err := storage.CreateUser(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, []int{1, 2}, []int{first, second})
+	storage.SetValue(ctx, "aaaaa", "https://example.com/a", 7, time.Time{})
+	storage.Close()
+
+	restored := NewStorage(fileName, l)
+	defer restored.Close()
+
+	for _, userID := range []int{first, second, 7} {
+		exists, err := restored.UserExists(ctx, userID)
+		require.NoError(t, err)
+		assert.True(t, exists, "user %d is known after a restart", userID)
+	}
+	exists, err := restored.UserExists(ctx, 3)
+	require.NoError(t, err)
+	assert.False(t, exists)
+
+	userID, err := restored.CreateUser(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 8, userID, "new IDs are issued after the largest known one")
+}
+
+func TestStorageAPIKeys(t *testing.T) {
+	l, err := logger.CreateLogger("error")
+	require.NoError(t, err)
+
+	ctx := context.Background()
+	fileName := filepath.Join(t.TempDir(), "short-url-db.json")
+	storage := NewStorage(fileName, l)
+
+	createdAt := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	ci := models.APIKey{ID: "ci", UserID: 1, Name: "CI", Scopes: []string{models.ScopeShorten},
+		Prefix: "sk_0123abcd", Hash: "hash-ci", CreatedAt: createdAt}
+	reader := models.APIKey{ID: "reader", UserID: 1, Name: "dashboard", Scopes: []string{models.ScopeRead},
+		Prefix: "sk_4567abcd", Hash: "hash-reader", CreatedAt: createdAt.Add(time.Minute)}
+	other := models.APIKey{ID: "other", UserID: 2, Scopes: []string{models.ScopeDelete},
+		Prefix: "sk_89abcdef", Hash: "hash-other", CreatedAt: createdAt}
+	for _, key := range []models.APIKey{reader, ci, other} {
+		require.NoError(t, storage.CreateAPIKey(ctx, key))
+	}
+
+	key, err := storage.GetAPIKeyByHash(ctx, "hash-ci")
+	require.NoError(t, err)
+	assert.Equal(t, ci, key)
+	_, err = storage.GetAPIKeyByHash(ctx, "unknown")
+	assert.ErrorIs(t, err, ErrAPIKeyNotFound)
+	assert.ErrorIs(t, err, ErrNotFound)
+
+	revokedAt := createdAt.Add(time.Hour)
+	assert.ErrorIs(t, storage.RevokeAPIKey(ctx, "other", 1, revokedAt), ErrAPIKeyNotFound, "keys of other users can not be revoked")
+	assert.ErrorIs(t, storage.RevokeAPIKey(ctx, "unknown", 1, revokedAt), ErrAPIKeyNotFound)
+	require.NoError(t, storage.RevokeAPIKey(ctx, "ci", 1, revokedAt))
+	require.NoError(t, storage.RevokeAPIKey(ctx, "ci", 1, revokedAt.Add(time.Hour)), "revoking twice succeeds")
+	storage.Close()
+
+	restored := NewStorage(fileName, l)
+	defer restored.Close()
+
+	ci.RevokedAt = &revokedAt
+	keys, err := restored.ListAPIKeys(ctx, 1)
+	require.NoError(t, err)
+	assert.Equal(t, []models.APIKey{ci, reader}, keys, "keys survive a restart in creation order and keep the first revocation")
+
+	key, err = restored.GetAPIKeyByHash(ctx, "hash-ci")
+	require.NoError(t, err)
+	assert.Equal(t, &revokedAt, key.RevokedAt)
+}
+
+func TestStorageSetValueConcurrent(t *testing.T) {
+	l, err := logger.CreateLogger("error")
+	require.NoError(t, err)
+
+	ctx := context.Background()
+	storage := NewStorage("", l)
+
+	const requests = 50
+	shortURLs := make([]string, requests)
+	errs := make([]error, requests)
+	var wg sync.WaitGroup
+	for i := 0; i < requests; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			shortURLs[i], errs[i] = storage.SetValue(ctx, fmt.Sprintf("code%d", i), "https://example.com/same", i, time.Time{})
+		}(i)
+	}
+	wg.Wait()
+
+	var created int
+	for i := 0; i < requests; i++ {
+		if errs[i] == nil {
+			created++
+		} else {
+			assert.ErrorIs(t, errs[i], ErrShortURLAlreadyExist)
+		}
+		assert.Equal(t, shortURLs[0], shortURLs[i])
+	}
+	assert.Equal(t, 1, created)
+
+	_, err = storage.SetValue(ctx, shortURLs[0], "https://example.com/other", 1, time.Time{})
+	assert.ErrorIs(t, err, ErrShortURLTaken)
+}
+
+func TestStorageSetValues(t *testing.T) {
+	l, err := logger.CreateLogger("error")
+	require.NoError(t, err)
+
+	ctx := context.Background()
+	fileName := filepath.Join(t.TempDir(), "short-url-db.json")
+	storage := NewStorage(fileName, l)
+
+	_, err = storage.SetValue(ctx, "aaaaa", "https://example.com/a", 1, time.Time{})
+	require.NoError(t, err)
+
+	taken := []models.BatchURL{
+		{CorrelationID: "1", OriginalURL: "https://example.com/b", ShortURL: "bbbbb"},
+		{CorrelationID: "2", OriginalURL: "https://example.com/c", ShortURL: "aaaaa"},
+	}
+	assert.ErrorIs(t, storage.SetValues(ctx, taken, 2), ErrShortURLTaken)
+	urls, err := storage.GetURLsByUserID(ctx, 2)
+	require.NoError(t, err)
+	assert.Empty(t, urls, "nothing is stored when a short URL is taken")
+
+	batch := []models.BatchURL{
+		{CorrelationID: "1", OriginalURL: "https://example.com/b", ShortURL: "bbbbb"},
+		{CorrelationID: "2", OriginalURL: "https://example.com/a", ShortURL: "xxxxx"},
+		{CorrelationID: "3", OriginalURL: "https://example.com/b", ShortURL: "yyyyy"},
+	}
+	require.NoError(t, storage.SetValues(ctx, batch, 2))
+	assert.Equal(t, []models.BatchURL{
+		{CorrelationID: "1", OriginalURL: "https://example.com/b", ShortURL: "bbbbb"},
+		{CorrelationID: "2", OriginalURL: "https://example.com/a", ShortURL: "aaaaa", Conflict: true},
+		{CorrelationID: "3", OriginalURL: "https://example.com/b", ShortURL: "bbbbb", Conflict: true},
+	}, batch)
+	storage.Close()
+
+	restored := NewStorage(fileName, l)
+	defer restored.Close()
+
+	urls, err = restored.GetURLsByUserID(ctx, 2)
+	require.NoError(t, err)
+	assert.Equal(t, []models.URLPair{{ShortenURL: "bbbbb", OriginalURL: "https://example.com/b"}}, urls)
+}
+
+func TestStorageSetAlias(t *testing.T) {
+	l, err := logger.CreateLogger("error")
+	require.NoError(t, err)
+
+	ctx := context.Background()
+	storage := NewStorage("", l)
+
+	ownerID, err := storage.SetAlias(ctx, "spring-sale", "https://example.com/sale", 1, time.Time{})
+	require.NoError(t, err)
+	assert.Equal(t, 1, ownerID)
+
+	ownerID, err = storage.SetAlias(ctx, "spring-sale", "https://example.com/other", 2, time.Time{})
+	assert.ErrorIs(t, err, ErrAliasAlreadyExist)
+	assert.Equal(t, 1, ownerID)
+
+	longURL, err := storage.GetOriginal(ctx, "spring-sale")
+	require.NoError(t, err)
+	assert.Equal(t, "https://example.com/sale", longURL)
+
+	shortURL, err := storage.SetValue(ctx, "aaaaa", "https://example.com/sale", 1, time.Time{})
+	require.NoError(t, err, "an alias does not count as a shortened URL")
+	assert.Equal(t, "aaaaa", shortURL)
+}
+
+func TestStorageExpiredURLs(t *testing.T) {
+	l, err := logger.CreateLogger("error")
+	require.NoError(t, err)
+
+	ctx := context.Background()
+	storage := NewStorage("", l)
+
+	storage.SetValue(ctx, "expired", "https://example.com/expired", 1, time.Now().Add(-time.Minute))
+	storage.SetValue(ctx, "active", "https://example.com/active", 1, time.Now().Add(time.Hour))
+
+	_, err = storage.GetOriginal(ctx, "expired")
+	assert.ErrorIs(t, err, ErrExpiredURL)
+
+	deleted, err := storage.DeleteExpired(ctx, time.Now())
+	require.NoError(t, err)
+	assert.Equal(t, 1, deleted)
+
+	_, err = storage.GetOriginal(ctx, "expired")
+	assert.ErrorIs(t, err, ErrURLNotFound)
+
+	longURL, err := storage.GetOriginal(ctx, "active")
+	require.NoError(t, err)
+	assert.Equal(t, "https://example.com/active", longURL)
+}
+
+func TestStorageShortenExpiredURLAgain(t *testing.T) {
+	l, err := logger.CreateLogger("error")
+	require.NoError(t, err)
+
+	ctx := context.Background()
+	storage := NewStorage("", l)
+
+	_, err = storage.SetValue(ctx, "expired", "https://example.com/expired", 1, time.Now().Add(-time.Minute))
+	require.NoError(t, err)
+
+	shortURL, err := storage.SetValue(ctx, "renewed", "https://example.com/expired", 1, time.Time{})
+	require.NoError(t, err, "an expired short URL does not conflict")
+	assert.Equal(t, "renewed", shortURL)
+
+	shortURL, err = storage.SetValue(ctx, "again", "https://example.com/expired", 1, time.Time{})
+	assert.ErrorIs(t, err, ErrShortURLAlreadyExist)
+	assert.Equal(t, "renewed", shortURL)
+
+	_, err = storage.SetValue(ctx, "expired-batch", "https://example.com/expired-batch", 1, time.Now().Add(-time.Minute))
+	require.NoError(t, err)
+	batch := []models.BatchURL{{CorrelationID: "1", OriginalURL: "https://example.com/expired-batch", ShortURL: "renewed-batch"}}
+	require.NoError(t, storage.SetValues(ctx, batch, 1))
+	assert.False(t, batch[0].Conflict)
+	assert.Equal(t, "renewed-batch", batch[0].ShortURL)
+
+	_, err = storage.GetOriginal(ctx, "expired")
+	assert.ErrorIs(t, err, ErrExpiredURL, "the expired short URL keeps answering as expired")
+	longURL, err := storage.GetOriginal(ctx, "renewed")
+	require.NoError(t, err)
+	assert.Equal(t, "https://example.com/expired", longURL)
+}
+
+func TestStorageCounts(t *testing.T) {
+	l, err := logger.CreateLogger("error")
+	require.NoError(t, err)
+
+	ctx := context.Background()
+	storage := NewStorage("", l)
+
+	urls, err := storage.CountURLs(ctx)
+	require.NoError(t, err)
+	users, err := storage.CountUsers(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, []int{0, 0}, []int{urls, users}, "the default mapping is not counted")
+
+	storage.SetValue(ctx, "aaaaa", "https://example.com/a", 1, time.Time{})
+	storage.SetValue(ctx, "bbbbb", "https://example.com/b", 1, time.Now().Add(time.Hour))
+	storage.SetValue(ctx, "ccccc", "https://example.com/c", 2, time.Time{})
+	storage.SetValue(ctx, "ddddd", "https://example.com/d", 3, time.Now().Add(-time.Minute))
+	_, err = storage.DeleteURLs(ctx, []models.URLsClientID{{URLs: []string{"ccccc"}, ClientID: 2}})
+	require.NoError(t, err)
+
+	urls, err = storage.CountURLs(ctx)
+	require.NoError(t, err)
+	users, err = storage.CountUsers(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, []int{2, 1}, []int{urls, users}, "deleted and expired short URLs are not counted")
+}
+
+func TestStorageRestoreURLs(t *testing.T) {
+	l, err := logger.CreateLogger("error")
+	require.NoError(t, err)
+
+	ctx := context.Background()
+	fileName := filepath.Join(t.TempDir(), "short-url-db.json")
+	storage := NewStorage(fileName, l)
+
+	storage.SetValue(ctx, "aaaaa", "https://example.com/a", 1, time.Time{})
+	storage.SetValue(ctx, "bbbbb", "https://example.com/b", 1, time.Time{})
+	storage.SetValue(ctx, "ccccc", "https://example.com/c", 2, time.Time{})
+	_, err = storage.DeleteURLs(ctx, []models.URLsClientID{
+		{URLs: []string{"aaaaa"}, ClientID: 1},
+		{URLs: []string{"ccccc"}, ClientID: 2},
+	})
+	require.NoError(t, err)
+
+	results, err := storage.RestoreURLs(ctx, []string{"aaaaa", "bbbbb", "ccccc", "zzzzz"}, 1, time.Now().Add(-time.Hour))
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{
+		"aaaaa": models.RestorationRestored,
+		"bbbbb": models.RestorationNotDeleted,
+		"ccccc": models.RestorationNotOwned,
+		"zzzzz": models.RestorationNotFound,
+	}, results)
+
+	results, err = storage.RestoreURLs(ctx, []string{"ccccc"}, 2, time.Now())
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{"ccccc": models.RestorationGraceExpired}, results)
+	require.NoError(t, storage.Close())
+
+	// Restored and deleted short URLs keep their state after a restart.
+	storage = NewStorage(fileName, l)
+
+	longURL, err := storage.GetOriginal(ctx, "aaaaa")
+	require.NoError(t, err)
+	assert.Equal(t, "https://example.com/a", longURL)
+
+	purged, err := storage.PurgeDeleted(ctx, time.Now().Add(-time.Hour))
+	require.NoError(t, err)
+	assert.Zero(t, purged, "short URLs deleted within the grace period are kept")
+
+	purged, err = storage.PurgeDeleted(ctx, time.Now())
+	require.NoError(t, err)
+	assert.Equal(t, 1, purged)
+
+	_, err = storage.GetOriginal(ctx, "ccccc")
+	assert.ErrorIs(t, err, ErrURLNotFound)
+	require.NoError(t, storage.Close())
+
+	// Purged short URLs stay removed after a restart.
+	storage = NewStorage(fileName, l)
+	defer storage.Close()
+	_, err = storage.GetOriginal(ctx, "ccccc")
+	assert.ErrorIs(t, err, ErrURLNotFound)
+}
+
+func TestStorageDeleteExpiredAfterRestart(t *testing.T) {
+	l, err := logger.CreateLogger("error")
+	require.NoError(t, err)
+
+	ctx := context.Background()
+	fileName := filepath.Join(t.TempDir(), "short-url-db.json")
+	storage := NewStorage(fileName, l)
+	_, err = storage.SetValue(ctx, "expired", "https://example.com/expired", 1, time.Now().Add(-time.Minute))
+	require.NoError(t, err)
+
+	deleted, err := storage.DeleteExpired(ctx, time.Now())
+	require.NoError(t, err)
+	assert.Equal(t, 1, deleted)
+	require.NoError(t, storage.Close())
+
+	restored := NewStorage(fileName, l)
+	defer restored.Close()
+	_, err = restored.GetOriginal(ctx, "expired")
+	assert.ErrorIs(t, err, ErrURLNotFound, "the expired short URL is not replayed from the file")
+}
+
+func TestStorageLegacyDeletedLine(t *testing.T) {
+	l, err := logger.CreateLogger("error")
+	require.NoError(t, err)
+
+	ctx := context.Background()
+	fileName := filepath.Join(t.TempDir(), "short-url-db.json")
+	line := `{"short_url":"aaaaa","original_url":"https://example.com/a","user_id":1,"is_deleted":true}` + "\n"
+	require.NoError(t, os.WriteFile(fileName, []byte(line), 0666))
+	written := time.Now().Add(-48 * time.Hour)
+	require.NoError(t, os.Chtimes(fileName, written, written))
+
+	storage := NewStorage(fileName, l)
+	defer storage.Close()
+
+	purged, err := storage.PurgeDeleted(ctx, time.Now().Add(-24*time.Hour))
+	require.NoError(t, err)
+	assert.Equal(t, 1, purged, "a deleted line without a deletion time counts from the modification time of the file")
+}
+
+func TestStorageLinkStats(t *testing.T) {
+	l, err := logger.CreateLogger("error")
+	require.NoError(t, err)
+
+	ctx := context.Background()
+	fileName := filepath.Join(t.TempDir(), "short-url-db.json")
+	storage := NewStorage(fileName, l)
+	storage.SetValue(ctx, "aaaaa", "https://example.com/a", 1, time.Time{})
+
+	day := time.Date(2024, time.March, 1, 12, 0, 0, 0, time.UTC)
+	require.NoError(t, storage.SaveClicks(ctx, []models.ClickEvent{
+		{ShortURL: "aaaaa", ClickedAt: day, Referrer: "https://ya.ru/"},
+		{ShortURL: "aaaaa", ClickedAt: day, Referrer: "https://ya.ru/"},
+		{ShortURL: "aaaaa", ClickedAt: day.AddDate(0, 0, 1), Referrer: "https://example.org/"},
+		{ShortURL: "aaaaa", ClickedAt: day.AddDate(0, 0, 1)},
+	}))
+
+	expected := models.LinkStats{
+		ShortURL: "aaaaa",
+		Total:    4,
+		Daily: []models.DailyClicks{
+			{Date: "2024-03-01", Clicks: 2},
+			{Date: "2024-03-02", Clicks: 2},
+		},
+		TopReferrers: []models.ReferrerClicks{
+			{Referrer: "https://ya.ru/", Clicks: 2},
+			{Referrer: "https://example.org/", Clicks: 1},
+		},
+	}
+	stats, err := storage.GetLinkStats(ctx, "aaaaa", 1)
+	require.NoError(t, err)
+	assert.Equal(t, expected, stats)
+	require.NoError(t, storage.Close())
+
+	storage = NewStorage(fileName, l)
+	defer storage.Close()
+	stats, err = storage.GetLinkStats(ctx, "aaaaa", 1)
+	require.NoError(t, err)
+	assert.Equal(t, expected, stats, "clicks are kept after a restart")
+
+	_, err = storage.GetLinkStats(ctx, "aaaaa", 2)
+	assert.ErrorIs(t, err, ErrURLNotFound)
+}
+
+func TestErrorCategories(t *testing.T) {
+	assert.ErrorIs(t, ErrShortURLAlreadyExist, ErrConflict)
+	assert.ErrorIs(t, ErrShortURLTaken, ErrConflict)
+	assert.ErrorIs(t, ErrAliasAlreadyExist, ErrConflict)
+	assert.ErrorIs(t, ErrURLNotFound, ErrNotFound)
+	assert.ErrorIs(t, ErrDeletedURL, ErrDeleted)
+	assert.ErrorIs(t, ErrExpiredURL, ErrExpired)
+
+	err := unavailable(errors.New("connection refused"))
+	assert.ErrorIs(t, err, ErrUnavailable)
+	assert.NotErrorIs(t, err, ErrNotFound)
+	assert.NoError(t, unavailable(nil))
+}
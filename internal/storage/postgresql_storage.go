@@ -5,160 +5,665 @@ import (
 	"context"
 	"database/sql"
 	"errors"
-	"log"
+	"fmt"
 	"strings"
 	"time"
 
 	"github.com/DariSorokina/go-first-sprint/internal/logger"
+	"github.com/DariSorokina/go-first-sprint/internal/migrations"
 	"github.com/DariSorokina/go-first-sprint/internal/models"
 	_ "github.com/jackc/pgx/v5/stdlib"
 )
 
 const (
-	createSchemaTableIndexQuery = `CREATE SCHEMA IF NOT EXISTS content;
-	CREATE TABLE IF NOT EXISTS content.urls (
-		originalURL TEXT, 
-		shortURL TEXT,
-		userID INTEGER,
-		deletedFlag BOOLEAN);
-	CREATE INDEX IF NOT EXISTS originalURL ON content.urls (originalURL);`
-	readShortURLQuery              = `SELECT shortURL FROM content.urls WHERE originalURL = $1;`
-	readOriginalURLQuery           = `SELECT originalURL, deletedFlag FROM content.urls WHERE shortURL = $1;`
-	readURLsByUserIDQuery          = `SELECT originalURL, shortURL FROM content.urls WHERE userID = $1;`
-	writeURLsQuery                 = `INSERT INTO content.urls (originalURL, shortURL, userID, deletedFlag) VALUES ($1, $2, $3, False);`
-	updateDeleteFlagQueryBeginning = `UPDATE content.urls SET deletedFlag = True WHERE shortURL in ('`
-	updateDeleteFlagQueryEndinning = `') AND userID = ($1);`
+	createUserQuery              = `INSERT INTO content.users DEFAULT VALUES RETURNING userID;`
+	readUserQuery                = `SELECT EXISTS (SELECT 1 FROM content.users WHERE userID = $1);`
+	writeAPIKeyQuery             = `INSERT INTO content.apiKeys (id, userID, name, scopes, prefix, keyHash, createdAt) VALUES ($1, $2, $3, $4, $5, $6, $7);`
+	readAPIKeyByHashQuery        = `SELECT id, userID, name, scopes, prefix, keyHash, createdAt, revokedAt FROM content.apiKeys WHERE keyHash = $1;`
+	readAPIKeysByUserIDQuery     = `SELECT id, userID, name, scopes, prefix, keyHash, createdAt, revokedAt FROM content.apiKeys WHERE userID = $1 ORDER BY createdAt, id;`
+	revokeAPIKeyQuery            = `UPDATE content.apiKeys SET revokedAt = COALESCE(revokedAt, $3) WHERE id = $1 AND userID = $2;`
+	readShortURLQuery            = `SELECT shortURL FROM content.urls WHERE originalURL = $1 AND NOT isAlias AND (expiresAt IS NULL OR expiresAt > now());`
+	readOriginalURLQuery         = `SELECT originalURL, deletedFlag, expiresAt FROM content.urls WHERE shortURL = $1;`
+	readURLsByUserIDQuery        = `SELECT originalURL, shortURL FROM content.urls WHERE userID = $1;`
+	writeURLsQuery               = `INSERT INTO content.urls (originalURL, shortURL, userID, deletedFlag, expiresAt) VALUES ($1, $2, $3, False, $4) ON CONFLICT DO NOTHING;`
+	writeURLsBatchQueryBeginning = `INSERT INTO content.urls (originalURL, shortURL, userID, deletedFlag, expiresAt) VALUES `
+	writeURLsBatchQueryEnding    = ` ON CONFLICT DO NOTHING RETURNING originalURL, shortURL;`
+	readShortURLsQuery           = `SELECT originalURL, shortURL FROM content.urls WHERE originalURL = ANY($1) AND NOT isAlias AND (expiresAt IS NULL OR expiresAt > now());`
+	releaseExpiredURLsQuery      = `UPDATE content.urls SET isAlias = True WHERE originalURL = ANY($1) AND NOT isAlias AND expiresAt <= now();`
+	writeAliasQuery              = `INSERT INTO content.urls (originalURL, shortURL, userID, deletedFlag, expiresAt, isAlias) VALUES ($1, $2, $3, False, $4, True) ON CONFLICT (shortURL) DO NOTHING;`
+	deleteExpiredURLsQuery       = `DELETE FROM content.urls WHERE expiresAt IS NOT NULL AND expiresAt <= $1;`
+	purgeDeletedURLsQuery        = `DELETE FROM content.urls WHERE deletedFlag AND deletedAt < $1;`
+	restoreURLsQuery             = `WITH restored AS (UPDATE content.urls SET deletedFlag = False, deletedAt = NULL WHERE shortURL = ANY($1) AND userID = $2 AND deletedFlag AND deletedAt > $3 RETURNING shortURL) SELECT requested.shortURL, urls.userID, urls.deletedFlag, restored.shortURL IS NOT NULL FROM unnest($1::text[]) AS requested (shortURL) LEFT JOIN content.urls AS urls ON urls.shortURL = requested.shortURL LEFT JOIN restored ON restored.shortURL = requested.shortURL;`
+	readOwnerByShortURLQuery     = `SELECT userID FROM content.urls WHERE shortURL = $1;`
+	countURLsQuery               = `SELECT count(*) FROM content.urls WHERE userID > 0 AND NOT deletedFlag AND (expiresAt IS NULL OR expiresAt > now());`
+	countUsersQuery              = `SELECT count(DISTINCT userID) FROM content.urls WHERE userID > 0 AND NOT deletedFlag AND (expiresAt IS NULL OR expiresAt > now());`
+	writeClicksBatchQuery        = `INSERT INTO content.clicks (shortURL, clickedAt, referrer, userAgent, ipHash) VALUES `
+	readClicksTotalQuery         = `SELECT count(*) FROM content.clicks WHERE shortURL = $1;`
+	readDailyClicksQuery         = `SELECT to_char(clickedAt AT TIME ZONE 'UTC', 'YYYY-MM-DD') AS day, count(*) FROM content.clicks WHERE shortURL = $1 GROUP BY day ORDER BY day;`
+	readTopReferrersQuery        = `SELECT referrer, count(*) AS clicks FROM content.clicks WHERE shortURL = $1 AND referrer <> '' GROUP BY referrer ORDER BY clicks DESC, referrer LIMIT $2;`
+	updateDeleteFlagQuery        = `WITH requested AS (SELECT * FROM unnest($1::text[], $2::integer[]) AS r (shortURL, userID)),
+		deleted AS (UPDATE content.urls SET deletedFlag = True, deletedAt = now() WHERE shortURL = ANY($1) AND NOT deletedFlag
+			AND (shortURL, userID) IN (SELECT shortURL, userID FROM requested) RETURNING shortURL, userID)
+		SELECT requested.shortURL, requested.userID, urls.userID, deleted.shortURL IS NOT NULL
+		FROM requested LEFT JOIN content.urls AS urls ON urls.shortURL = requested.shortURL
+		LEFT JOIN deleted ON deleted.shortURL = requested.shortURL AND deleted.userID = requested.userID;`
 )
 
-// ErrReadOriginalURL indicates that the provided URL can not be read.
-var ErrReadOriginalURL = errors.New("can not read url")
-
-// ErrDeletedURL indicates that requested url was deleted.
-var ErrDeletedURL = errors.New("requested url was deleted")
+// writeBatchSize limits the rows of one multi-row insert to stay well below the bind parameter limit.
+const writeBatchSize = 1000
 
 // PostgresqlDB represents a structure for working with a PostgreSQL database.
 type PostgresqlDB struct {
-	db  *sql.DB        // Connection to the database.
+	db  *tracedDB      // Connection to the database.
 	log *logger.Logger // Logger for recording events and errors.
 }
 
-// NewPostgresqlDB creates a new PostgresqlDB instance, initializes the database connection and create database schema, tables and indexes.
+// NewPostgresqlDB creates a new PostgresqlDB instance, initializes the database connection and applies pending schema migrations.
 func NewPostgresqlDB(cofigBDString string, l *logger.Logger) (*PostgresqlDB, error) {
 	db, err := sql.Open("pgx", cofigBDString)
 	if err != nil {
 		l.Sugar().Errorf("Failed to open a database: %s", err)
-		return &PostgresqlDB{db: db, log: l}, err
+		return &PostgresqlDB{db: newTracedDB(db), log: l}, err
 	}
 
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
 
-	_, err = db.ExecContext(ctx, createSchemaTableIndexQuery)
+	migrator, err := migrations.NewMigrator(db, l)
+	if err != nil {
+		l.Sugar().Errorf("Failed to load migrations: %s", err)
+		return &PostgresqlDB{db: newTracedDB(db), log: l}, err
+	}
+
+	if _, err = migrator.Up(ctx); err != nil {
+		l.Sugar().Errorf("Failed to apply migrations: %s", err)
+		return &PostgresqlDB{db: newTracedDB(db), log: l}, unavailable(err)
+	}
+
+	return &PostgresqlDB{db: newTracedDB(db), log: l}, nil
+}
+
+// CreateUser stores a new user and returns the ID issued to it by the database.
+func (postgresqlDB *PostgresqlDB) CreateUser(ctx context.Context) (userID int, err error) {
+	if err = postgresqlDB.db.QueryRowContext(ctx, createUserQuery).Scan(&userID); err != nil {
+		postgresqlDB.log.Sugar().Errorf("Failed to execute a query createUserQuery: %s", err)
+		return 0, unavailable(err)
+	}
+	return userID, nil
+}
+
+// UserExists reports whether the user ID was issued by CreateUser.
+func (postgresqlDB *PostgresqlDB) UserExists(ctx context.Context, userID int) (exists bool, err error) {
+	err = postgresqlDB.db.QueryRowContext(ctx, readUserQuery, userID).Scan(&exists)
+	return exists, unavailable(err)
+}
+
+// CreateAPIKey stores the API key with its scopes separated by spaces.
+func (postgresqlDB *PostgresqlDB) CreateAPIKey(ctx context.Context, key models.APIKey) error {
+	_, err := postgresqlDB.db.ExecContext(ctx, writeAPIKeyQuery,
+		key.ID, key.UserID, key.Name, strings.Join(key.Scopes, " "), key.Prefix, key.Hash, key.CreatedAt)
+	if err != nil {
+		postgresqlDB.log.Sugar().Errorf("Failed to execute a query writeAPIKeyQuery: %s", err)
+		return unavailable(err)
+	}
+	return nil
+}
+
+// GetAPIKeyByHash retrieves the API key with the given hash, including a revoked one.
+// It returns ErrAPIKeyNotFound if there is no such key.
+func (postgresqlDB *PostgresqlDB) GetAPIKeyByHash(ctx context.Context, hash string) (key models.APIKey, err error) {
+	key, err = scanAPIKey(postgresqlDB.db.QueryRowContext(ctx, readAPIKeyByHashQuery, hash))
+	if errors.Is(err, sql.ErrNoRows) {
+		return models.APIKey{}, ErrAPIKeyNotFound
+	}
+	if err != nil {
+		postgresqlDB.log.Sugar().Errorf("Failed to execute a query readAPIKeyByHashQuery: %s", err)
+		return models.APIKey{}, unavailable(err)
+	}
+	return key, nil
+}
+
+// ListAPIKeys retrieves the API keys of the user, including revoked ones, in the order they were created.
+func (postgresqlDB *PostgresqlDB) ListAPIKeys(ctx context.Context, userID int) (keys []models.APIKey, err error) {
+	rows, err := postgresqlDB.db.QueryContext(ctx, readAPIKeysByUserIDQuery, userID)
+	if err != nil {
+		postgresqlDB.log.Sugar().Errorf("Failed to execute a query readAPIKeysByUserIDQuery: %s", err)
+		return nil, unavailable(err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		key, err := scanAPIKey(rows)
+		if err != nil {
+			postgresqlDB.log.Sugar().Errorf("Failed to scan API key in ListAPIKeys method: %s", err)
+			return nil, unavailable(err)
+		}
+		keys = append(keys, key)
+	}
+
+	if err = rows.Err(); err != nil {
+		postgresqlDB.log.Sugar().Errorf("The last error encountered by Rows.Scan in ListAPIKeys method: %s", err)
+		return nil, unavailable(err)
+	}
+	return keys, nil
+}
+
+// RevokeAPIKey marks the user's API key as revoked at revokedAt, keeping the time of an earlier revocation.
+// It returns ErrAPIKeyNotFound if the key does not exist or is owned by another user.
+func (postgresqlDB *PostgresqlDB) RevokeAPIKey(ctx context.Context, id string, userID int, revokedAt time.Time) error {
+	result, err := postgresqlDB.db.ExecContext(ctx, revokeAPIKeyQuery, id, userID, revokedAt)
 	if err != nil {
-		l.Sugar().Errorf("Failed to execute a query createSchemaQuery: %s", err)
-		return &PostgresqlDB{db: db, log: l}, err
+		postgresqlDB.log.Sugar().Errorf("Failed to execute a query revokeAPIKeyQuery: %s", err)
+		return unavailable(err)
 	}
 
-	return &PostgresqlDB{db: db, log: l}, nil
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return unavailable(err)
+	}
+	if rows == 0 {
+		return ErrAPIKeyNotFound
+	}
+	return nil
+}
+
+// scanAPIKey reads an API key from a row selected by readAPIKeyByHashQuery or readAPIKeysByUserIDQuery.
+func scanAPIKey(row interface{ Scan(dest ...any) error }) (key models.APIKey, err error) {
+	var scopes string
+	var revokedAt sql.NullTime
+	err = row.Scan(&key.ID, &key.UserID, &key.Name, &scopes, &key.Prefix, &key.Hash, &key.CreatedAt, &revokedAt)
+	if err != nil {
+		return models.APIKey{}, err
+	}
+	key.Scopes = strings.Fields(scopes)
+	if revokedAt.Valid {
+		key.RevokedAt = &revokedAt.Time
+	}
+	return key, nil
 }
 
-// SetValue sets a value in the database for a given shortURL, longURL, and userID.
-func (postgresqlDB *PostgresqlDB) SetValue(ctx context.Context, shortURL, longURL string, userID int) {
-	_, err := postgresqlDB.db.ExecContext(ctx, writeURLsQuery, longURL, shortURL, userID)
+// SetValue stores longURL under shortURL with a single insert backed by the unique indexes on both columns.
+// If longURL is already shortened and the short URL has not expired, it returns the existing short URL and ErrShortURLAlreadyExist;
+// if shortURL is used for another URL, it returns ErrShortURLTaken. A zero expiresAt means the short URL never expires.
+func (postgresqlDB *PostgresqlDB) SetValue(ctx context.Context, shortURL, longURL string, userID int, expiresAt time.Time) (storedShortURL string, err error) {
+	result, err := postgresqlDB.db.ExecContext(ctx, writeURLsQuery, longURL, shortURL, userID, nullTime(expiresAt))
 	if err != nil {
 		postgresqlDB.log.Sugar().Errorf("Failed to execute a query writeURLsQuery: %s", err)
+		return "", unavailable(err)
 	}
 
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return "", unavailable(err)
+	}
+	if rows == 1 {
+		return shortURL, nil
+	}
+
+	// ON CONFLICT waits for a concurrent insert of the same row to commit, so a new statement sees it.
+	err = postgresqlDB.db.QueryRowContext(ctx, readShortURLQuery, longURL).Scan(&storedShortURL)
+	if errors.Is(err, sql.ErrNoRows) {
+		released, err := releaseExpired(ctx, postgresqlDB.db, []string{longURL})
+		if err != nil {
+			postgresqlDB.log.Sugar().Errorf("Failed to execute a query releaseExpiredURLsQuery: %s", err)
+			return "", unavailable(err)
+		}
+		if released > 0 {
+			return postgresqlDB.SetValue(ctx, shortURL, longURL, userID, expiresAt)
+		}
+		return "", ErrShortURLTaken
+	}
+	if err != nil {
+		postgresqlDB.log.Sugar().Errorf("Failed to execute a query readShortURLQuery: %s", err)
+		return "", unavailable(err)
+	}
+	return storedShortURL, ErrShortURLAlreadyExist
 }
 
-// GetShort retrieves the short URL corresponding to a given long URL from the database.
-func (postgresqlDB *PostgresqlDB) GetShort(ctx context.Context, longURL string) (shortURL string, errShortURL error) {
-	err := postgresqlDB.db.QueryRowContext(ctx, readShortURLQuery, longURL).Scan(&shortURL)
+// SetValues stores a batch of URLs for the user in one transaction using multi-row inserts.
+// Items whose original URL is already shortened under a short URL that has not expired, also earlier
+// in the same batch, get the existing short URL and Conflict set. If a proposed short URL is used for another URL, the transaction is rolled back
+// and ErrShortURLTaken is returned.
+func (postgresqlDB *PostgresqlDB) SetValues(ctx context.Context, urls []models.BatchURL, userID int) error {
+	tx, err := postgresqlDB.db.BeginTx(ctx, nil)
 	if err != nil {
-		return "", nil
+		return unavailable(err)
+	}
+	defer tx.Rollback()
+
+	originalURLs := make([]string, 0, len(urls))
+	for _, url := range urls {
+		originalURLs = append(originalURLs, url.OriginalURL)
+	}
+	if _, err = releaseExpired(ctx, tx, originalURLs); err != nil {
+		postgresqlDB.log.Sugar().Errorf("Failed to execute a query releaseExpiredURLsQuery: %s", err)
+		return unavailable(err)
+	}
+
+	inserted := make(map[string]string, len(urls))
+	for start := 0; start < len(urls); start += writeBatchSize {
+		end := min(start+writeBatchSize, len(urls))
+		if err = insertURLs(ctx, tx, urls[start:end], userID, inserted); err != nil {
+			postgresqlDB.log.Sugar().Errorf("Failed to execute a query writeURLsBatchQuery: %s", err)
+			return unavailable(err)
+		}
+	}
+
+	var pending []string
+	for i := range urls {
+		if shortURL, ok := inserted[urls[i].OriginalURL]; ok && shortURL == urls[i].ShortURL {
+			// Later duplicates of the same original URL are reported as conflicts.
+			delete(inserted, urls[i].OriginalURL)
+			continue
+		}
+		urls[i].Conflict = true
+		pending = append(pending, urls[i].OriginalURL)
 	}
 
-	return shortURL, ErrShortURLAlreadyExist
+	if len(pending) > 0 {
+		existing, err := readShortURLs(ctx, tx, pending)
+		if err != nil {
+			postgresqlDB.log.Sugar().Errorf("Failed to execute a query readShortURLsQuery: %s", err)
+			return unavailable(err)
+		}
+		for i := range urls {
+			if !urls[i].Conflict {
+				continue
+			}
+			shortURL, ok := existing[urls[i].OriginalURL]
+			if !ok {
+				return ErrShortURLTaken
+			}
+			urls[i].ShortURL = shortURL
+		}
+	}
+
+	return unavailable(tx.Commit())
+}
+
+// releaseExpired lets the original URLs be shortened again while their generated short URL has expired.
+// The expired short URL is kept as an alias, so it keeps answering as expired until the janitor removes it.
+func releaseExpired(ctx context.Context, db execer, originalURLs []string) (int64, error) {
+	result, err := db.ExecContext(ctx, releaseExpiredURLsQuery, originalURLs)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+// insertURLs inserts the URLs with a single statement and records the inserted ones as original to short URL.
+func insertURLs(ctx context.Context, tx *tracedTx, urls []models.BatchURL, userID int, inserted map[string]string) error {
+	var query strings.Builder
+	args := make([]any, 0, 3*len(urls)+1)
+	args = append(args, userID)
+
+	query.WriteString(writeURLsBatchQueryBeginning)
+	for i, url := range urls {
+		if i > 0 {
+			query.WriteString(", ")
+		}
+		fmt.Fprintf(&query, "($%d, $%d, $1, False, $%d)", len(args)+1, len(args)+2, len(args)+3)
+		args = append(args, url.OriginalURL, url.ShortURL, nullTime(url.ExpiresAt))
+	}
+	query.WriteString(writeURLsBatchQueryEnding)
+
+	rows, err := tx.QueryContext(ctx, query.String(), args...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var originalURL, shortURL string
+		if err = rows.Scan(&originalURL, &shortURL); err != nil {
+			return err
+		}
+		inserted[originalURL] = shortURL
+	}
+	return rows.Err()
+}
+
+// readShortURLs returns the generated short URLs of the original URLs that are already stored.
+func readShortURLs(ctx context.Context, tx *tracedTx, originalURLs []string) (map[string]string, error) {
+	rows, err := tx.QueryContext(ctx, readShortURLsQuery, originalURLs)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	existing := make(map[string]string, len(originalURLs))
+	for rows.Next() {
+		var originalURL, shortURL string
+		if err = rows.Scan(&originalURL, &shortURL); err != nil {
+			return nil, err
+		}
+		existing[originalURL] = shortURL
+	}
+	return existing, rows.Err()
+}
+
+// SetAlias stores longURL under the alias unless the alias is already taken,
+// in which case it returns the ID of the user owning the alias and ErrAliasAlreadyExist.
+func (postgresqlDB *PostgresqlDB) SetAlias(ctx context.Context, alias, longURL string, userID int, expiresAt time.Time) (ownerID int, err error) {
+	result, err := postgresqlDB.db.ExecContext(ctx, writeAliasQuery, longURL, alias, userID, nullTime(expiresAt))
+	if err != nil {
+		postgresqlDB.log.Sugar().Errorf("Failed to execute a query writeAliasQuery: %s", err)
+		return 0, unavailable(err)
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return 0, unavailable(err)
+	}
+	if rows == 1 {
+		return userID, nil
+	}
+
+	err = postgresqlDB.db.QueryRowContext(ctx, readOwnerByShortURLQuery, alias).Scan(&ownerID)
+	if err != nil {
+		postgresqlDB.log.Sugar().Errorf("Failed to execute a query readOwnerByShortURLQuery: %s", err)
+		return 0, unavailable(err)
+	}
+	return ownerID, ErrAliasAlreadyExist
 }
 
 // GetOriginal retrieves the original long URL corresponding to a given short URL from the database.
 func (postgresqlDB *PostgresqlDB) GetOriginal(ctx context.Context, shortURL string) (longURL string, getOriginalErr error) {
 	var deletedFlag bool
+	var expiresAt sql.NullTime
 
-	err := postgresqlDB.db.QueryRowContext(ctx, readOriginalURLQuery, shortURL).Scan(&longURL, &deletedFlag)
+	err := postgresqlDB.db.QueryRowContext(ctx, readOriginalURLQuery, shortURL).Scan(&longURL, &deletedFlag, &expiresAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return "", ErrURLNotFound
+	}
 	if err != nil {
-		return "", ErrReadOriginalURL
+		postgresqlDB.log.Sugar().Errorf("Failed to execute a query readOriginalURLQuery: %s", err)
+		return "", unavailable(err)
 	}
 
 	if deletedFlag {
 		return "", ErrDeletedURL
 	}
 
+	if expiresAt.Valid && !time.Now().Before(expiresAt.Time) {
+		return "", ErrExpiredURL
+	}
+
 	return longURL, nil
 }
 
 // GetURLsByUserID retrieves URLs associated with a given user ID from the database.
-func (postgresqlDB *PostgresqlDB) GetURLsByUserID(ctx context.Context, userID int) (urls []models.URLPair) {
-
+func (postgresqlDB *PostgresqlDB) GetURLsByUserID(ctx context.Context, userID int) (urls []models.URLPair, err error) {
 	rows, err := postgresqlDB.db.QueryContext(ctx, readURLsByUserIDQuery, userID)
 	if err != nil {
-		return
+		postgresqlDB.log.Sugar().Errorf("Failed to execute a query readURLsByUserIDQuery: %s", err)
+		return nil, unavailable(err)
 	}
 	defer rows.Close()
 
 	for rows.Next() {
 		var url models.URLPair
-		if err := rows.Scan(&url.OriginalURL, &url.ShortenURL); err != nil {
+		if err = rows.Scan(&url.OriginalURL, &url.ShortenURL); err != nil {
 			postgresqlDB.log.Sugar().Errorf("Failed to scan original and shorten urls in GetURLsByUserID method: %s", err)
+			return nil, unavailable(err)
 		}
 		urls = append(urls, url)
 	}
 
-	rerr := rows.Close()
-	if rerr != nil {
-		postgresqlDB.log.Sugar().Errorf("Close error in GetURLsByUserID method: %s", rerr)
+	if err = rows.Err(); err != nil {
+		postgresqlDB.log.Sugar().Errorf("The last error encountered by Rows.Scan in GetURLsByUserID method: %s", err)
+		return nil, unavailable(err)
 	}
+	return urls, nil
+}
 
-	if err := rows.Err(); err != nil {
-		postgresqlDB.log.Sugar().Errorf("The last error encountered by Rows.Scan in GetURLsByUserID method: %s", err)
-		log.Fatal(err)
+// DeleteExpired removes short URLs that expired before expiredBefore from the database.
+func (postgresqlDB *PostgresqlDB) DeleteExpired(ctx context.Context, expiredBefore time.Time) (deleted int, err error) {
+	result, err := postgresqlDB.db.ExecContext(ctx, deleteExpiredURLsQuery, expiredBefore)
+	if err != nil {
+		postgresqlDB.log.Sugar().Errorf("Failed to execute a query deleteExpiredURLsQuery: %s", err)
+		return 0, unavailable(err)
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return 0, unavailable(err)
+	}
+	return int(rows), nil
+}
+
+// CountURLs returns the number of short URLs that are not deleted in the database.
+func (postgresqlDB *PostgresqlDB) CountURLs(ctx context.Context) (count int, err error) {
+	err = postgresqlDB.db.QueryRowContext(ctx, countURLsQuery).Scan(&count)
+	return count, unavailable(err)
+}
+
+// CountUsers returns the number of distinct users owning short URLs that are not deleted in the database.
+func (postgresqlDB *PostgresqlDB) CountUsers(ctx context.Context) (count int, err error) {
+	err = postgresqlDB.db.QueryRowContext(ctx, countUsersQuery).Scan(&count)
+	return count, unavailable(err)
+}
+
+// SaveClicks writes a batch of click events to the database in a single transaction.
+func (postgresqlDB *PostgresqlDB) SaveClicks(ctx context.Context, clicks []models.ClickEvent) error {
+	tx, err := postgresqlDB.db.BeginTx(ctx, nil)
+	if err != nil {
+		return unavailable(err)
+	}
+	defer tx.Rollback()
+
+	for start := 0; start < len(clicks); start += writeBatchSize {
+		end := min(start+writeBatchSize, len(clicks))
+		if err = insertClicks(ctx, tx, clicks[start:end]); err != nil {
+			postgresqlDB.log.Sugar().Errorf("Failed to execute a query writeClicksBatchQuery: %s", err)
+			return unavailable(err)
+		}
+	}
+
+	return unavailable(tx.Commit())
+}
+
+// insertClicks inserts the click events with a single statement.
+func insertClicks(ctx context.Context, tx *tracedTx, clicks []models.ClickEvent) error {
+	var query strings.Builder
+	args := make([]any, 0, 5*len(clicks))
+
+	query.WriteString(writeClicksBatchQuery)
+	for i, click := range clicks {
+		if i > 0 {
+			query.WriteString(", ")
+		}
+		fmt.Fprintf(&query, "($%d, $%d, $%d, $%d, $%d)", len(args)+1, len(args)+2, len(args)+3, len(args)+4, len(args)+5)
+		args = append(args, click.ShortURL, click.ClickedAt, click.Referrer, click.UserAgent, click.IPHash)
+	}
+	query.WriteString(";")
+
+	_, err := tx.ExecContext(ctx, query.String(), args...)
+	return err
+}
+
+// GetLinkStats retrieves click statistics for a short URL owned by the user from the database.
+func (postgresqlDB *PostgresqlDB) GetLinkStats(ctx context.Context, shortURL string, userID int) (stats models.LinkStats, err error) {
+	var ownerID int
+	err = postgresqlDB.db.QueryRowContext(ctx, readOwnerByShortURLQuery, shortURL).Scan(&ownerID)
+	if errors.Is(err, sql.ErrNoRows) || (err == nil && ownerID != userID) {
+		return stats, ErrURLNotFound
+	}
+	if err != nil {
+		return stats, unavailable(err)
+	}
+
+	stats = models.LinkStats{ShortURL: shortURL, Daily: []models.DailyClicks{}, TopReferrers: []models.ReferrerClicks{}}
+
+	if err = postgresqlDB.db.QueryRowContext(ctx, readClicksTotalQuery, shortURL).Scan(&stats.Total); err != nil {
+		return stats, unavailable(err)
+	}
+
+	dailyRows, err := postgresqlDB.db.QueryContext(ctx, readDailyClicksQuery, shortURL)
+	if err != nil {
+		return stats, unavailable(err)
+	}
+	defer dailyRows.Close()
+
+	for dailyRows.Next() {
+		var daily models.DailyClicks
+		if err = dailyRows.Scan(&daily.Date, &daily.Clicks); err != nil {
+			return stats, unavailable(err)
+		}
+		stats.Daily = append(stats.Daily, daily)
+	}
+	if err = dailyRows.Err(); err != nil {
+		return stats, unavailable(err)
 	}
 
-	return
+	referrerRows, err := postgresqlDB.db.QueryContext(ctx, readTopReferrersQuery, shortURL, topReferrersLimit)
+	if err != nil {
+		return stats, unavailable(err)
+	}
+	defer referrerRows.Close()
+
+	for referrerRows.Next() {
+		var referrer models.ReferrerClicks
+		if err = referrerRows.Scan(&referrer.Referrer, &referrer.Clicks); err != nil {
+			return stats, unavailable(err)
+		}
+		stats.TopReferrers = append(stats.TopReferrers, referrer)
+	}
+	return stats, unavailable(referrerRows.Err())
 }
 
 // Ping checks the connection to the database.
 func (postgresqlDB *PostgresqlDB) Ping(ctx context.Context) error {
-	if err := postgresqlDB.db.PingContext(ctx); err != nil {
-		return err
-	}
-	return nil
+	return unavailable(postgresqlDB.db.PingContext(ctx))
 }
 
 // Close closes the database connection if it's not already closed.
-func (postgresqlDB *PostgresqlDB) Close() {
+func (postgresqlDB *PostgresqlDB) Close() error {
 	if postgresqlDB.db != nil {
-		postgresqlDB.db.Close()
+		return postgresqlDB.db.Close()
 	}
+	return nil
 }
 
-// DeleteURLsWorker updates the delete flag for a set of short URLs associated with a user ID.
-func (postgresqlDB *PostgresqlDB) DeleteURLsWorker(shortURLs []string, userID int) {
-	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
-	defer cancel()
-
-	processedShortURLs := strings.Join(shortURLs, "', '")
-	updateDeleteFlagQuery := updateDeleteFlagQueryBeginning + processedShortURLs + updateDeleteFlagQueryEndinning
+// DeleteURLs sets the delete flag for the short URLs of every deletion request that are owned by its user
+// with a single statement. It returns, for every request, the deletion result of each of its short URLs,
+// such as models.DeletionNotOwned.
+func (postgresqlDB *PostgresqlDB) DeleteURLs(ctx context.Context, deletions []models.URLsClientID) (results []map[string]string, err error) {
+	// Short URLs and their users are passed as two parallel arrays matched pairwise by unnest.
+	var shortURLs []string
+	var userIDs []int
+	results = make([]map[string]string, len(deletions))
+	requestsByURL := make(map[string][]int)
+	for i, deletion := range deletions {
+		results[i] = make(map[string]string, len(deletion.URLs))
+		for _, shortURL := range deletion.URLs {
+			shortURLs = append(shortURLs, shortURL)
+			userIDs = append(userIDs, deletion.ClientID)
+			requestsByURL[shortURL] = append(requestsByURL[shortURL], i)
+		}
+	}
+	if len(shortURLs) == 0 {
+		return results, nil
+	}
 
-	result, err := postgresqlDB.db.ExecContext(ctx, updateDeleteFlagQuery, userID)
+	rows, err := postgresqlDB.db.QueryContext(ctx, updateDeleteFlagQuery, shortURLs, userIDs)
 	if err != nil {
 		postgresqlDB.log.Sugar().Errorf("Failed to execute a query updateDeleteFlagQuery: %s", err)
+		return nil, unavailable(err)
 	}
-	rows, err := result.RowsAffected()
+	defer rows.Close()
+
+	for rows.Next() {
+		var shortURL string
+		var userID int
+		var ownerID sql.NullInt64
+		var deleted bool
+		if err = rows.Scan(&shortURL, &userID, &ownerID, &deleted); err != nil {
+			return nil, unavailable(err)
+		}
+
+		result := deletionResult(userID, ownerID, deleted)
+		for _, i := range requestsByURL[shortURL] {
+			if deletions[i].ClientID == userID {
+				results[i][shortURL] = result
+			}
+		}
+	}
+	return results, unavailable(rows.Err())
+}
+
+// deletionResult returns the result of the user's request to delete a short URL owned by ownerID, which is
+// not valid for an unknown short URL. Ownership is checked first, so another user asking to delete the
+// short URL in the same statement is told it is not owned rather than that it was deleted.
+func deletionResult(userID int, ownerID sql.NullInt64, deleted bool) string {
+	switch {
+	case !ownerID.Valid:
+		return models.DeletionNotFound
+	case int(ownerID.Int64) != userID:
+		return models.DeletionNotOwned
+	case deleted:
+		return models.DeletionDeleted
+	default:
+		return models.DeletionAlreadyDeleted
+	}
+}
+
+// RestoreURLs clears the delete flag of the user's short URLs deleted after deletedAfter with a single statement.
+// It returns the restoration result of each short URL, such as models.RestorationGraceExpired.
+func (postgresqlDB *PostgresqlDB) RestoreURLs(ctx context.Context, shortURLs []string, userID int, deletedAfter time.Time) (results map[string]string, err error) {
+	results = make(map[string]string, len(shortURLs))
+	if len(shortURLs) == 0 {
+		return results, nil
+	}
+
+	rows, err := postgresqlDB.db.QueryContext(ctx, restoreURLsQuery, shortURLs, userID, deletedAfter)
+	if err != nil {
+		postgresqlDB.log.Sugar().Errorf("Failed to execute a query restoreURLsQuery: %s", err)
+		return nil, unavailable(err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var shortURL string
+		var ownerID sql.NullInt64
+		var deletedFlag sql.NullBool
+		var restored bool
+		if err = rows.Scan(&shortURL, &ownerID, &deletedFlag, &restored); err != nil {
+			return nil, unavailable(err)
+		}
+
+		// The rows read outside of the update show the state before the statement.
+		switch {
+		case restored:
+			results[shortURL] = models.RestorationRestored
+		case !ownerID.Valid:
+			results[shortURL] = models.RestorationNotFound
+		case int(ownerID.Int64) != userID:
+			results[shortURL] = models.RestorationNotOwned
+		case !deletedFlag.Bool:
+			results[shortURL] = models.RestorationNotDeleted
+		default:
+			results[shortURL] = models.RestorationGraceExpired
+		}
+	}
+	return results, unavailable(rows.Err())
+}
+
+// PurgeDeleted removes short URLs deleted before deletedBefore from the database.
+func (postgresqlDB *PostgresqlDB) PurgeDeleted(ctx context.Context, deletedBefore time.Time) (purged int, err error) {
+	result, err := postgresqlDB.db.ExecContext(ctx, purgeDeletedURLsQuery, deletedBefore)
 	if err != nil {
-		postgresqlDB.log.Sugar().Errorf("Failed to execute RowsAffected: %s", err)
+		postgresqlDB.log.Sugar().Errorf("Failed to execute a query purgeDeletedURLsQuery: %s", err)
+		return 0, unavailable(err)
 	}
-	if rows != 1 {
-		postgresqlDB.log.Sugar().Infof("Affected rows: %d", rows)
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return 0, unavailable(err)
 	}
+	return int(rows), nil
+}
+
+func nullTime(t time.Time) sql.NullTime {
+	return sql.NullTime{Time: t, Valid: !t.IsZero()}
 }
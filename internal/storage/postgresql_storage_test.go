@@ -0,0 +1,31 @@
+package storage
+
+import (
+	"database/sql"
+	"testing"
+
+	"github.com/DariSorokina/go-first-sprint/internal/models"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDeletionResult(t *testing.T) {
+	owner := sql.NullInt64{Int64: 1, Valid: true}
+	testCases := []struct {
+		name     string
+		userID   int
+		ownerID  sql.NullInt64
+		deleted  bool
+		expected string
+	}{
+		{name: "deleted by the owner", userID: 1, ownerID: owner, deleted: true, expected: models.DeletionDeleted},
+		{name: "already deleted", userID: 1, ownerID: owner, expected: models.DeletionAlreadyDeleted},
+		{name: "other user", userID: 2, ownerID: owner, expected: models.DeletionNotOwned},
+		{name: "other user in the batch deleting it", userID: 2, ownerID: owner, deleted: true, expected: models.DeletionNotOwned},
+		{name: "unknown short URL", userID: 1, expected: models.DeletionNotFound},
+	}
+	for _, test := range testCases {
+		t.Run(test.name, func(t *testing.T) {
+			assert.Equal(t, test.expected, deletionResult(test.userID, test.ownerID, test.deleted))
+		})
+	}
+}
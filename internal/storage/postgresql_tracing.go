@@ -0,0 +1,147 @@
+// Package storage provides primitives for connecting to data storages.
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"strings"
+	"unicode"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	semconv "go.opentelemetry.io/otel/semconv/v1.21.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// maxTracedStatementLength limits the statement kept in a span, as multi-row inserts grow with the batch.
+const maxTracedStatementLength = 2048
+
+var tracer = otel.Tracer("github.com/DariSorokina/go-first-sprint/internal/storage")
+
+// tracedDB is a connection to the database starting a span with the SQL statement of each query it runs.
+// Spans of queries returning rows end once the query is executed, before the rows are read.
+type tracedDB struct {
+	*sql.DB
+}
+
+// newTracedDB wraps db, keeping nil as is.
+func newTracedDB(db *sql.DB) *tracedDB {
+	if db == nil {
+		return nil
+	}
+	return &tracedDB{DB: db}
+}
+
+// execer runs a statement either on the database or within a transaction.
+type execer interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+}
+
+// tracedTx is a transaction started by tracedDB, tracing its queries the same way.
+type tracedTx struct {
+	*sql.Tx
+}
+
+// startStatement starts a client span named after the SQL operation of query, such as "SELECT" or "INSERT".
+func startStatement(ctx context.Context, query string) (context.Context, trace.Span) {
+	operation := statementOperation(query)
+	if len(query) > maxTracedStatementLength {
+		query = query[:maxTracedStatementLength] + "..."
+	}
+	return tracer.Start(ctx, "postgresql "+operation,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			semconv.DBSystemPostgreSQL,
+			semconv.DBOperation(operation),
+			semconv.DBStatement(query),
+		))
+}
+
+// dataModifyingOperations are the operations a statement with common table expressions is reported as
+// when one of them modifies data.
+var dataModifyingOperations = map[string]bool{"INSERT": true, "UPDATE": true, "DELETE": true}
+
+// statementOperation returns the SQL operation of query. A statement starting with common table expressions
+// is reported as the first data-modifying operation it contains, so an UPDATE inside a WITH query is traced
+// as an UPDATE, and as a SELECT if it modifies nothing.
+func statementOperation(query string) string {
+	operation, _, _ := strings.Cut(strings.TrimSpace(query), " ")
+	operation = strings.ToUpper(operation)
+	if operation != "WITH" {
+		return operation
+	}
+
+	words := strings.FieldsFunc(query, func(r rune) bool {
+		return !unicode.IsLetter(r) && r != '_'
+	})
+	for _, word := range words {
+		if word = strings.ToUpper(word); dataModifyingOperations[word] {
+			return word
+		}
+	}
+	return "SELECT"
+}
+
+// endStatement records err in the span and ends it. sql.ErrNoRows is an expected result rather than a failure.
+func endStatement(span trace.Span, err error) {
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
+
+func (db *tracedDB) ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error) {
+	ctx, span := startStatement(ctx, query)
+	result, err := db.DB.ExecContext(ctx, query, args...)
+	if err == nil {
+		if rows, rowsErr := result.RowsAffected(); rowsErr == nil {
+			span.SetAttributes(attribute.Int64("db.rows_affected", rows))
+		}
+	}
+	endStatement(span, err)
+	return result, err
+}
+
+func (db *tracedDB) QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error) {
+	ctx, span := startStatement(ctx, query)
+	rows, err := db.DB.QueryContext(ctx, query, args...)
+	endStatement(span, err)
+	return rows, err
+}
+
+func (db *tracedDB) QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row {
+	ctx, span := startStatement(ctx, query)
+	row := db.DB.QueryRowContext(ctx, query, args...)
+	endStatement(span, row.Err())
+	return row
+}
+
+func (db *tracedDB) BeginTx(ctx context.Context, opts *sql.TxOptions) (*tracedTx, error) {
+	tx, err := db.DB.BeginTx(ctx, opts)
+	if err != nil {
+		return nil, err
+	}
+	return &tracedTx{Tx: tx}, nil
+}
+
+func (tx *tracedTx) QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error) {
+	ctx, span := startStatement(ctx, query)
+	rows, err := tx.Tx.QueryContext(ctx, query, args...)
+	endStatement(span, err)
+	return rows, err
+}
+
+func (tx *tracedTx) ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error) {
+	ctx, span := startStatement(ctx, query)
+	result, err := tx.Tx.ExecContext(ctx, query, args...)
+	if err == nil {
+		if rows, rowsErr := result.RowsAffected(); rowsErr == nil {
+			span.SetAttributes(attribute.Int64("db.rows_affected", rows))
+		}
+	}
+	endStatement(span, err)
+	return result, err
+}
@@ -0,0 +1,27 @@
+package storage
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStatementOperation(t *testing.T) {
+	testCases := []struct {
+		name     string
+		query    string
+		expected string
+	}{
+		{name: "select", query: readShortURLQuery, expected: "SELECT"},
+		{name: "lowercase insert", query: "  insert into content.users DEFAULT VALUES", expected: "INSERT"},
+		{name: "update in a common table expression", query: updateDeleteFlagQuery, expected: "UPDATE"},
+		{name: "restore in a common table expression", query: restoreURLsQuery, expected: "UPDATE"},
+		{name: "read-only common table expression", query: "WITH recent AS (SELECT shortURL FROM content.urls) SELECT * FROM recent", expected: "SELECT"},
+		{name: "columns named after operations", query: "WITH d AS (SELECT deletedFlag, updated_at FROM content.urls) SELECT * FROM d", expected: "SELECT"},
+	}
+	for _, test := range testCases {
+		t.Run(test.name, func(t *testing.T) {
+			assert.Equal(t, test.expected, statementOperation(test.query))
+		})
+	}
+}
@@ -3,25 +3,38 @@ package storage
 
 import (
 	"context"
-	"errors"
+	"time"
 
 	"github.com/DariSorokina/go-first-sprint/internal/config"
 	"github.com/DariSorokina/go-first-sprint/internal/logger"
 	"github.com/DariSorokina/go-first-sprint/internal/models"
 )
 
-// ErrShortURLAlreadyExist indicates that a corresponding short URL already exists.
-var ErrShortURLAlreadyExist = errors.New("corresponding short URL already exists")
-
 // Database is a set of method signatures for data storage.
+// Errors returned by its methods belong to the categories ErrNotFound, ErrConflict, ErrDeleted,
+// ErrExpired and ErrUnavailable.
 type Database interface {
-	SetValue(ctx context.Context, shortURL, longURL string, userID int)
-	GetShort(ctx context.Context, longURL string) (shortURL string, err error)
+	CreateUser(ctx context.Context) (userID int, err error)
+	UserExists(ctx context.Context, userID int) (exists bool, err error)
+	CreateAPIKey(ctx context.Context, key models.APIKey) error
+	GetAPIKeyByHash(ctx context.Context, hash string) (key models.APIKey, err error)
+	ListAPIKeys(ctx context.Context, userID int) (keys []models.APIKey, err error)
+	RevokeAPIKey(ctx context.Context, id string, userID int, revokedAt time.Time) error
+	SetValue(ctx context.Context, shortURL, longURL string, userID int, expiresAt time.Time) (storedShortURL string, err error)
+	SetValues(ctx context.Context, urls []models.BatchURL, userID int) error
+	SetAlias(ctx context.Context, alias, longURL string, userID int, expiresAt time.Time) (ownerID int, err error)
 	GetOriginal(ctx context.Context, shortURL string) (longURL string, err error)
-	GetURLsByUserID(ctx context.Context, userID int) (urls []models.URLPair)
-	DeleteURLsWorker(shortURLs []string, userID int)
+	GetURLsByUserID(ctx context.Context, userID int) (urls []models.URLPair, err error)
+	DeleteURLs(ctx context.Context, deletions []models.URLsClientID) (results []map[string]string, err error)
+	RestoreURLs(ctx context.Context, shortURLs []string, userID int, deletedAfter time.Time) (results map[string]string, err error)
+	PurgeDeleted(ctx context.Context, deletedBefore time.Time) (purged int, err error)
+	DeleteExpired(ctx context.Context, expiredBefore time.Time) (deleted int, err error)
+	CountURLs(ctx context.Context) (count int, err error)
+	CountUsers(ctx context.Context) (count int, err error)
+	SaveClicks(ctx context.Context, clicks []models.ClickEvent) error
+	GetLinkStats(ctx context.Context, shortURL string, userID int) (stats models.LinkStats, err error)
 	Ping(ctx context.Context) error
-	Close()
+	Close() error
 }
 
 // SetStorage is a constructor function for data storage object.
@@ -0,0 +1,143 @@
+package tracing
+
+import (
+	"context"
+	"time"
+
+	"github.com/DariSorokina/go-first-sprint/internal/models"
+	"github.com/DariSorokina/go-first-sprint/internal/storage"
+)
+
+// database is a storage.Database starting a span for each call of the wrapped one.
+type database struct {
+	db storage.Database
+}
+
+// NewDatabase wraps db, so each of its calls is traced as a child of the span in the call context.
+func NewDatabase(db storage.Database) storage.Database {
+	return &database{db: db}
+}
+
+func (d *database) CreateUser(ctx context.Context) (userID int, err error) {
+	ctx, span := Start(ctx, "Database.CreateUser")
+	defer func() { End(span, err) }()
+	return d.db.CreateUser(ctx)
+}
+
+func (d *database) UserExists(ctx context.Context, userID int) (exists bool, err error) {
+	ctx, span := Start(ctx, "Database.UserExists")
+	defer func() { End(span, err) }()
+	return d.db.UserExists(ctx, userID)
+}
+
+func (d *database) CreateAPIKey(ctx context.Context, key models.APIKey) (err error) {
+	ctx, span := Start(ctx, "Database.CreateAPIKey")
+	defer func() { End(span, err) }()
+	return d.db.CreateAPIKey(ctx, key)
+}
+
+func (d *database) GetAPIKeyByHash(ctx context.Context, hash string) (key models.APIKey, err error) {
+	ctx, span := Start(ctx, "Database.GetAPIKeyByHash")
+	defer func() { End(span, err) }()
+	return d.db.GetAPIKeyByHash(ctx, hash)
+}
+
+func (d *database) ListAPIKeys(ctx context.Context, userID int) (keys []models.APIKey, err error) {
+	ctx, span := Start(ctx, "Database.ListAPIKeys")
+	defer func() { End(span, err) }()
+	return d.db.ListAPIKeys(ctx, userID)
+}
+
+func (d *database) RevokeAPIKey(ctx context.Context, id string, userID int, revokedAt time.Time) (err error) {
+	ctx, span := Start(ctx, "Database.RevokeAPIKey")
+	defer func() { End(span, err) }()
+	return d.db.RevokeAPIKey(ctx, id, userID, revokedAt)
+}
+
+func (d *database) SetValue(ctx context.Context, shortURL, longURL string, userID int, expiresAt time.Time) (storedShortURL string, err error) {
+	ctx, span := Start(ctx, "Database.SetValue")
+	defer func() { End(span, err) }()
+	return d.db.SetValue(ctx, shortURL, longURL, userID, expiresAt)
+}
+
+func (d *database) SetValues(ctx context.Context, urls []models.BatchURL, userID int) (err error) {
+	ctx, span := Start(ctx, "Database.SetValues")
+	defer func() { End(span, err) }()
+	return d.db.SetValues(ctx, urls, userID)
+}
+
+func (d *database) SetAlias(ctx context.Context, alias, longURL string, userID int, expiresAt time.Time) (ownerID int, err error) {
+	ctx, span := Start(ctx, "Database.SetAlias")
+	defer func() { End(span, err) }()
+	return d.db.SetAlias(ctx, alias, longURL, userID, expiresAt)
+}
+
+func (d *database) GetOriginal(ctx context.Context, shortURL string) (longURL string, err error) {
+	ctx, span := Start(ctx, "Database.GetOriginal")
+	defer func() { End(span, err) }()
+	return d.db.GetOriginal(ctx, shortURL)
+}
+
+func (d *database) GetURLsByUserID(ctx context.Context, userID int) (urls []models.URLPair, err error) {
+	ctx, span := Start(ctx, "Database.GetURLsByUserID")
+	defer func() { End(span, err) }()
+	return d.db.GetURLsByUserID(ctx, userID)
+}
+
+func (d *database) DeleteURLs(ctx context.Context, deletions []models.URLsClientID) (results []map[string]string, err error) {
+	ctx, span := Start(ctx, "Database.DeleteURLs")
+	defer func() { End(span, err) }()
+	return d.db.DeleteURLs(ctx, deletions)
+}
+
+func (d *database) RestoreURLs(ctx context.Context, shortURLs []string, userID int, deletedAfter time.Time) (results map[string]string, err error) {
+	ctx, span := Start(ctx, "Database.RestoreURLs")
+	defer func() { End(span, err) }()
+	return d.db.RestoreURLs(ctx, shortURLs, userID, deletedAfter)
+}
+
+func (d *database) PurgeDeleted(ctx context.Context, deletedBefore time.Time) (purged int, err error) {
+	ctx, span := Start(ctx, "Database.PurgeDeleted")
+	defer func() { End(span, err) }()
+	return d.db.PurgeDeleted(ctx, deletedBefore)
+}
+
+func (d *database) DeleteExpired(ctx context.Context, now time.Time) (deleted int, err error) {
+	ctx, span := Start(ctx, "Database.DeleteExpired")
+	defer func() { End(span, err) }()
+	return d.db.DeleteExpired(ctx, now)
+}
+
+func (d *database) CountURLs(ctx context.Context) (count int, err error) {
+	ctx, span := Start(ctx, "Database.CountURLs")
+	defer func() { End(span, err) }()
+	return d.db.CountURLs(ctx)
+}
+
+func (d *database) CountUsers(ctx context.Context) (count int, err error) {
+	ctx, span := Start(ctx, "Database.CountUsers")
+	defer func() { End(span, err) }()
+	return d.db.CountUsers(ctx)
+}
+
+func (d *database) SaveClicks(ctx context.Context, clicks []models.ClickEvent) (err error) {
+	ctx, span := Start(ctx, "Database.SaveClicks")
+	defer func() { End(span, err) }()
+	return d.db.SaveClicks(ctx, clicks)
+}
+
+func (d *database) GetLinkStats(ctx context.Context, shortURL string, userID int) (stats models.LinkStats, err error) {
+	ctx, span := Start(ctx, "Database.GetLinkStats")
+	defer func() { End(span, err) }()
+	return d.db.GetLinkStats(ctx, shortURL, userID)
+}
+
+func (d *database) Ping(ctx context.Context) (err error) {
+	ctx, span := Start(ctx, "Database.Ping")
+	defer func() { End(span, err) }()
+	return d.db.Ping(ctx)
+}
+
+func (d *database) Close() error {
+	return d.db.Close()
+}
@@ -0,0 +1,133 @@
+// Package tracing provides OpenTelemetry tracing of the HTTP server, the application and the storage.
+package tracing
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+
+	"github.com/DariSorokina/go-first-sprint/internal/config"
+	"github.com/DariSorokina/go-first-sprint/internal/storage"
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracegrpc"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.21.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// serviceName identifies the spans of the shortener in the tracing backend.
+const serviceName = "shortener"
+
+var tracer = otel.Tracer("github.com/DariSorokina/go-first-sprint/internal/tracing")
+
+// Setup installs the W3C trace context propagator and, unless tracing is disabled, a tracer provider exporting
+// spans with the exporter chosen in flagConfig. The returned function flushes pending spans and must be called
+// before the process exits.
+func Setup(ctx context.Context, flagConfig *config.FlagConfig) (shutdown func(ctx context.Context) error, err error) {
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{}))
+
+	var exporter sdktrace.SpanExporter
+	var file *os.File
+	switch flagConfig.FlagTraceExporter {
+	case "":
+		return func(context.Context) error { return nil }, nil
+	case "otlp":
+		options := []otlptracegrpc.Option{otlptracegrpc.WithEndpoint(flagConfig.FlagTraceEndpoint)}
+		if flagConfig.FlagTraceInsecure {
+			options = append(options, otlptracegrpc.WithInsecure())
+		}
+		exporter, err = otlptracegrpc.New(ctx, options...)
+	case "stdout":
+		var w io.Writer = os.Stdout
+		if flagConfig.FlagTraceFile != "" {
+			if file, err = os.OpenFile(flagConfig.FlagTraceFile, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0666); err != nil {
+				return nil, err
+			}
+			w = file
+		}
+		exporter, err = stdouttrace.New(stdouttrace.WithWriter(w))
+	default:
+		return nil, fmt.Errorf("unknown trace exporter %q", flagConfig.FlagTraceExporter)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(resource.NewSchemaless(semconv.ServiceName(serviceName))),
+	)
+	otel.SetTracerProvider(provider)
+
+	return func(ctx context.Context) error {
+		err := provider.Shutdown(ctx)
+		if file != nil {
+			if closeErr := file.Close(); err == nil {
+				err = closeErr
+			}
+		}
+		return err
+	}, nil
+}
+
+// Start starts a span named after the operation as a child of the span in ctx.
+func Start(ctx context.Context, operation string, attributes ...attribute.KeyValue) (context.Context, trace.Span) {
+	return tracer.Start(ctx, operation, trace.WithAttributes(attributes...))
+}
+
+// End records err, if any, in the span and ends it. Storage errors reporting a missing, clashing, deleted
+// or expired record are expected outcomes, so they do not mark the span as failed.
+func End(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		if !isOutcome(err) {
+			span.SetStatus(codes.Error, err.Error())
+		}
+	}
+	span.End()
+}
+
+func isOutcome(err error) bool {
+	return errors.Is(err, storage.ErrNotFound) || errors.Is(err, storage.ErrConflict) ||
+		errors.Is(err, storage.ErrDeleted) || errors.Is(err, storage.ErrExpired)
+}
+
+// Middleware is a middleware starting a server span for each request, continuing the trace of the caller
+// given in the W3C traceparent header. The span covers the rest of the middleware chain and is named after
+// the chi route pattern the request matched, so it must be the first middleware of the router.
+func Middleware(h http.Handler) http.Handler {
+	fn := func(w http.ResponseWriter, r *http.Request) {
+		ctx := otel.GetTextMapPropagator().Extract(r.Context(), propagation.HeaderCarrier(r.Header))
+		ctx, span := tracer.Start(ctx, r.Method,
+			trace.WithSpanKind(trace.SpanKindServer),
+			trace.WithAttributes(semconv.HTTPMethod(r.Method), semconv.URLPath(r.URL.Path)))
+		defer span.End()
+
+		ww := middleware.NewWrapResponseWriter(w, r.ProtoMajor)
+		h.ServeHTTP(ww, r.WithContext(ctx))
+
+		if routeContext := chi.RouteContext(r.Context()); routeContext != nil && routeContext.RoutePattern() != "" {
+			span.SetName(r.Method + " " + routeContext.RoutePattern())
+			span.SetAttributes(semconv.HTTPRoute(routeContext.RoutePattern()))
+		}
+		status := ww.Status()
+		if status == 0 {
+			status = http.StatusOK
+		}
+		span.SetAttributes(semconv.HTTPStatusCode(status))
+		if status >= http.StatusInternalServerError {
+			span.SetStatus(codes.Error, http.StatusText(status))
+		}
+	}
+	return http.HandlerFunc(fn)
+}
@@ -0,0 +1,58 @@
+package tracing
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/DariSorokina/go-first-sprint/internal/config"
+	"github.com/DariSorokina/go-first-sprint/internal/logger"
+	"github.com/DariSorokina/go-first-sprint/internal/storage"
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func TestMiddlewareContinuesTrace(t *testing.T) {
+	_, err := Setup(context.Background(), config.NewFlagConfig())
+	require.NoError(t, err)
+	recorder := tracetest.NewSpanRecorder()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+
+	l, err := logger.CreateLogger("error")
+	require.NoError(t, err)
+	db := NewDatabase(storage.NewStorage("", l))
+	defer db.Close()
+
+	router := chi.NewRouter()
+	router.Use(Middleware)
+	router.Get("/{id}", func(w http.ResponseWriter, r *http.Request) {
+		if _, err := db.GetOriginal(r.Context(), chi.URLParam(r, "id")); err != nil {
+			http.NotFound(w, r)
+			return
+		}
+		w.WriteHeader(http.StatusTemporaryRedirect)
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/missing", nil)
+	req.Header.Set("traceparent", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	router.ServeHTTP(httptest.NewRecorder(), req)
+
+	spans := recorder.Ended()
+	require.Len(t, spans, 2)
+	storageSpan, serverSpan := spans[0], spans[1]
+
+	assert.Equal(t, "GET /{id}", serverSpan.Name())
+	assert.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", serverSpan.SpanContext().TraceID().String())
+	assert.Equal(t, "00f067aa0ba902b7", serverSpan.Parent().SpanID().String(), "the span continues the caller's trace")
+
+	assert.Equal(t, "Database.GetOriginal", storageSpan.Name())
+	assert.Equal(t, serverSpan.SpanContext().SpanID(), storageSpan.Parent().SpanID())
+	assert.Equal(t, codes.Unset, storageSpan.Status().Code, "a missing short URL is not a failure")
+	assert.Len(t, storageSpan.Events(), 1, "the error is still recorded")
+}